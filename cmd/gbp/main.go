@@ -12,8 +12,14 @@ import (
 
 	"github.com/qxuken/gbp/internals/api"
 	"github.com/qxuken/gbp/internals/completions"
+	"github.com/qxuken/gbp/internals/doctor"
+	"github.com/qxuken/gbp/internals/i18n"
+	"github.com/qxuken/gbp/internals/jobs"
 	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
+	"github.com/qxuken/gbp/internals/progress"
 	"github.com/qxuken/gbp/internals/seed"
+	"github.com/qxuken/gbp/internals/workspaces"
 	_ "github.com/qxuken/gbp/migrations"
 )
 
@@ -28,25 +34,83 @@ func main() {
 		Automigrate: true,
 	})
 
+	var preloadDir string
+	app.RootCmd.PersistentFlags().StringVar(
+		&preloadDir,
+		"preload-dir",
+		os.Getenv(seed.PRELOAD_DIR_ENV),
+		"the directory holding the preload seed.db, seed.hash and seed.note (env "+seed.PRELOAD_DIR_ENV+")",
+	)
+
+	var seedUpstream, seedPullSchedule string
+	app.RootCmd.PersistentFlags().StringVar(
+		&seedUpstream,
+		"seed-upstream",
+		os.Getenv(seed.SEED_UPSTREAM_ENV),
+		"the base url of the instance to pull seeds from (env "+seed.SEED_UPSTREAM_ENV+")",
+	)
+	app.RootCmd.PersistentFlags().StringVar(
+		&seedPullSchedule,
+		"seed-pull-schedule",
+		os.Getenv(seed.SEED_PULL_SCHEDULE_ENV),
+		"the cron expression of the upstream seed pull, e.g. \"0 4 * * *\" (env "+seed.SEED_PULL_SCHEDULE_ENV+")",
+	)
+
+	progressSnapshotSchedule := os.Getenv(progress.SNAPSHOT_SCHEDULE_ENV)
+	if progressSnapshotSchedule == "" {
+		progressSnapshotSchedule = progress.DEFAULT_SNAPSHOT_SCHEDULE
+	}
+	app.RootCmd.PersistentFlags().StringVar(
+		&progressSnapshotSchedule,
+		"progress-snapshot-schedule",
+		progressSnapshotSchedule,
+		"the cron expression of the periodic plan snapshots, empty to disable (env "+progress.SNAPSHOT_SCHEDULE_ENV+")",
+	)
+
 	app.RootCmd.AddCommand(seed.NewCobraSeedCommand(app))
 
 	app.RootCmd.AddCommand(seed.NewCobraDumpCommand(app))
 
 	app.RootCmd.AddCommand(seed.NewCobraSeedHashCommand())
 
+	app.RootCmd.AddCommand(seed.NewCobraDictionaryCommand(app))
+
+	app.RootCmd.AddCommand(doctor.NewCobraDoctorCommand(app))
+
 	app.RootCmd.AddCommand(completions.NewCompletionsCommand(app.RootCmd))
 
 	latestDumpCache := models.NewLatestDbDumpCache()
 	latestDumpCache.Bind(app)
 
+	plansCache := plans.NewCache()
+	plansCache.Bind(app)
+	plans.BindProfiles(app)
+	plans.BindCompletion(app)
+	workspaces.Bind(app)
+	progress.Bind(app)
+
+	seed.BindMaintenanceGuard(app)
+	seed.BindSchemaCheck(app)
+	seed.BindIconPipeline(app)
+	i18n.Bind(app)
+
 	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
-		if err := seed.UpdateFromPreload(app, latestDumpCache); err != nil {
+		if err := seed.UpdateFromPreload(app, latestDumpCache, preloadDir); err != nil {
 			app.Logger().Error(err.Error())
 		}
+		if err := seed.BindPullJob(app, seedUpstream, seedPullSchedule); err != nil {
+			return err
+		}
+		if err := progress.BindSnapshotJob(app, progressSnapshotSchedule); err != nil {
+			return err
+		}
 		return se.Next()
 	})
 
-	api.Bind(app, latestDumpCache)
+	jobRunner := jobs.NewRunner(app)
+	jobRunner.Bind()
+
+	api.Bind(app, latestDumpCache, plansCache, jobRunner)
 
 	if err := app.Start(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,340 @@
+// Command seedgen reads the pb and db tags of the seed structs and writes
+// their typed record mappings, see the go:generate directive of
+// internals/seed/models.go. The structs without a generated mapping go
+// through the reflection mapping of the seed package.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/qxuken/gbp/internals/seed/tags"
+)
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindFloat
+	kindBool
+	kindBlob
+	kindFiles
+	kindDateTime
+	kindStringSlice
+)
+
+var kindsByType = map[string]kind{
+	"string":                  kindString,
+	"int":                     kindInt,
+	"int64":                   kindInt,
+	"float32":                 kindFloat,
+	"float64":                 kindFloat,
+	"bool":                    kindBool,
+	"Icon":                    kindBlob,
+	"[]byte":                  kindBlob,
+	"Files":                   kindFiles,
+	"types.DateTime":          kindDateTime,
+	"types.JSONArray[string]": kindStringSlice,
+	"[]string":                kindStringSlice,
+}
+
+// sqlTypes mirror sqlTypeFromGo of the seed package.
+var sqlTypes = map[kind]string{
+	kindString:      "TEXT",
+	kindInt:         "INTEGER",
+	kindFloat:       "REAL",
+	kindBool:        "BOOL",
+	kindBlob:        "BLOB",
+	kindFiles:       "TEXT",
+	kindDateTime:    "TEXT",
+	kindStringSlice: "TEXT",
+}
+
+var recordGetters = map[kind]string{
+	kindString: "GetString",
+	kindInt:    "GetInt",
+	kindFloat:  "GetFloat",
+	kindBool:   "GetBool",
+}
+
+type field struct {
+	name  string
+	dbKey string
+	tag   tags.Tag
+	kind  kind
+}
+
+type model struct {
+	name   string
+	fields []field
+}
+
+func (m model) fileExt(key string) (field, bool) {
+	for _, f := range m.fields {
+		if f.tag.FileExt && f.tag.Key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func (m model) primaryKey() (field, bool) {
+	for _, f := range m.fields {
+		if f.dbKey == "id" {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func typeString(fset *token.FileSet, expr ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, fset, expr)
+	return buf.String()
+}
+
+func parseModels(path string) ([]model, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	models := []model{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			m := model{name: typeSpec.Name.Name}
+			for _, f := range structType.Fields.List {
+				if f.Tag == nil {
+					continue
+				}
+				rawTag, err := strconv.Unquote(f.Tag.Value)
+				if err != nil {
+					return nil, err
+				}
+				structTag := reflect.StructTag(rawTag)
+				pbTag := structTag.Get("pb")
+				if pbTag == "" || pbTag == "-" {
+					continue
+				}
+				if len(f.Names) != 1 {
+					return nil, fmt.Errorf("%s: a tagged field has to be declared alone", m.name)
+				}
+				name := f.Names[0].Name
+				tag, err := tags.Parse(pbTag)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", m.name, name, err)
+				}
+				goType := typeString(fset, f.Type)
+				k, ok := kindsByType[goType]
+				if !ok {
+					return nil, fmt.Errorf("%s.%s: unsupported type %s", m.name, name, goType)
+				}
+				dbKey := structTag.Get("db")
+				if dbKey == "" {
+					return nil, fmt.Errorf("%s.%s: missing db tag", m.name, name)
+				}
+				m.fields = append(m.fields, field{name: name, dbKey: dbKey, tag: tag, kind: k})
+			}
+			if len(m.fields) == 0 {
+				continue
+			}
+			if err := validate(m); err != nil {
+				return nil, err
+			}
+			models = append(models, m)
+		}
+	}
+	return models, nil
+}
+
+// validate rejects the tags the reflection mapping would only trip over at
+// runtime.
+func validate(m model) error {
+	pk, ok := m.primaryKey()
+	if !ok || pk.kind != kindString {
+		return fmt.Errorf("%s: missing the string id field", m.name)
+	}
+	for _, f := range m.fields {
+		where := m.name + "." + f.name
+		switch {
+		case f.tag.File && f.kind != kindBlob:
+			return fmt.Errorf("%s: file field must be of type Icon", where)
+		case f.tag.File:
+			if _, ok := m.fileExt(f.tag.Key); !ok {
+				return fmt.Errorf("%s: file field without its fileext field", where)
+			}
+		case f.tag.FileExt && f.kind != kindString:
+			return fmt.Errorf("%s: fileext field must be a string", where)
+		case f.tag.Files && f.kind != kindFiles:
+			return fmt.Errorf("%s: files field must be of type Files", where)
+		case f.tag.Select && f.kind != kindString && f.kind != kindStringSlice:
+			return fmt.Errorf("%s: select field must be a string or a string slice", where)
+		case f.tag.JSON && f.kind != kindStringSlice:
+			return fmt.Errorf("%s: json field must be a string slice", where)
+		case f.kind == kindBlob && !f.tag.File:
+			return fmt.Errorf("%s: byte fields have to be tagged as file", where)
+		}
+	}
+	return nil
+}
+
+type writer struct {
+	bytes.Buffer
+	usesJSON bool
+}
+
+func (w *writer) line(format string, args ...any) {
+	fmt.Fprintf(w, format+"\n", args...)
+}
+
+func (w *writer) writeModel(m model) {
+	pk, _ := m.primaryKey()
+	w.line("func (item %s) recordId() string {", m.name)
+	w.line("return item.%s", pk.name)
+	w.line("}")
+	w.line("")
+
+	w.line("func (item %s) toRecord(record *core.Record) error {", m.name)
+	for _, f := range m.fields {
+		key := strconv.Quote(f.tag.Key)
+		switch {
+		case f.dbKey == "id" || f.tag.FileExt:
+		case f.tag.File:
+			ext, _ := m.fileExt(f.tag.Key)
+			w.line("%sFile, err := filesystem.NewFileFromBytes(item.%s, item.%s)", f.tag.Key, f.name, ext.name)
+			w.line("if err != nil {\nreturn err\n}")
+			w.line("record.Set(%s, %sFile)", key, f.tag.Key)
+		case f.tag.Files:
+			w.line("%sFiles, err := item.%s.toFilesystem()", f.tag.Key, f.name)
+			w.line("if err != nil {\nreturn err\n}")
+			w.line("record.Set(%s, %sFiles)", key, f.tag.Key)
+		default:
+			if f.tag.Select {
+				spread := ""
+				if f.kind == kindStringSlice {
+					spread = "..."
+				}
+				w.line("if err := checkSelectValues(record.Collection(), %s, item.%s%s); err != nil {\nreturn err\n}", key, f.name, spread)
+			}
+			w.line("record.Set(%s, item.%s)", key, f.name)
+		}
+	}
+	w.line("return nil")
+	w.line("}")
+	w.line("")
+
+	w.line("func (%s) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {", m.name)
+	w.line("params := dbx.Params{}")
+	for _, f := range m.fields {
+		key := strconv.Quote(f.tag.Key)
+		dbKey := strconv.Quote(f.dbKey)
+		switch {
+		case f.tag.FileExt:
+		case f.tag.File:
+			ext, _ := m.fileExt(f.tag.Key)
+			w.line("%sName, %sContent, err := getFileContent(fsys, record, %s)", f.tag.Key, f.tag.Key, key)
+			w.line("if err != nil {\nreturn nil, err\n}")
+			w.line("params[%s] = %sContent", dbKey, f.tag.Key)
+			w.line("params[%s] = %sName", strconv.Quote(ext.dbKey), f.tag.Key)
+		case f.tag.Files:
+			w.line("%sFiles, err := getFilesContent(fsys, record, %s)", f.tag.Key, key)
+			w.line("if err != nil {\nreturn nil, err\n}")
+			w.line("params[%s] = %sFiles", dbKey, f.tag.Key)
+		case f.kind == kindStringSlice:
+			w.usesJSON = true
+			w.line("%sJSON, err := json.Marshal(record.GetStringSlice(%s))", f.tag.Key, key)
+			w.line("if err != nil {\nreturn nil, err\n}")
+			w.line("params[%s] = %sJSON", dbKey, f.tag.Key)
+		case f.kind == kindDateTime:
+			w.line("params[%s] = record.GetDateTime(%s).String()", dbKey, key)
+		default:
+			w.line("params[%s] = record.%s(%s)", dbKey, recordGetters[f.kind], key)
+		}
+	}
+	w.line("return params, nil")
+	w.line("}")
+	w.line("")
+
+	w.line("func (%s) tableSchema() map[string]string {", m.name)
+	w.line("return map[string]string{")
+	for _, f := range m.fields {
+		sqlType := sqlTypes[f.kind]
+		if f.dbKey == "id" {
+			sqlType += " NOT NULL PRIMARY KEY"
+		} else if !f.tag.Opt {
+			sqlType += " NOT NULL"
+		}
+		w.line("%s: %s,", strconv.Quote(f.dbKey), strconv.Quote(sqlType))
+	}
+	w.line("}")
+	w.line("}")
+	w.line("")
+}
+
+func generate(source string, pkg string, models []model) ([]byte, error) {
+	body := &writer{}
+	for _, m := range models {
+		body.writeModel(m)
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "// Code generated by seedgen from %s; DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(out, "package %s\n\n", pkg)
+	out.WriteString("import (\n")
+	if body.usesJSON {
+		out.WriteString("\"encoding/json\"\n\n")
+	}
+	out.WriteString("\"github.com/pocketbase/dbx\"\n")
+	out.WriteString("\"github.com/pocketbase/pocketbase/core\"\n")
+	out.WriteString("\"github.com/pocketbase/pocketbase/tools/filesystem\"\n")
+	out.WriteString(")\n\n")
+	out.Write(body.Bytes())
+	return format.Source(out.Bytes())
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("seedgen: ")
+	source := flag.String("file", os.Getenv("GOFILE"), "the go file declaring the seed structs")
+	output := flag.String("output", "", "the generated file, <file>_gen.go when empty")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "the package of the generated file")
+	flag.Parse()
+
+	if *source == "" || *pkg == "" {
+		log.Fatal("-file and -package are required outside of go generate")
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(*source, ".go") + "_gen.go"
+	}
+	models, err := parseModels(*source)
+	if err != nil {
+		log.Fatal(err)
+	}
+	code, err := generate(*source, *pkg, models)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, code, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestGeneratedUpToDate fails when models_gen.go wasn't regenerated after a
+// change of the seed structs.
+func TestGeneratedUpToDate(t *testing.T) {
+	const dir = "../../internals/seed/"
+	models, err := parseModels(dir + "models.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := generate("models.go", "seed", models)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile(dir + "models_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, current) {
+		t.Error("models_gen.go is stale, run go generate ./internals/seed")
+	}
+}
@@ -5,19 +5,25 @@ import (
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
 
+	"github.com/qxuken/gbp/internals/jobs"
 	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
 	"github.com/qxuken/gbp/ui"
 )
 
 // Bind registers the SPA and the custom /api routes on the app serve event.
-func Bind(app core.App, latestDumpCache *models.LatestDbDumpCache) {
+func Bind(app core.App, latestDumpCache *models.LatestDbDumpCache, plansCache *plans.Cache, jobRunner *jobs.Runner) {
 	bindStatic(app)
 
 	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
 		g := se.Router.Group("/api")
 
-		bindPlansRoutes(app, g)
-		bindDumpRoutes(app, g, latestDumpCache)
+		bindPlansRoutes(app, g, plansCache)
+		bindWorkspacesRoutes(app, g)
+		bindDumpRoutes(app, g, latestDumpCache, jobRunner)
+		bindJobsRoutes(app, g, jobRunner)
+		bindIconsRoutes(app, g)
+		bindDictionaryRoutes(app, g)
 
 		return se.Next()
 	})
@@ -2,12 +2,14 @@ package api_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,23 +18,67 @@ import (
 	"github.com/pocketbase/pocketbase/tests"
 
 	"github.com/qxuken/gbp/internals/api"
+	"github.com/qxuken/gbp/internals/icons"
+	"github.com/qxuken/gbp/internals/jobs"
 	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
+	"github.com/qxuken/gbp/internals/progress"
 	"github.com/qxuken/gbp/internals/seed"
 	"github.com/qxuken/gbp/internals/testutil"
+	"github.com/qxuken/gbp/internals/workspaces"
 )
 
 func TestMain(m *testing.M) {
 	testutil.Main(m)
 }
 
+// jobRunners keeps the job runner of every app built by bindApi, so that the
+// scenarios can wait for the background jobs they started.
+var jobRunners sync.Map
+
+// bindApi registers the custom routes on the app.
+func bindApi(app *tests.TestApp) *jobs.Runner {
+	latestDumpCache := models.NewLatestDbDumpCache()
+	latestDumpCache.Bind(app)
+	jobRunner := jobs.NewRunner(app)
+	jobRunners.Store(app, jobRunner)
+	plansCache := plans.NewCache()
+	plansCache.Bind(app)
+	plans.BindProfiles(app)
+	plans.BindCompletion(app)
+	workspaces.Bind(app)
+	progress.Bind(app)
+	api.Bind(app, latestDumpCache, plansCache, jobRunner)
+	return jobRunner
+}
+
+// waitForJob waits for the job queued by the response and returns it.
+func waitForJob(t testing.TB, app *tests.TestApp, res *http.Response) *models.Job {
+	t.Helper()
+	data := struct {
+		JobId string `json:"jobId"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	jobRunner, ok := jobRunners.Load(app)
+	if !ok {
+		t.Fatal("the app wasn't bound with bindApi")
+	}
+	jobRunner.(*jobs.Runner).Wait(data.JobId)
+	job, err := models.FindJobById(app, data.JobId)
+	if err != nil {
+		t.Fatalf("job %q: %v", data.JobId, err)
+	}
+	return job
+}
+
 // testApp returns an ApiScenario factory serving the custom routes, with an
 // optional setup step running before the request.
 func testApp(setup func(t testing.TB, app *tests.TestApp)) func(t testing.TB) *tests.TestApp {
 	return func(t testing.TB) *tests.TestApp {
 		app := testutil.NewTestAppWithoutCleanup(t)
-		latestDumpCache := models.NewLatestDbDumpCache()
-		latestDumpCache.Bind(app)
-		api.Bind(app, latestDumpCache)
+		bindApi(app)
 		if setup != nil {
 			setup(t, app)
 		}
@@ -40,6 +86,34 @@ func testApp(setup func(t testing.TB, app *tests.TestApp)) func(t testing.TB) *t
 	}
 }
 
+// userSetup prepares the app of a scenario and returns the tokens of the users
+// it created, by the names the scenarios pick them with.
+type userSetup func(t testing.TB, app *tests.TestApp) map[string]string
+
+// userScenario returns an ApiScenario authenticated as the user as of the
+// setup, the users only exist once the factory built the app, so it fills in
+// the Authorization header.
+func userScenario(name string, setup userSetup, as string, method string, url string, body string, status int, content []string, notContent []string) tests.ApiScenario {
+	headers := map[string]string{"Content-Type": "application/json"}
+	return tests.ApiScenario{
+		Name:               name,
+		Method:             method,
+		URL:                url,
+		Body:               strings.NewReader(body),
+		Headers:            headers,
+		ExpectedStatus:     status,
+		ExpectedContent:    content,
+		NotExpectedContent: notContent,
+		TestAppFactory: testApp(func(t testing.TB, app *tests.TestApp) {
+			token, ok := setup(t, app)[as]
+			if !ok {
+				t.Fatalf("the setup created no user %q", as)
+			}
+			headers["Authorization"] = token
+		}),
+	}
+}
+
 func TestPlansCollections(t *testing.T) {
 	scenario := tests.ApiScenario{
 		Name:           "plans collections dictionary",
@@ -133,13 +207,56 @@ func TestLatestDump(t *testing.T) {
 	}
 }
 
+func TestDumpChangelog(t *testing.T) {
+	setup := func(t testing.TB, app *tests.TestApp) {
+		testutil.CreateDbDump(t, app, "first-hash", "- Initial dictionary")
+		time.Sleep(5 * time.Millisecond)
+		testutil.CreateDbDump(t, app, "second-hash", "- Added characters: Bennett")
+	}
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "empty changelog",
+			Method:          http.MethodGet,
+			URL:             "/api/dump/changelog",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`[]`},
+			TestAppFactory:  testApp(nil),
+		},
+		{
+			Name:           "changelog json",
+			Method:         http.MethodGet,
+			URL:            "/api/dump/changelog",
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"hash":"second-hash"`,
+				`"notes":"- Added characters: Bennett"`,
+				`"hash":"first-hash"`,
+			},
+			TestAppFactory: testApp(setup),
+		},
+		{
+			Name:           "changelog markdown",
+			Method:         http.MethodGet,
+			URL:            "/api/dump/changelog?format=markdown",
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				"# Changelog",
+				"- Added characters: Bennett\n\n## ",
+				"- Initial dictionary",
+			},
+			TestAppFactory: testApp(setup),
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
 // TestLatestDumpCacheRefresh checks that the cached response follows the dumps
 // collection changes without restarting the app.
 func TestLatestDumpCacheRefresh(t *testing.T) {
 	app := testutil.NewTestApp(t)
-	latestDumpCache := models.NewLatestDbDumpCache()
-	latestDumpCache.Bind(app)
-	api.Bind(app, latestDumpCache)
+	bindApi(app)
 
 	mux := buildMux(t, app)
 	get := func(url string) (int, string) {
@@ -215,6 +332,22 @@ func TestDumpRoutesRequireSuperuser(t *testing.T) {
 			ExpectedContent: []string{`"status":401`},
 			TestAppFactory:  testApp(nil),
 		},
+		{
+			Name:            "rollback",
+			Method:          http.MethodPost,
+			URL:             "/api/dump/rollback",
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"status":401`},
+			TestAppFactory:  testApp(nil),
+		},
+		{
+			Name:            "job",
+			Method:          http.MethodGet,
+			URL:             "/api/jobs/somejobid000000",
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"status":401`},
+			TestAppFactory:  testApp(nil),
+		},
 	}
 	for _, scenario := range scenarios {
 		scenario.Test(t)
@@ -222,7 +355,7 @@ func TestDumpRoutesRequireSuperuser(t *testing.T) {
 }
 
 // TestDumpUpload uploads a seed file through the superuser endpoint and checks
-// that it is both stored as a dump and applied to the app.
+// that the queued job both stores it as a dump and applies it to the app.
 func TestDumpUpload(t *testing.T) {
 	// build a valid seed file out of a throwaway app
 	source := testutil.NewTestApp(t)
@@ -261,14 +394,25 @@ func TestDumpUpload(t *testing.T) {
 		URL:             "/api/dump/upload",
 		Body:            body,
 		Headers:         headers,
-		ExpectedStatus:  http.StatusOK,
-		ExpectedContent: []string{`"status":"ok"`},
+		ExpectedStatus:  http.StatusAccepted,
+		ExpectedContent: []string{`"status":"queued"`, `"jobId":`},
 		TestAppFactory: testApp(func(t testing.TB, app *tests.TestApp) {
 			t.Cleanup(app.Cleanup)
 			headers["Authorization"] = superuserToken(t, app)
 		}),
 		DisableTestAppCleanup: true,
 		AfterTestFunc: func(t testing.TB, app *tests.TestApp, res *http.Response) {
+			job := waitForJob(t, app, res)
+			if job.Status() != models.JOB_STATUS_DONE {
+				t.Fatalf("job: expected done, got %q %q", job.Status(), job.Error())
+			}
+			progress := map[string]jobs.CollectionProgress{}
+			if err := job.UnmarshalJSONField("progress", &progress); err != nil {
+				t.Fatal(err)
+			}
+			if p := progress[models.CHARACTERS_COLLECTION_NAME]; p.Done != 1 || p.Total != 1 {
+				t.Errorf("characters progress: unexpected %+v", p)
+			}
 			dump, err := models.FindLatestDbDump(app)
 			if err != nil {
 				t.Fatalf("stored dump: %v", err)
@@ -288,6 +432,133 @@ func TestDumpUpload(t *testing.T) {
 	scenario.Test(t)
 }
 
+// TestCompressedDumpDownload checks the Accept-Encoding negotiation of a dump
+// stored with each encoding against its precompressed variants.
+func TestCompressedDumpDownload(t *testing.T) {
+	for _, stored := range []seed.Compression{seed.COMPRESSION_NONE, seed.COMPRESSION_GZIP, seed.COMPRESSION_ZSTD} {
+		t.Run("stored "+string(stored), func(t *testing.T) {
+			app := testutil.NewTestApp(t)
+			testutil.SeedDictionaries(t, app)
+			bindApi(app)
+
+			dumpPath := filepath.Join(t.TempDir(), "seed.db")
+			if err := seed.DumpWithOptions(app, dumpPath, seed.DumpOptions{Compression: stored}); err != nil {
+				t.Fatal(err)
+			}
+			dump, err := models.FindLatestDbDump(app)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dump.Encoding() != string(stored) {
+				t.Fatalf("expected the dump stored with %q, got %q", stored, dump.Encoding())
+			}
+
+			mux := buildMux(t, app)
+			cases := map[string]seed.Compression{
+				"":                   seed.COMPRESSION_NONE,
+				"gzip":               seed.COMPRESSION_GZIP,
+				"zstd":               seed.COMPRESSION_ZSTD,
+				"gzip, zstd":         seed.COMPRESSION_ZSTD,
+				"gzip, zstd;q=0":     seed.COMPRESSION_GZIP,
+				"zstd;q=0, gzip;q=0": seed.COMPRESSION_NONE,
+				"br":                 seed.COMPRESSION_NONE,
+			}
+			for acceptEncoding, expected := range cases {
+				req := httptest.NewRequest(http.MethodGet, "/api/dump/latest_seed.db", nil)
+				if acceptEncoding != "" {
+					req.Header.Set("Accept-Encoding", acceptEncoding)
+				}
+				res := httptest.NewRecorder()
+				mux.ServeHTTP(res, req)
+				if res.Code != http.StatusOK || res.Header().Get("Content-Encoding") != string(expected) {
+					t.Fatalf("%q: expected a %q response, got %d %q", acceptEncoding, expected, res.Code, res.Header().Get("Content-Encoding"))
+				}
+
+				body := filepath.Join(t.TempDir(), "body")
+				if err := os.WriteFile(body, res.Body.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if compression, err := seed.DetectCompression(body); err != nil || compression != expected {
+					t.Fatalf("%q: expected a %q body, got %q %v", acceptEncoding, expected, compression, err)
+				}
+				hash, err := seed.GetSeedHash(body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hash != dump.Hash() {
+					t.Errorf("%q: expected the dump content, got the hash %s", acceptEncoding, hash)
+				}
+			}
+		})
+	}
+}
+
+// TestDumpRestoreSelection checks the partial restore of a stored dump.
+func TestDumpRestoreSelection(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	bindApi(app)
+	if err := seed.Dump(app, filepath.Join(t.TempDir(), "seed.db"), ""); err != nil {
+		t.Fatal(err)
+	}
+	dump, err := models.FindLatestDbDump(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := map[string]string{
+		models.CHARACTERS_COLLECTION_NAME: "characterdiluc0",
+		models.WEAPONS_COLLECTION_NAME:    "weaponaquila000",
+	}
+	for collection, id := range renamed {
+		record, err := app.FindRecordById(collection, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		record.Set("name", "Renamed")
+		if err := app.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	token := superuserToken(t, app)
+	mux := buildMux(t, app)
+	restore := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/dump/restore/"+dump.Id, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if res := restore(`{"collections":["unknown"]}`); res.Code != http.StatusBadRequest {
+		t.Errorf("unknown collection: expected 400, got %d", res.Code)
+	}
+
+	res := restore(`{"collections":["characters"]}`)
+	if res.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d %s", res.Code, res.Body.String())
+	}
+	if job := waitForJob(t, app, res.Result()); job.Status() != models.JOB_STATUS_DONE {
+		t.Fatalf("job: expected done, got %q %q", job.Status(), job.Error())
+	}
+	character, err := app.FindRecordById(models.CHARACTERS_COLLECTION_NAME, "characterdiluc0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if character.GetString("name") != "Diluc" {
+		t.Errorf("character: expected the restored name, got %q", character.GetString("name"))
+	}
+	weapon, err := app.FindRecordById(models.WEAPONS_COLLECTION_NAME, "weaponaquila000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weapon.GetString("name") != "Renamed" {
+		t.Errorf("weapon: expected to be left out of the restore, got %q", weapon.GetString("name"))
+	}
+}
+
 func superuserToken(t testing.TB, app *tests.TestApp) string {
 	t.Helper()
 	collection, err := app.FindCollectionByNameOrId(core.CollectionNameSuperusers)
@@ -306,3 +577,618 @@ func superuserToken(t testing.TB, app *tests.TestApp) string {
 	}
 	return token
 }
+
+// TestMaintenanceGuard checks that the plan writes touching dictionary
+// relations are rejected while the seed lock is held.
+func TestMaintenanceGuard(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	bindApi(app)
+	seed.BindMaintenanceGuard(app)
+	user, token := testutil.CreateUser(t, app, "user@test.com")
+	plan := testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00", map[string]any{
+		"user": user.Id, "character": "characterdiluc0", "order": 1,
+	})
+
+	mux := buildMux(t, app)
+	send := func(method string, url string, body string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+	planUrl := "/api/collections/" + models.CHARACTER_PLANS_COLLECTION_NAME + "/records"
+
+	// a dump only reads the dictionaries
+	lock, err := seed.AcquireDumpLock(app, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := send(http.MethodPost, planUrl, `{"user":"`+user.Id+`","character":"characterdiluc0","order":2}`); status != http.StatusOK {
+		t.Errorf("create during a dump: expected 200, got %d", status)
+	}
+	lock.Release()
+
+	lock, err = seed.AcquireSeedLock(app, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	if status := send(http.MethodPost, planUrl, `{"user":"`+user.Id+`","character":"characterdiluc0","order":2}`); status != http.StatusServiceUnavailable {
+		t.Errorf("create: expected 503, got %d", status)
+	}
+	if status := send(http.MethodPatch, planUrl+"/"+plan.Id, `{"characterRole":"charrolemaindps"}`); status != http.StatusServiceUnavailable {
+		t.Errorf("relation update: expected 503, got %d", status)
+	}
+	// the writes that leave the dictionary relations alone go through
+	if status := send(http.MethodPatch, planUrl+"/"+plan.Id, `{"levelCurrent":20}`); status != http.StatusOK {
+		t.Errorf("plain update: expected 200, got %d", status)
+	}
+
+	lock.Release()
+	if status := send(http.MethodPatch, planUrl+"/"+plan.Id, `{"characterRole":"charrolemaindps"}`); status != http.StatusOK {
+		t.Errorf("relation update after the seed: expected 200, got %d", status)
+	}
+}
+
+func TestIcons(t *testing.T) {
+	icon, err := icons.Normalize(testutil.PngContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withIcons := testApp(func(t testing.TB, app *tests.TestApp) {
+		seed.BindIconPipeline(app)
+		testutil.SeedDictionaries(t, app)
+	})
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "unknown hash",
+			Method:          http.MethodGet,
+			URL:             "/api/icons/00000000000000000000000000000000",
+			ExpectedStatus:  http.StatusNotFound,
+			ExpectedContent: []string{`"status":404`},
+			TestAppFactory:  withIcons,
+		},
+		{
+			Name:            "malformed hash",
+			Method:          http.MethodGet,
+			URL:             "/api/icons/..%2F" + icon.Hash,
+			ExpectedStatus:  http.StatusNotFound,
+			ExpectedContent: []string{`"status":404`},
+			TestAppFactory:  withIcons,
+		},
+		{
+			Name:            "icon",
+			Method:          http.MethodGet,
+			URL:             "/api/icons/" + icon.Hash,
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{"WEBP"},
+			TestAppFactory:  withIcons,
+			AfterTestFunc: func(t testing.TB, app *tests.TestApp, res *http.Response) {
+				if v := res.Header.Get("Cache-Control"); !strings.Contains(v, "immutable") {
+					t.Errorf("Cache-Control: expected an immutable response, got %q", v)
+				}
+			},
+		},
+		{
+			Name:            "thumbnail",
+			Method:          http.MethodGet,
+			URL:             "/api/icons/" + icon.Hash + "?thumb=1",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{"WEBP"},
+			TestAppFactory:  withIcons,
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestDictionarySearch(t *testing.T) {
+	withDictionaries := testApp(func(t testing.TB, app *tests.TestApp) {
+		testutil.SeedDictionaries(t, app)
+		testutil.CreateRecord(t, app, models.CHARACTERS_COLLECTION_NAME, "characterraiden", map[string]any{
+			"name": "Raiden Shogun", "rarity": 5, "element": "elementpyro0000",
+			"weaponType": "weapontypesword", "special": "spcritrate00000",
+			"icon": testutil.Icon(t, "raiden.png"), "aliases": []string{"Ei", "Baal"},
+		})
+		testutil.CreateRecord(t, app, models.TRANSLATIONS_COLLECTION_NAME, "trdilucja000000", map[string]any{
+			"collection": models.CHARACTERS_COLLECTION_NAME, "record": "characterdiluc0", "locale": "ja", "name": "ディルック",
+		})
+	})
+	firstResult := func(expectedId string, expectedName string) func(t testing.TB, app *tests.TestApp, res *http.Response) {
+		return func(t testing.TB, app *tests.TestApp, res *http.Response) {
+			results := []struct {
+				Id   string `json:"id"`
+				Name string `json:"name"`
+			}{}
+			if err := json.NewDecoder(res.Body).Decode(&results); err != nil {
+				t.Fatal(err)
+			}
+			if len(results) == 0 || results[0].Id != expectedId || results[0].Name != expectedName {
+				t.Errorf("expected %s %q first, got %+v", expectedId, expectedName, results)
+			}
+		}
+	}
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "missing query",
+			Method:          http.MethodGet,
+			URL:             "/api/dictionary/search?q=%20",
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"status":400`},
+			TestAppFactory:  withDictionaries,
+		},
+		{
+			Name:            "unknown collection",
+			Method:          http.MethodGet,
+			URL:             "/api/dictionary/search?q=ei&collections=users",
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"status":400`},
+			TestAppFactory:  withDictionaries,
+		},
+		{
+			Name:            "alias match",
+			Method:          http.MethodGet,
+			URL:             "/api/dictionary/search?q=ei",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"matched":"Ei"`, `"score":1000`},
+			TestAppFactory:  withDictionaries,
+			AfterTestFunc:   firstResult("characterraiden", "Raiden Shogun"),
+		},
+		{
+			Name:            "partial name across collections",
+			Method:          http.MethodGet,
+			URL:             "/api/dictionary/search?q=glad",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"collection":"artifactSets"`},
+			TestAppFactory:  withDictionaries,
+			AfterTestFunc:   firstResult("artsetgladiator", "Gladiator's Finale"),
+		},
+		{
+			Name:               "collections filter",
+			Method:             http.MethodGet,
+			URL:                "/api/dictionary/search?q=aquila&collections=characters",
+			ExpectedStatus:     http.StatusOK,
+			ExpectedContent:    []string{`[]`},
+			NotExpectedContent: []string{`weaponaquila000`},
+			TestAppFactory:     withDictionaries,
+		},
+		{
+			Name:            "translated name",
+			Method:          http.MethodGet,
+			URL:             "/api/dictionary/search?q=%E3%83%87%E3%82%A3%E3%83%AB&lang=ja",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"matched":"ディルック"`},
+			TestAppFactory:  withDictionaries,
+			AfterTestFunc:   firstResult("characterdiluc0", "ディルック"),
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestPlansList(t *testing.T) {
+	setup := func(t testing.TB, app *tests.TestApp) map[string]string {
+		testutil.SeedDictionaries(t, app)
+		user, token := testutil.CreateUser(t, app, "user@test.com")
+		testutil.SeedPlans(t, app, user.Id)
+		testutil.CreateUser(t, app, "other@test.com")
+		return map[string]string{"user": token}
+	}
+	scenario := func(name string, url string, status int, content []string, notContent []string) tests.ApiScenario {
+		return userScenario(name, setup, "user", http.MethodGet, url, "", status, content, notContent)
+	}
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "anonymous",
+			Method:          http.MethodGet,
+			URL:             "/api/plans",
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"status":401`},
+			TestAppFactory:  testApp(nil),
+		},
+		scenario("incomplete plans by default", "/api/plans", http.StatusOK,
+			[]string{`"totalItems":1`, `"id":"characterplan00"`, `"expand":`, `"Aquila Favonia"`, `"facets":`, `"elements":["elementpyro0000"]`},
+			[]string{`"id":"characterplan01"`}),
+		scenario("complete plans included", "/api/plans?complete=true", http.StatusOK,
+			[]string{`"totalItems":2`, `"id":"characterplan01"`}, nil),
+		scenario("filtered out", "/api/plans?elements=other", http.StatusOK,
+			[]string{`"totalItems":0`, `"items":[]`}, nil),
+		scenario("second page", "/api/plans?complete=true&perPage=1&page=2", http.StatusOK,
+			[]string{`"page":2`, `"perPage":1`, `"totalPages":2`, `"id":"characterplan01"`},
+			[]string{`"id":"characterplan00"`}),
+		scenario("invalid complete", "/api/plans?complete=maybe", http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+		scenario("invalid page", "/api/plans?page=0", http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+		scenario("sorted", "/api/plans?complete=true&sort=-order", http.StatusOK,
+			[]string{`"items":[{"id":"characterplan01"`}, nil),
+		scenario("invalid sort", "/api/plans?sort=name", http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+		{
+			Name:            "all plans anonymous",
+			Method:          http.MethodGet,
+			URL:             "/api/plans/all",
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"status":401`},
+			TestAppFactory:  testApp(nil),
+		},
+		scenario("all plans", "/api/plans/all", http.StatusOK,
+			[]string{`"id":"characterplan00"`, `"id":"characterplan01"`, `"completion":88`, `"artifactSets":["artsetgladiator"]`, `"weaponPlans":[]`},
+			[]string{`"expand"`}),
+		scenario("completion through the plans view", "/api/collections/plans/records?filter=(id='characterplan00')", http.StatusOK,
+			[]string{`"totalItems":1`, `"completion":88`, `"completeSuggested":false`}, nil),
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestPlansDue(t *testing.T) {
+	scenario := func(name string, url string, currentPatch string, status int, content []string, notContent []string) tests.ApiScenario {
+		setup := func(t testing.TB, app *tests.TestApp) map[string]string {
+			testutil.SeedDictionaries(t, app)
+			user, token := testutil.CreateUser(t, app, "user@test.com")
+			testutil.SeedPlans(t, app, user.Id)
+			testutil.CreateRecord(t, app, models.PATCH_COLLECTION_NAME, "patch5dot200000", map[string]any{"major": 5, "patch": 2})
+			plan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00")
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan.Load(map[string]any{"priority": 2, "targetPatch": "patch5dot100000"})
+			if err := app.Save(plan); err != nil {
+				t.Fatal(err)
+			}
+			testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{
+				"user": user.Id, "character": "characterdiluc0", "order": 3, "priority": 3, "targetPatch": "patch5dot200000",
+			})
+			if currentPatch != "" {
+				if _, err := models.CreateAppSettings(app, plans.CURRENT_PATCH_SETTING_KEY, currentPatch); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return map[string]string{"user": token}
+		}
+		return userScenario(name, setup, "user", http.MethodGet, url, "", status, content, notContent)
+	}
+	scenarios := []tests.ApiScenario{
+		scenario("due by the latest patch", "/api/plans/due", "", http.StatusOK,
+			[]string{`"next":null`, `"items":[{"id":"characterplan00"`, `"id":"characterplan02"`, `"targetPatch":{`},
+			[]string{`"id":"characterplan01"`}),
+		scenario("due by the pinned current patch", "/api/plans/due?by=current", "5.1", http.StatusOK,
+			[]string{`"id":"characterplan00"`, `"next":{"collectionId"`},
+			[]string{`"id":"characterplan02"`}),
+		scenario("due by the next patch", "/api/plans/due?by=next", "5.1", http.StatusOK,
+			[]string{`"id":"characterplan00"`, `"id":"characterplan02"`}, nil),
+		scenario("invalid by", "/api/plans/due?by=later", "", http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestPlansReorder(t *testing.T) {
+	setup := func(t testing.TB, app *tests.TestApp) map[string]string {
+		testutil.SeedDictionaries(t, app)
+		user, token := testutil.CreateUser(t, app, "user@test.com")
+		testutil.SeedPlans(t, app, user.Id)
+		other, _ := testutil.CreateUser(t, app, "other@test.com")
+		testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "otherplan000000", map[string]any{
+			"user": other.Id, "character": "characterdiluc0", "order": 1,
+		})
+		return map[string]string{"user": token}
+	}
+	scenario := func(name string, body string, status int, content []string) tests.ApiScenario {
+		return userScenario(name, setup, "user", http.MethodPost, "/api/plans/reorder", body, status, content, nil)
+	}
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "anonymous",
+			Method:          http.MethodPost,
+			URL:             "/api/plans/reorder",
+			Body:            strings.NewReader(`{"collection":"characterPlans","ids":["characterplan00"]}`),
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"status":401`},
+			TestAppFactory:  testApp(nil),
+		},
+		scenario("reorder", `{"collection":"characterPlans","ids":["characterplan01","characterplan00"]}`, http.StatusOK,
+			[]string{`[{"id":"characterplan01","order":1},{"id":"characterplan00","order":2}]`}),
+		scenario("unordered collection", `{"collection":"teamPlans","ids":["teamplan0000000"]}`, http.StatusBadRequest,
+			[]string{`"status":400`}),
+		scenario("no ids", `{"collection":"characterPlans","ids":[]}`, http.StatusBadRequest,
+			[]string{`"status":400`}),
+		scenario("plan of another user", `{"collection":"characterPlans","ids":["characterplan00","otherplan000000"]}`, http.StatusNotFound,
+			[]string{`"status":404`}),
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestPlansClone(t *testing.T) {
+	setup := func(t testing.TB, app *tests.TestApp) map[string]string {
+		testutil.SeedDictionaries(t, app)
+		user, token := testutil.CreateUser(t, app, "user@test.com")
+		testutil.SeedPlans(t, app, user.Id)
+		_, otherToken := testutil.CreateUser(t, app, "other@test.com")
+		return map[string]string{"user": token, "other": otherToken}
+	}
+	scenario := func(name string, as string, body string, status int, content []string) tests.ApiScenario {
+		return userScenario(name, setup, as, http.MethodPost, "/api/plans/characterplan00/clone", body, status, content, nil)
+	}
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "anonymous",
+			Method:          http.MethodPost,
+			URL:             "/api/plans/characterplan00/clone",
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"status":401`},
+			TestAppFactory:  testApp(nil),
+		},
+		scenario("clone", "user", "", http.StatusOK,
+			[]string{`"order":2`, `"levelCurrent":80`, `"weapon":"weaponaquila000"`}),
+		scenario("clone with reset", "user", `{"resetCurrent":true}`, http.StatusOK,
+			[]string{`"order":2`, `"levelCurrent":1`, `"levelTarget":90`}),
+		scenario("plan of another user", "other", "", http.StatusNotFound,
+			[]string{`"status":404`}),
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestPlansProfiles(t *testing.T) {
+	setup := func(t testing.TB, app *tests.TestApp) map[string]string {
+		testutil.SeedDictionaries(t, app)
+		user, token := testutil.CreateUser(t, app, "user@test.com")
+		testutil.SeedPlans(t, app, user.Id)
+		testutil.CreateRecord(t, app, models.GAME_PROFILES_COLLECTION_NAME, "profilealt00000", map[string]any{
+			"user": user.Id, "name": "Alt",
+		})
+		testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "altplan00000000", map[string]any{
+			"user": user.Id, "profile": "profilealt00000", "character": "characterdiluc0", "order": 3,
+		})
+		other, _ := testutil.CreateUser(t, app, "other@test.com")
+		testutil.CreateRecord(t, app, models.GAME_PROFILES_COLLECTION_NAME, "profileother000", map[string]any{
+			"user": other.Id, "name": "Main",
+		})
+		return map[string]string{"user": token}
+	}
+	scenario := func(name string, method string, url string, body string, status int, content []string, notContent []string) tests.ApiScenario {
+		return userScenario(name, setup, "user", method, url, body, status, content, notContent)
+	}
+	scenarios := []tests.ApiScenario{
+		scenario("all plans of a profile", http.MethodGet, "/api/plans/all?profile=profilealt00000", "", http.StatusOK,
+			[]string{`"id":"altplan00000000"`, `"profile":"profilealt00000"`}, []string{`"id":"characterplan00"`}),
+		scenario("all plans of every profile", http.MethodGet, "/api/plans/all", "", http.StatusOK,
+			[]string{`"id":"altplan00000000"`, `"id":"characterplan00"`}, nil),
+		scenario("filtered plans of a profile", http.MethodGet, "/api/plans?profile=profilealt00000", "", http.StatusOK,
+			[]string{`"totalItems":1`, `"id":"altplan00000000"`}, nil),
+		scenario("profile of another user", http.MethodGet, "/api/plans/all?profile=profileother000", "", http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("reorder out of the profile", http.MethodPost, "/api/plans/reorder",
+			`{"collection":"characterPlans","ids":["altplan00000000","characterplan00"],"profile":"profilealt00000"}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("clone to another profile", http.MethodPost, "/api/plans/characterplan00/clone", `{"profile":"profilealt00000"}`, http.StatusOK,
+			[]string{`"profile":"profilealt00000"`, `"order":2`}, nil),
+		scenario("clone to a profile of another user", http.MethodPost, "/api/plans/characterplan00/clone", `{"profile":"profileother000"}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("profiles listed through the rules", http.MethodGet, "/api/collections/gameProfiles/records", "", http.StatusOK,
+			[]string{`"totalItems":2`, `"name":"Alt"`}, []string{`"id":"profileother000"`}),
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+
+	// the body needs the id of the user, it is written once the factory
+	// created them
+	for _, createCase := range []struct {
+		name       string
+		profile    string
+		status     int
+		content    []string
+		notContent []string
+	}{
+		{"plan created without a profile", "", http.StatusOK, []string{`"profile":"`}, []string{`"profile":""`}},
+		{"plan created in a profile of another user", "profileother000", http.StatusBadRequest, []string{`"status":400`}, nil},
+	} {
+		body := &bytes.Buffer{}
+		headers := map[string]string{"Content-Type": "application/json"}
+		scenario := tests.ApiScenario{
+			Name:               createCase.name,
+			Method:             http.MethodPost,
+			URL:                "/api/collections/characterPlans/records",
+			Body:               body,
+			Headers:            headers,
+			ExpectedStatus:     createCase.status,
+			ExpectedContent:    createCase.content,
+			NotExpectedContent: createCase.notContent,
+			TestAppFactory: testApp(func(t testing.TB, app *tests.TestApp) {
+				testutil.SeedDictionaries(t, app)
+				user, token := testutil.CreateUser(t, app, "user@test.com")
+				other, _ := testutil.CreateUser(t, app, "other@test.com")
+				testutil.CreateRecord(t, app, models.GAME_PROFILES_COLLECTION_NAME, "profileother000", map[string]any{
+					"user": other.Id, "name": "Main",
+				})
+				if err := json.NewEncoder(body).Encode(map[string]any{
+					"user": user.Id, "profile": createCase.profile, "character": "characterdiluc0", "order": 1,
+				}); err != nil {
+					t.Fatal(err)
+				}
+				headers["Authorization"] = token
+			}),
+		}
+		scenario.Test(t)
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	setup := func(t testing.TB, app *tests.TestApp) map[string]string {
+		testutil.SeedDictionaries(t, app)
+		tokens := map[string]string{}
+		users := map[string]*core.Record{}
+		for _, email := range []string{"owner", "editor", "viewer", "outsider"} {
+			users[email], tokens[email] = testutil.CreateUser(t, app, email+"@test.com")
+		}
+		testutil.SeedPlans(t, app, users["owner"].Id)
+		testutil.CreateRecord(t, app, models.WORKSPACES_COLLECTION_NAME, "workspace000000", map[string]any{
+			"name": "Abyss",
+		})
+		for _, role := range models.WORKSPACE_ROLES {
+			testutil.CreateRecord(t, app, models.WORKSPACE_MEMBERS_COLLECTION_NAME, "member"+role+strings.Repeat("0", 9-len(role)), map[string]any{
+				"workspace": "workspace000000", "user": users[role].Id, "role": role,
+			})
+		}
+		// a workspace of the editor alone
+		testutil.CreateRecord(t, app, models.WORKSPACES_COLLECTION_NAME, "workspace000001", map[string]any{
+			"name": "Domain",
+		})
+		testutil.CreateRecord(t, app, models.WORKSPACE_MEMBERS_COLLECTION_NAME, "membereditor001", map[string]any{
+			"workspace": "workspace000001", "user": users["editor"].Id, "role": models.WORKSPACE_ROLE_OWNER,
+		})
+		plan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00")
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan.Set("workspace", "workspace000000")
+		if err := app.Save(plan); err != nil {
+			t.Fatal(err)
+		}
+		testutil.CreateRecord(t, app, models.WORKSPACE_INVITATIONS_COLLECTION_NAME, "invitation00000", map[string]any{
+			"workspace": "workspace000000", "role": models.WORKSPACE_ROLE_VIEWER,
+			"token": "invitationtoken", "expires": time.Now().Add(time.Hour),
+		})
+		testutil.CreateRecord(t, app, models.WORKSPACE_INVITATIONS_COLLECTION_NAME, "invitation00001", map[string]any{
+			"workspace": "workspace000000", "role": models.WORKSPACE_ROLE_VIEWER,
+			"token": "expiredtoken", "expires": time.Now().Add(-time.Hour),
+		})
+		return tokens
+	}
+	scenario := func(name string, as string, method string, url string, body string, status int, content []string, notContent []string) tests.ApiScenario {
+		return userScenario(name, setup, as, method, url, body, status, content, notContent)
+	}
+	scenarios := []tests.ApiScenario{
+		scenario("workspace plans of a viewer", "viewer", http.MethodGet, "/api/plans/all?workspace=workspace000000", "", http.StatusOK,
+			[]string{`"id":"characterplan00"`, `"workspace":"workspace000000"`, `"id":"weaponplan00000"`}, []string{`"id":"characterplan01"`}),
+		scenario("filtered workspace plans", "editor", http.MethodGet, "/api/plans?workspace=workspace000000", "", http.StatusOK,
+			[]string{`"totalItems":1`, `"id":"characterplan00"`}, nil),
+		scenario("workspace plans of an outsider", "outsider", http.MethodGet, "/api/plans/all?workspace=workspace000000", "", http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("workspace plans listed through the rules", "viewer", http.MethodGet, "/api/collections/characterPlans/records", "", http.StatusOK,
+			[]string{`"totalItems":1`, `"id":"characterplan00"`}, nil),
+		scenario("workspace plans hidden from an outsider", "outsider", http.MethodGet, "/api/collections/characterPlans/records", "", http.StatusOK,
+			[]string{`"totalItems":0`}, nil),
+		scenario("plan update by a viewer", "viewer", http.MethodPatch, "/api/collections/characterPlans/records/characterplan00", `{"levelCurrent":85}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("plan update by an editor", "editor", http.MethodPatch, "/api/collections/characterPlans/records/characterplan00", `{"levelCurrent":85}`, http.StatusOK,
+			[]string{`"levelCurrent":85`}, nil),
+		scenario("plan handed to another user by an editor", "editor", http.MethodPatch, "/api/collections/characterPlans/records/characterplan00", `{"user":"someoneelse0000"}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("plan taken out of the workspace by an editor", "editor", http.MethodPatch, "/api/collections/characterPlans/records/characterplan00", `{"workspace":""}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("plan moved to another workspace by an editor", "editor", http.MethodPatch, "/api/collections/characterPlans/records/characterplan00", `{"workspace":"workspace000001"}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("plan taken out of the workspace by its user", "owner", http.MethodPatch, "/api/collections/characterPlans/records/characterplan00", `{"workspace":""}`, http.StatusOK,
+			[]string{`"workspace":""`}, nil),
+		scenario("workspace plans reordered by an editor", "editor", http.MethodPost, "/api/plans/reorder", `{"collection":"characterPlans","ids":["characterplan00"]}`, http.StatusOK,
+			[]string{`"id":"characterplan00"`, `"order":1`}, nil),
+		scenario("workspace plans reordered by a viewer", "viewer", http.MethodPost, "/api/plans/reorder", `{"collection":"characterPlans","ids":["characterplan00"]}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("workspace child plans reordered by an editor", "editor", http.MethodPost, "/api/plans/reorder", `{"collection":"weaponPlans","ids":["weaponplan00000"]}`, http.StatusOK,
+			[]string{`"id":"weaponplan00000"`}, nil),
+		scenario("workspace plan cloned by an editor", "editor", http.MethodPost, "/api/plans/characterplan00/clone", "", http.StatusOK,
+			[]string{`"workspace":"workspace000000"`, `"order":2`, `"weaponPlans":[{`}, []string{`"id":"characterplan00"`}),
+		scenario("workspace plan cloned by a viewer", "viewer", http.MethodPost, "/api/plans/characterplan00/clone", "", http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("child plan update by a viewer", "viewer", http.MethodPatch, "/api/collections/weaponPlans/records/weaponplan00000", `{"levelCurrent":80}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("child plan update by an editor", "editor", http.MethodPatch, "/api/collections/weaponPlans/records/weaponplan00000", `{"levelCurrent":80}`, http.StatusOK,
+			[]string{`"levelCurrent":80`}, nil),
+		scenario("invitation by an owner", "owner", http.MethodPost, "/api/workspaces/workspace000000/invitations", `{"role":"editor","email":"friend@test.com"}`, http.StatusOK,
+			[]string{`"role":"editor"`, `"email":"friend@test.com"`, `"token":"`}, nil),
+		scenario("invitation by an editor", "editor", http.MethodPost, "/api/workspaces/workspace000000/invitations", `{"role":"editor"}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("invitation with an unknown role", "owner", http.MethodPost, "/api/workspaces/workspace000000/invitations", `{"role":"admin"}`, http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+		scenario("invitation accepted", "outsider", http.MethodPost, "/api/workspaces/invitations/invitationtoken/accept", "", http.StatusOK,
+			[]string{`"workspace":"workspace000000"`, `"role":"viewer"`}, nil),
+		scenario("invitation accepted by a member", "viewer", http.MethodPost, "/api/workspaces/invitations/invitationtoken/accept", "", http.StatusConflict,
+			[]string{`"status":409`}, nil),
+		scenario("expired invitation", "outsider", http.MethodPost, "/api/workspaces/invitations/expiredtoken/accept", "", http.StatusGone,
+			[]string{`"status":410`}, nil),
+		scenario("unknown invitation", "outsider", http.MethodPost, "/api/workspaces/invitations/unknown/accept", "", http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("last owner removed", "owner", http.MethodDelete, "/api/collections/workspaceMembers/records/memberowner0000", "", http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+		scenario("last owner demoted", "owner", http.MethodPatch, "/api/collections/workspaceMembers/records/memberowner0000", `{"role":"editor"}`, http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+		scenario("member promoted by the owner", "owner", http.MethodPatch, "/api/collections/workspaceMembers/records/membereditor000", `{"role":"owner"}`, http.StatusOK,
+			[]string{`"role":"owner"`}, nil),
+		scenario("member promoting themselves", "editor", http.MethodPatch, "/api/collections/workspaceMembers/records/membereditor000", `{"role":"owner"}`, http.StatusNotFound,
+			[]string{`"status":404`}, nil),
+		scenario("viewer leaving", "viewer", http.MethodDelete, "/api/collections/workspaceMembers/records/memberviewer000", "", http.StatusNoContent,
+			nil, nil),
+		scenario("workspace created", "outsider", http.MethodPost, "/api/collections/workspaces/records", `{"name":"Theater"}`, http.StatusOK,
+			[]string{`"name":"Theater"`, `"owners":["`}, []string{`"owners":[]`}),
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestPlansProgress(t *testing.T) {
+	setup := func(t testing.TB, app *tests.TestApp) map[string]string {
+		testutil.SeedDictionaries(t, app)
+		user, token := testutil.CreateUser(t, app, "user@test.com")
+		testutil.SeedPlans(t, app, user.Id)
+		other, _ := testutil.CreateUser(t, app, "other@test.com")
+		testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "otherplan000000", map[string]any{
+			"user": other.Id, "character": "characterdiluc0", "order": 1,
+		})
+		plan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00")
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan.Set("levelCurrent", 90)
+		if err := app.Save(plan); err != nil {
+			t.Fatal(err)
+		}
+		return map[string]string{"user": token}
+	}
+	scenario := func(name string, url string, status int, content []string, notContent []string) tests.ApiScenario {
+		return userScenario(name, setup, "user", http.MethodGet, url, "", status, content, notContent)
+	}
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "anonymous",
+			Method:          http.MethodGet,
+			URL:             "/api/plans/progress",
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"status":401`},
+			TestAppFactory:  testApp(nil),
+		},
+		scenario("the last weeks", "/api/plans/progress", http.StatusOK,
+			[]string{`"levelsGained":10`, `"plan":"characterplan00"`, `"plan":"characterplan01"`, `"level":90`},
+			[]string{`"plan":"otherplan000000"`}),
+		scenario("a period without snapshots", "/api/plans/progress?from=2025-01-01&to=2025-02-01", http.StatusOK,
+			[]string{`"plans":[]`, `"week":"2024-12-30 00:00:00.000Z"`, `"levelsGained":0`}, nil),
+		scenario("invalid from", "/api/plans/progress?from=yesterday", http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+		scenario("reversed period", "/api/plans/progress?from=2025-02-01&to=2025-01-01", http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+		scenario("too long period", "/api/plans/progress?from=2020-01-01&to=2025-01-01", http.StatusBadRequest,
+			[]string{`"status":400`}, nil),
+		scenario("snapshots listed through the rules", "/api/collections/planSnapshots/records", http.StatusOK,
+			[]string{`"characterPlan":"characterplan00"`}, []string{`"characterPlan":"otherplan000000"`}),
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
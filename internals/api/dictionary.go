@@ -0,0 +1,140 @@
+package api
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+
+	"github.com/qxuken/gbp/internals/fuzzy"
+	"github.com/qxuken/gbp/internals/i18n"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// searchCollections are the dictionaries the search goes through, in the
+// order the equally ranked results are listed.
+var searchCollections = []string{
+	models.CHARACTERS_COLLECTION_NAME,
+	models.WEAPONS_COLLECTION_NAME,
+	models.ARTIFACT_SETS_COLLECTION_NAME,
+}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type searchResult struct {
+	Collection string `json:"collection"`
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Icon       string `json:"icon"`
+	// Matched is the name, alias or translation the query matched best
+	Matched string `json:"matched"`
+	Score   int    `json:"score"`
+}
+
+// searchCollection scores the records of the collection against the query
+// over their names, aliases and translations in the locales.
+func searchCollection(app core.App, collection string, query string, locales []string) ([]searchResult, error) {
+	records, err := app.FindAllRecords(collection)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.Id
+	}
+	translations, err := models.FindTranslations(app, collection, ids, locales)
+	if err != nil {
+		return nil, err
+	}
+	translated := map[string][]string{}
+	for _, t := range translations {
+		translated[t.RecordId()] = append(translated[t.RecordId()], t.Name())
+	}
+
+	results := []searchResult{}
+	matchedRecords := []*core.Record{}
+	for _, record := range records {
+		best := searchResult{}
+		candidates := slices.Concat([]string{record.GetString("name")}, record.GetStringSlice("aliases"), translated[record.Id])
+		for _, candidate := range candidates {
+			if score := fuzzy.Score(query, candidate); score > best.Score {
+				best.Score = score
+				best.Matched = candidate
+			}
+		}
+		if best.Score == 0 {
+			continue
+		}
+		best.Collection = collection
+		best.Id = record.Id
+		best.Icon = record.GetString("icon")
+		results = append(results, best)
+		matchedRecords = append(matchedRecords, record)
+	}
+	if err := i18n.Localize(app, collection, matchedRecords, locales); err != nil {
+		return nil, err
+	}
+	for i, record := range matchedRecords {
+		results[i].Name = record.GetString("name")
+	}
+	return results, nil
+}
+
+func bindDictionaryRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent]) {
+	// GET /api/dictionary/search?q=raiden&limit=20&collections=characters,weapons
+	g.GET("/dictionary/search", func(e *core.RequestEvent) error {
+		query := e.Request.URL.Query()
+		q := strings.TrimSpace(query.Get("q"))
+		if fuzzy.Normalize(q) == "" {
+			return e.BadRequestError("The q param is required.", nil)
+		}
+		limit := defaultSearchLimit
+		if raw := query.Get("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 1 {
+				return e.BadRequestError("The limit param has to be a positive number.", nil)
+			}
+			limit = min(parsed, maxSearchLimit)
+		}
+		collections := searchCollections
+		if raw := query.Get("collections"); raw != "" {
+			collections = strings.Split(raw, ",")
+			for _, collection := range collections {
+				if !slices.Contains(searchCollections, collection) {
+					return e.BadRequestError("Unknown collection "+strconv.Quote(collection)+".", nil)
+				}
+			}
+		}
+
+		locales := i18n.RequestLocales(e.Request)
+		e.Response.Header().Add("Vary", "Accept-Language")
+		results := []searchResult{}
+		for _, collection := range collections {
+			collectionResults, err := searchCollection(app, collection, q, locales)
+			if err != nil {
+				return err
+			}
+			results = append(results, collectionResults...)
+		}
+		slices.SortStableFunc(results, func(a, b searchResult) int {
+			if c := cmp.Compare(b.Score, a.Score); c != 0 {
+				return c
+			}
+			if c := cmp.Compare(slices.Index(searchCollections, a.Collection), slices.Index(searchCollections, b.Collection)); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Name, b.Name)
+		})
+		if len(results) > limit {
+			results = results[:limit]
+		}
+		return e.JSON(http.StatusOK, results)
+	})
+}
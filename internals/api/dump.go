@@ -2,46 +2,64 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/router"
 
+	"github.com/qxuken/gbp/internals/jobs"
 	"github.com/qxuken/gbp/internals/models"
 	"github.com/qxuken/gbp/internals/seed"
 )
 
 // bindDumpRoutes registers the superuser gated seed management routes plus the
 // public endpoints exposing the latest dump.
-func bindDumpRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent], latestDumpCache *models.LatestDbDumpCache) {
+//
+// The generate, upload and restore routes only validate the request and hand
+// the work over to a background job, they respond with its id.
+func bindDumpRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent], latestDumpCache *models.LatestDbDumpCache, jobRunner *jobs.Runner) {
 	g.POST("/dump/generate", func(e *core.RequestEvent) error {
 		if !e.HasSuperuserAuth() {
 			return e.UnauthorizedError("", nil)
 		}
 		data := struct {
-			Notes string `json:"notes" form:"notes"`
+			Notes         string `json:"notes" form:"notes"`
+			GenerateNotes bool   `json:"generateNotes" form:"generateNotes"`
+			Compression   string `json:"compression" form:"compression"`
 		}{}
 		if err := e.BindBody(&data); err != nil {
 			return e.BadRequestError("Failed to read request data", err)
 		}
-		tmpFile, err := os.CreateTemp(app.DataDir(), "*-dump.db")
+		compression, err := seed.ParseCompression(data.Compression)
 		if err != nil {
-			return e.InternalServerError(err.Error(), nil)
-		}
-		tmpPath := tmpFile.Name()
-		tmpFile.Close()
-		defer os.Remove(tmpPath)
-		err = seed.Dump(app, tmpPath, data.Notes)
-		if err != nil {
-			return e.InternalServerError(err.Error(), nil)
-		}
-		err = seed.UpdateDictionaryVersion(app, tmpPath)
-		if err != nil {
-			return e.InternalServerError(err.Error(), nil)
+			return e.BadRequestError(err.Error(), nil)
 		}
-		return e.JSON(http.StatusOK, map[string]any{"status": "ok"})
+		job, err := jobRunner.Start("generate", false, func(app core.App, progress func(string, int, int)) (any, error) {
+			tmpFile, err := os.CreateTemp(app.DataDir(), "*-dump.db")
+			if err != nil {
+				return nil, err
+			}
+			tmpPath := tmpFile.Name()
+			tmpFile.Close()
+			defer os.Remove(tmpPath)
+			err = seed.DumpWithOptions(app, tmpPath, seed.DumpOptions{
+				Notes:         data.Notes,
+				GenerateNotes: data.GenerateNotes,
+				Compression:   compression,
+				Progress:      progress,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return nil, seed.UpdateDictionaryVersion(app, tmpPath)
+		})
+		return jobStartedResponse(e, job, err)
 	})
 
 	g.POST("/dump/upload", func(e *core.RequestEvent) error {
@@ -59,25 +77,28 @@ func bindDumpRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent], lat
 			return e.InternalServerError(err.Error(), nil)
 		}
 		tmpPath := tmpFile.Name()
-		defer os.Remove(tmpPath)
 		// the upload is streamed to disk, a large dump doesn't fit in memory
 		// and a single Read is allowed to return less than the full file
 		if _, err = io.Copy(tmpFile, mf); err != nil {
 			tmpFile.Close()
+			os.Remove(tmpPath)
 			return e.InternalServerError(err.Error(), nil)
 		}
 		if err = tmpFile.Close(); err != nil {
+			os.Remove(tmpPath)
 			return e.InternalServerError(err.Error(), nil)
 		}
-		err = seed.SaveDump(app, tmpPath, notes)
-		if err != nil {
-			return e.InternalServerError(err.Error(), nil)
-		}
-		err = seed.Seed(app, tmpPath)
+		job, err := jobRunner.Start("upload", true, func(app core.App, progress func(string, int, int)) (any, error) {
+			defer os.Remove(tmpPath)
+			if err := seed.SaveDump(app, tmpPath, notes); err != nil {
+				return nil, err
+			}
+			return nil, seed.SeedWithOptions(app, tmpPath, seed.SeedOptions{Progress: progress})
+		})
 		if err != nil {
-			return e.InternalServerError(err.Error(), nil)
+			os.Remove(tmpPath)
 		}
-		return e.JSON(http.StatusOK, map[string]any{"status": "ok"})
+		return jobStartedResponse(e, job, err)
 	})
 
 	g.POST("/dump/restore/{dumpId}", func(e *core.RequestEvent) error {
@@ -91,11 +112,35 @@ func bindDumpRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent], lat
 		} else if err != nil {
 			return e.InternalServerError(err.Error(), nil)
 		}
-		err = seed.Seed(app, dump.DumpPath(app))
-		if err != nil {
+		data := struct {
+			Collections []string `json:"collections" form:"collections"`
+		}{}
+		if err := e.BindBody(&data); err != nil {
+			return e.BadRequestError("Failed to read request data", err)
+		}
+		// the selection is checked upfront to reject it with a 400, the seed
+		// checks it again within its transaction
+		opts := seed.SeedOptions{Only: data.Collections}
+		if err := seed.CheckSeedSelection(app, dump.DumpPath(app), opts); errors.Is(err, seed.ErrInvalidSelection) {
+			return e.BadRequestError(err.Error(), nil)
+		} else if err != nil {
 			return e.InternalServerError(err.Error(), nil)
 		}
-		return e.JSON(http.StatusOK, map[string]any{"status": "ok"})
+		job, err := jobRunner.Start("restore", true, func(app core.App, progress func(string, int, int)) (any, error) {
+			opts.Progress = progress
+			return nil, seed.SeedWithOptions(app, dump.DumpPath(app), opts)
+		})
+		return jobStartedResponse(e, job, err)
+	})
+
+	g.POST("/dump/rollback", func(e *core.RequestEvent) error {
+		if !e.HasSuperuserAuth() {
+			return e.UnauthorizedError("", nil)
+		}
+		job, err := jobRunner.Start("rollback", true, func(app core.App, progress func(string, int, int)) (any, error) {
+			return seed.Rollback(app, seed.RollbackOptions{Progress: progress})
+		})
+		return jobStartedResponse(e, job, err)
 	})
 
 	g.GET("/dump/latest", func(e *core.RequestEvent) error {
@@ -111,6 +156,25 @@ func bindDumpRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent], lat
 		})
 	})
 
+	g.GET("/dump/changelog", func(e *core.RequestEvent) error {
+		dumps, err := models.FindAllDbDumps(app)
+		if err != nil {
+			return e.InternalServerError(err.Error(), nil)
+		}
+		if e.Request.URL.Query().Get("format") == "markdown" {
+			return e.Blob(http.StatusOK, "text/markdown; charset=utf-8", []byte(changelogMarkdown(dumps)))
+		}
+		items := make([]map[string]any, 0, len(dumps))
+		for _, dump := range dumps {
+			items = append(items, map[string]any{
+				"hash":    dump.Hash(),
+				"notes":   dump.Notes(),
+				"created": dump.GetDateTime("created"),
+			})
+		}
+		return e.JSON(http.StatusOK, items)
+	})
+
 	g.GET("/dump/latest_seed.db", func(e *core.RequestEvent) error {
 		latestDump, err := latestDumpCache.Get(app)
 		if err == sql.ErrNoRows {
@@ -118,6 +182,83 @@ func bindDumpRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent], lat
 		} else if err != nil {
 			return e.InternalServerError(err.Error(), nil)
 		}
-		return e.FileFS(os.DirFS(latestDump.DumpDir(app)), latestDump.DumpFilename())
+		e.Response.Header().Add("Vary", "Accept-Encoding")
+		e.Response.Header().Set("Content-Type", "application/vnd.sqlite3")
+		dir := os.DirFS(latestDump.DumpDir(app))
+		encoding := latestDump.Encoding()
+		acceptEncoding := e.Request.Header.Get("Accept-Encoding")
+		// the stored file or its precompressed variant goes out as is when the
+		// client can decode it, see seed.SaveDump
+		for _, variant := range seed.DumpVariants {
+			if !acceptsEncoding(acceptEncoding, string(variant)) {
+				continue
+			}
+			name := latestDump.DumpFilename()
+			if string(variant) != encoding {
+				name += variant.Ext()
+				// the dumps stored before the variants have none
+				if _, err := fs.Stat(dir, name); err != nil {
+					continue
+				}
+			}
+			e.Response.Header().Set("Content-Encoding", string(variant))
+			return e.FileFS(dir, name)
+		}
+		if encoding == "" {
+			return e.FileFS(dir, latestDump.DumpFilename())
+		}
+		r, _, err := seed.OpenSeedFile(latestDump.DumpPath(app))
+		if err != nil {
+			return e.InternalServerError(err.Error(), nil)
+		}
+		defer r.Close()
+		return e.Stream(http.StatusOK, "application/vnd.sqlite3", r)
 	})
 }
+
+// changelogMarkdown renders the notes of the dumps as a single document, a
+// section per dump headed by its date.
+func changelogMarkdown(dumps []*models.DbDump) string {
+	var b strings.Builder
+	b.WriteString("# Changelog\n")
+	for _, dump := range dumps {
+		notes := strings.TrimSpace(dump.Notes())
+		if notes == "" {
+			continue
+		}
+		b.WriteString("\n## ")
+		b.WriteString(dump.GetDateTime("created").Time().Format("2006-01-02"))
+		b.WriteString("\n\n")
+		b.WriteString(notes)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header allows the
+// given content coding, honouring the "*" wildcard and q=0 exclusions.
+func acceptsEncoding(header string, encoding string) bool {
+	accepted := false
+	for _, part := range strings.Split(header, ",") {
+		token, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		token = strings.ToLower(strings.TrimSpace(token))
+		if token != encoding && token != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(k) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if token == encoding {
+			// an explicit entry wins over the wildcard
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
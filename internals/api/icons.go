@@ -0,0 +1,40 @@
+package api
+
+import (
+	"database/sql"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+
+	"github.com/qxuken/gbp/internals/icons"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// bindIconsRoutes serves the normalized icons by their content hash, the
+// response of a hash never changes so it's cached for good. ?thumb=1 serves
+// the thumbnail instead.
+func bindIconsRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent]) {
+	g.GET("/icons/{hash}", func(e *core.RequestEvent) error {
+		hash, ok := icons.HashFromName(icons.FileName(e.Request.PathValue("hash")))
+		if !ok {
+			return e.NotFoundError("", nil)
+		}
+		icon, err := models.FindIconByHash(app, hash)
+		if err == sql.ErrNoRows {
+			return e.NotFoundError("", nil)
+		} else if err != nil {
+			return err
+		}
+		filename := icon.ImageFilename()
+		if e.Request.URL.Query().Get("thumb") == "1" {
+			filename = icon.ThumbFilename()
+		}
+		fsys, err := app.NewFilesystem()
+		if err != nil {
+			return err
+		}
+		defer fsys.Close()
+		e.Response.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		return fsys.Serve(e.Response, e.Request, icon.BaseFilesPath()+"/"+filename, filename)
+	})
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+
+	"github.com/qxuken/gbp/internals/jobs"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// jobStartedResponse answers a request that queued a background job.
+func jobStartedResponse(e *core.RequestEvent, job *models.Job, err error) error {
+	if errors.Is(err, jobs.ErrBusy) {
+		return e.Error(http.StatusConflict, err.Error(), nil)
+	} else if err != nil {
+		return e.InternalServerError(err.Error(), nil)
+	}
+	return e.JSON(http.StatusAccepted, map[string]any{
+		"status": "queued",
+		"jobId":  job.Id,
+		"topic":  jobs.TOPIC_PREFIX + job.Id,
+	})
+}
+
+// bindJobsRoutes exposes the state of the background jobs to the superusers,
+// the live progress is also pushed on the "jobs/{id}" realtime topic.
+func bindJobsRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent], jobRunner *jobs.Runner) {
+	g.GET("/jobs/{id}", func(e *core.RequestEvent) error {
+		if !e.HasSuperuserAuth() {
+			return e.UnauthorizedError("", nil)
+		}
+		if job, progress, ok := jobRunner.Job(e.Request.PathValue("id")); ok {
+			return e.JSON(http.StatusOK, jobs.Payload(job, progress))
+		}
+		job, err := models.FindJobById(app, e.Request.PathValue("id"))
+		if err == sql.ErrNoRows {
+			return e.NotFoundError("Job not found", nil)
+		} else if err != nil {
+			return e.InternalServerError(err.Error(), nil)
+		}
+		return e.JSON(http.StatusOK, jobs.Payload(job, nil))
+	})
+}
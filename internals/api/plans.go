@@ -1,14 +1,119 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/pocketbase/pocketbase/tools/types"
 
+	"github.com/qxuken/gbp/internals/i18n"
 	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
+	"github.com/qxuken/gbp/internals/progress"
+	"github.com/qxuken/gbp/internals/seed"
+	"github.com/qxuken/gbp/internals/workspaces"
 )
 
+const (
+	defaultPlansPerPage = 30
+	maxPlansPerPage     = 200
+)
+
+const (
+	defaultProgressPeriod = 12 * 7 * 24 * time.Hour
+	maxProgressPeriod     = 2 * 366 * 24 * time.Hour
+)
+
+type plansPage struct {
+	Page       int          `json:"page"`
+	PerPage    int          `json:"perPage"`
+	TotalItems int          `json:"totalItems"`
+	TotalPages int          `json:"totalPages"`
+	Items      []plans.Plan `json:"items"`
+	Facets     plans.Facets `json:"facets"`
+}
+
+// positiveQueryInt reads an optional positive int param.
+func positiveQueryInt(e *core.RequestEvent, key string, fallback int) (int, error) {
+	raw := e.Request.URL.Query().Get(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 {
+		return 0, e.BadRequestError("The "+key+" param has to be a positive number.", nil)
+	}
+	return value, nil
+}
+
+// queryTime reads an optional date or datetime param.
+func queryTime(e *core.RequestEvent, key string, fallback time.Time) (time.Time, error) {
+	raw := e.Request.URL.Query().Get(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := types.ParseDateTime(raw)
+	if err != nil || value.IsZero() {
+		return time.Time{}, e.BadRequestError("The "+key+" param has to be a date.", nil)
+	}
+	return value.Time(), nil
+}
+
+// queryProfile reads the optional profile param, it has to be a game profile
+// of the authenticated user.
+func queryProfile(app core.App, e *core.RequestEvent, profileId string) error {
+	if profileId == "" {
+		return nil
+	}
+	if _, err := plans.FindProfile(app, e.Auth.Id, profileId); err != nil {
+		return e.NotFoundError(err.Error(), nil)
+	}
+	return nil
+}
+
+// requestPlans returns the plans of the authenticated user, or with the
+// workspace param the plans of that workspace of theirs, narrowed to the game
+// profile of the profile param.
+func requestPlans(app core.App, e *core.RequestEvent, plansCache *plans.Cache) ([]plans.Plan, error) {
+	query := e.Request.URL.Query()
+	profileId := query.Get("profile")
+	if err := queryProfile(app, e, profileId); err != nil {
+		return nil, err
+	}
+	var requested []plans.Plan
+	var err error
+	if workspaceId := query.Get("workspace"); workspaceId != "" {
+		if _, err := workspaces.FindMember(app, workspaceId, e.Auth.Id); errors.Is(err, workspaces.ErrWorkspaceNotFound) {
+			return nil, e.NotFoundError(err.Error(), nil)
+		} else if err != nil {
+			return nil, err
+		}
+		requested, err = plansCache.GetWorkspace(app, workspaceId)
+	} else {
+		requested, err = plansCache.Get(app, e.Auth.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plans.InProfile(requested, profileId), nil
+}
+
+type duePlans struct {
+	Current *core.Record `json:"current"`
+	Next    *core.Record `json:"next"`
+	By      *core.Record `json:"by"`
+	Items   []plans.Plan `json:"items"`
+}
+
+type planOrder struct {
+	Id    string `json:"id"`
+	Order int    `json:"order"`
+}
+
 type planCollectionDict struct {
 	Id   string `json:"id"`
 	Name string `json:"name"`
@@ -26,12 +131,241 @@ func loadCollectionsDictionary(app core.App) []planCollectionDict {
 	return plansCollections
 }
 
-func bindPlansRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent]) {
+func bindPlansRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent], plansCache *plans.Cache) {
 	plansCollections := loadCollectionsDictionary(app)
 	g.GET("/plansCollections", func(e *core.RequestEvent) error {
 		return e.JSON(http.StatusOK, plansCollections)
 	})
 
+	// GET /api/plans/all?profile=id&workspace=id returns every plan of the
+	// authenticated user, see requestPlans, with its child plans, in the shape
+	// of the plans view records.
+	g.GET("/plans/all", func(e *core.RequestEvent) error {
+		if e.Auth == nil || e.Auth.Collection().Name != models.USERS_COLLECTION_NAME {
+			return e.UnauthorizedError("", nil)
+		}
+		requested, err := requestPlans(app, e, plansCache)
+		if err != nil {
+			return err
+		}
+		return e.JSON(http.StatusOK, requested)
+	})
+
+	// GET /api/plans lists the plans of the authenticated user filtered like
+	// the plans page, see plans.ParseFilters for the params, with their
+	// dictionary fields expanded and the facets of the filters. The sort param
+	// orders them, see plans.ParseSort. The profile and workspace params pick
+	// the plans like requestPlans.
+	g.GET("/plans", func(e *core.RequestEvent) error {
+		if e.Auth == nil || e.Auth.Collection().Name != models.USERS_COLLECTION_NAME {
+			return e.UnauthorizedError("", nil)
+		}
+		filters, err := plans.ParseFilters(e.Request.URL.Query())
+		if err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		sort, err := plans.ParseSort(e.Request.URL.Query().Get("sort"))
+		if err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		page, err := positiveQueryInt(e, "page", 1)
+		if err != nil {
+			return err
+		}
+		perPage, err := positiveQueryInt(e, "perPage", defaultPlansPerPage)
+		if err != nil {
+			return err
+		}
+		perPage = min(perPage, maxPlansPerPage)
+
+		userPlans, err := requestPlans(app, e, plansCache)
+		if err != nil {
+			return err
+		}
+		e.Response.Header().Add("Vary", "Accept-Language")
+		dictionary, err := plans.LoadDictionary(app, userPlans, i18n.RequestLocales(e.Request))
+		if err != nil {
+			return err
+		}
+		matched := []plans.Plan{}
+		for _, plan := range userPlans {
+			if filters.Match(plan, dictionary.Characters()[plan.Character]) {
+				matched = append(matched, plan)
+			}
+		}
+		if len(sort) > 0 {
+			patches, err := plans.LoadPatches(app)
+			if err != nil {
+				return err
+			}
+			plans.SortPlans(matched, sort, patches)
+		}
+
+		result := plansPage{
+			Page:       page,
+			PerPage:    perPage,
+			TotalItems: len(matched),
+			TotalPages: (len(matched) + perPage - 1) / perPage,
+			Items:      []plans.Plan{},
+			Facets:     plans.NewFacets(userPlans, dictionary.Characters(), filters),
+		}
+		if start := (page - 1) * perPage; start < len(matched) {
+			result.Items = matched[start:min(start+perPage, len(matched))]
+		}
+		dictionary.Expand(result.Items)
+		return e.JSON(http.StatusOK, result)
+	})
+
+	// GET /api/plans/due?by=next lists the incomplete plans of the
+	// authenticated user targeting the current patch or an earlier one, or
+	// with by=next the patch after it, see plans.Patches.Due. The profile and
+	// workspace params pick the plans like requestPlans.
+	g.GET("/plans/due", func(e *core.RequestEvent) error {
+		if e.Auth == nil || e.Auth.Collection().Name != models.USERS_COLLECTION_NAME {
+			return e.UnauthorizedError("", nil)
+		}
+		by := e.Request.URL.Query().Get("by")
+		if by != "" && by != "current" && by != "next" {
+			return e.BadRequestError("The by param has to be current or next.", nil)
+		}
+		requested, err := requestPlans(app, e, plansCache)
+		if err != nil {
+			return err
+		}
+		patches, err := plans.LoadPatches(app)
+		if err != nil {
+			return err
+		}
+		current, next, err := patches.Current(app)
+		if err != nil {
+			return err
+		}
+		result := duePlans{Current: current, Next: next, Items: []plans.Plan{}}
+		// the latest patch has no next one yet, the plans due are those of
+		// the current one
+		result.By = current
+		if by == "next" && next != nil {
+			result.By = next
+		}
+		if result.By != nil {
+			result.Items = patches.Due(requested, result.By.Id)
+		}
+
+		e.Response.Header().Add("Vary", "Accept-Language")
+		dictionary, err := plans.LoadDictionary(app, result.Items, i18n.RequestLocales(e.Request))
+		if err != nil {
+			return err
+		}
+		dictionary.Expand(result.Items)
+		return e.JSON(http.StatusOK, result)
+	})
+
+	// GET /api/plans/progress?from=2025-01-01&to=2025-03-01 returns the
+	// weekly progress of the plans of the authenticated user and the
+	// timelines of the plans, see progress.LoadHistory. The period defaults to
+	// the last 12 weeks. The profile and workspace params pick the plans like
+	// requestPlans.
+	g.GET("/plans/progress", func(e *core.RequestEvent) error {
+		if e.Auth == nil || e.Auth.Collection().Name != models.USERS_COLLECTION_NAME {
+			return e.UnauthorizedError("", nil)
+		}
+		to, err := queryTime(e, "to", time.Now())
+		if err != nil {
+			return err
+		}
+		from, err := queryTime(e, "from", to.Add(-defaultProgressPeriod))
+		if err != nil {
+			return err
+		}
+		if !from.Before(to) {
+			return e.BadRequestError("The from param has to be before the to param.", nil)
+		}
+		if to.Sub(from) > maxProgressPeriod {
+			return e.BadRequestError("The period can't be longer than two years.", nil)
+		}
+		requested, err := requestPlans(app, e, plansCache)
+		if err != nil {
+			return err
+		}
+		history, err := progress.LoadHistory(app, requested, from, to)
+		if err != nil {
+			return err
+		}
+		return e.JSON(http.StatusOK, history)
+	})
+
+	// POST /api/plans/reorder {"collection": "weaponPlans", "ids": [...],
+	// "profile": id} moves the listed plans of the authenticated user, or of a
+	// workspace they can write to, to the given order, see plans.Reorder.
+	g.POST("/plans/reorder", func(e *core.RequestEvent) error {
+		if e.Auth == nil || e.Auth.Collection().Name != models.USERS_COLLECTION_NAME {
+			return e.UnauthorizedError("", nil)
+		}
+		data := struct {
+			Collection string   `json:"collection" form:"collection"`
+			Ids        []string `json:"ids" form:"ids"`
+			Profile    string   `json:"profile" form:"profile"`
+		}{}
+		if err := e.BindBody(&data); err != nil {
+			return e.BadRequestError("Failed to read request data", err)
+		}
+		if err := queryProfile(app, e, data.Profile); err != nil {
+			return err
+		}
+		ordered, err := plans.Reorder(app, e.Auth.Id, data.Profile, data.Collection, data.Ids)
+		switch {
+		case errors.Is(err, plans.ErrPlanNotFound):
+			return e.NotFoundError(err.Error(), nil)
+		case errors.Is(err, plans.ErrNotOrdered), errors.Is(err, plans.ErrEmptyOrder),
+			errors.Is(err, plans.ErrDuplicateId), errors.Is(err, plans.ErrMixedParents):
+			return e.BadRequestError(err.Error(), nil)
+		case err != nil:
+			return err
+		}
+		result := make([]planOrder, len(ordered))
+		for i, record := range ordered {
+			result[i] = planOrder{Id: record.Id, Order: record.GetInt("order")}
+		}
+		return e.JSON(http.StatusOK, result)
+	})
+
+	// POST /api/plans/{id}/clone {"resetCurrent": true, "profile": id} copies
+	// the plan of the authenticated user, or of a workspace they can write to,
+	// with its child plans right after it, see plans.Clone.
+	g.POST("/plans/{id}/clone", func(e *core.RequestEvent) error {
+		if e.Auth == nil || e.Auth.Collection().Name != models.USERS_COLLECTION_NAME {
+			return e.UnauthorizedError("", nil)
+		}
+		data := struct {
+			ResetCurrent bool   `json:"resetCurrent" form:"resetCurrent"`
+			Profile      string `json:"profile" form:"profile"`
+		}{}
+		if err := e.BindBody(&data); err != nil {
+			return e.BadRequestError("Failed to read request data", err)
+		}
+		// the copy references the dictionaries, like the plan writes the
+		// maintenance guard holds back
+		if seed.IsSeeding(app) {
+			return e.Error(http.StatusServiceUnavailable, "The dictionaries are being updated, please try again shortly.", nil)
+		}
+		clone, err := plans.Clone(app, e.Auth.Id, e.Request.PathValue("id"), data.Profile, data.ResetCurrent)
+		if errors.Is(err, plans.ErrPlanNotFound) || errors.Is(err, plans.ErrProfileNotFound) {
+			return e.NotFoundError(err.Error(), nil)
+		} else if err != nil {
+			return err
+		}
+		userPlans, err := plansCache.Get(app, e.Auth.Id)
+		if err != nil {
+			return err
+		}
+		for _, plan := range userPlans {
+			if plan.Id == clone.Id {
+				return e.JSON(http.StatusOK, plan)
+			}
+		}
+		return e.NotFoundError("", nil)
+	})
+
 	g.GET("/dictionaryVersion", func(e *core.RequestEvent) error {
 		rec, err := models.FindAppSettingsByKey(app, "dictionaryVersion")
 		if err != nil {
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/workspaces"
+)
+
+type workspaceInvitation struct {
+	Id        string         `json:"id"`
+	Workspace string         `json:"workspace"`
+	Role      string         `json:"role"`
+	Email     string         `json:"email"`
+	Token     string         `json:"token"`
+	Expires   types.DateTime `json:"expires"`
+}
+
+type workspaceMembership struct {
+	Id        string `json:"id"`
+	Workspace string `json:"workspace"`
+	User      string `json:"user"`
+	Role      string `json:"role"`
+}
+
+func bindWorkspacesRoutes(app core.App, g *router.RouterGroup[*core.RequestEvent]) {
+	// POST /api/workspaces/{id}/invitations {"role": "editor", "email": ""}
+	// lets an owner invite into the workspace, the response carries the
+	// token to pass on. With an email only the user of that email can accept.
+	g.POST("/workspaces/{id}/invitations", func(e *core.RequestEvent) error {
+		if e.Auth == nil || e.Auth.Collection().Name != models.USERS_COLLECTION_NAME {
+			return e.UnauthorizedError("", nil)
+		}
+		data := struct {
+			Role  string `json:"role" form:"role"`
+			Email string `json:"email" form:"email"`
+		}{}
+		if err := e.BindBody(&data); err != nil {
+			return e.BadRequestError("Failed to read request data", err)
+		}
+		invitation, err := workspaces.Invite(app, e.Request.PathValue("id"), e.Auth.Id, data.Role, data.Email)
+		if errors.Is(err, workspaces.ErrInvalidRole) {
+			return e.BadRequestError(err.Error(), nil)
+		} else if errors.Is(err, workspaces.ErrWorkspaceNotFound) {
+			return e.NotFoundError(err.Error(), nil)
+		} else if err != nil {
+			return e.BadRequestError("Failed to create the invitation", err)
+		}
+		return e.JSON(http.StatusOK, workspaceInvitation{
+			Id:        invitation.Id,
+			Workspace: invitation.WorkspaceId(),
+			Role:      invitation.Role(),
+			Email:     invitation.Email(),
+			Token:     invitation.Token(),
+			Expires:   invitation.Expires(),
+		})
+	})
+
+	// POST /api/workspaces/invitations/{token}/accept makes the authenticated
+	// user a member of the workspace with the role of the invitation.
+	g.POST("/workspaces/invitations/{token}/accept", func(e *core.RequestEvent) error {
+		if e.Auth == nil || e.Auth.Collection().Name != models.USERS_COLLECTION_NAME {
+			return e.UnauthorizedError("", nil)
+		}
+		member, err := workspaces.Accept(app, e.Request.PathValue("token"), e.Auth)
+		switch {
+		case errors.Is(err, workspaces.ErrInvitationNotFound):
+			return e.NotFoundError(err.Error(), nil)
+		case errors.Is(err, workspaces.ErrInvitationExpired):
+			return e.Error(http.StatusGone, err.Error(), nil)
+		case errors.Is(err, workspaces.ErrAlreadyMember):
+			return e.Error(http.StatusConflict, err.Error(), nil)
+		case err != nil:
+			return err
+		}
+		return e.JSON(http.StatusOK, workspaceMembership{
+			Id:        member.Id,
+			Workspace: member.WorkspaceId(),
+			User:      member.UserId(),
+			Role:      member.Role(),
+		})
+	})
+}
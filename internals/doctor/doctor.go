@@ -0,0 +1,110 @@
+// Package doctor checks an instance for the known problems the app can't
+// prevent on its own, e.g. a seed schema drifted from the migrations, and
+// repairs the ones it can.
+package doctor
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
+	"github.com/qxuken/gbp/internals/seed"
+)
+
+// Check inspects the app and returns the problems it found, the ones with a
+// Fix can repair them with --fix.
+type Check struct {
+	Name string
+	Run  func(app core.App) ([]string, error)
+	Fix  func(app core.App) (string, error)
+}
+
+// Checks run by the doctor command, in order.
+var Checks = []Check{
+	{Name: "seed schema", Run: checkSeedSchema},
+	{Name: "dictionary version", Run: checkDictionaryVersion},
+	{Name: "plan orders", Run: plans.FindOrderProblems, Fix: fixPlanOrders},
+}
+
+func checkSeedSchema(app core.App) ([]string, error) {
+	problems := []string{}
+	for _, issue := range seed.CheckSchema(app) {
+		problems = append(problems, issue.String())
+	}
+	return problems, nil
+}
+
+// checkDictionaryVersion makes sure the applied dictionaries can be served
+// to the clients and rolled back.
+func checkDictionaryVersion(app core.App) ([]string, error) {
+	version, err := models.FindAppSettingsByKey(app, "dictionaryVersion")
+	if err == sql.ErrNoRows {
+		return []string{"no dictionary seeded yet"}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	_, err = models.FindDbDumpByHash(app, version.Value())
+	if err == sql.ErrNoRows {
+		return []string{fmt.Sprintf("the dump of the applied version %s isn't stored", version.Value())}, nil
+	}
+	return nil, err
+}
+
+// fixPlanOrders renumbers the plans from this process, a running server
+// doesn't see the change in its plans cache until it restarts, see plans.Cache.
+func fixPlanOrders(app core.App) (string, error) {
+	changed, err := plans.RepairOrders(app)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("renumbered %d plans, restart a running server to drop its plans cache", changed), nil
+}
+
+var errUnhealthy = errors.New("doctor found problems")
+
+func NewCobraDoctorCommand(app core.App) *cobra.Command {
+	var fix bool
+	command := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the instance for known problems",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			healthy := true
+			for _, check := range Checks {
+				problems, err := check.Run(app)
+				if err != nil {
+					problems = append(problems, err.Error())
+				}
+				if len(problems) == 0 {
+					fmt.Fprintf(out, "ok    %s\n", check.Name)
+					continue
+				}
+				if fix && check.Fix != nil && err == nil {
+					summary, err := check.Fix(app)
+					if err == nil {
+						fmt.Fprintf(out, "fixed %s: %s\n", check.Name, summary)
+						continue
+					}
+					problems = append(problems, err.Error())
+				}
+				healthy = false
+				fmt.Fprintf(out, "fail  %s\n", check.Name)
+				for _, problem := range problems {
+					fmt.Fprintf(out, "      %s\n", problem)
+				}
+			}
+			if !healthy {
+				return errUnhealthy
+			}
+			return nil
+		},
+	}
+	command.Flags().BoolVar(&fix, "fix", false, "repair the problems of the checks that can")
+	return command
+}
@@ -0,0 +1,125 @@
+package doctor_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/doctor"
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
+	"github.com/qxuken/gbp/internals/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// runDoctor runs the doctor command with the args, returns its output and
+// whether it found the instance healthy.
+func runDoctor(t *testing.T, app core.App, args ...string) (string, bool) {
+	t.Helper()
+	var out bytes.Buffer
+	command := doctor.NewCobraDoctorCommand(app)
+	command.SetArgs(args)
+	command.SetOut(&out)
+	command.SetErr(io.Discard)
+	err := command.Execute()
+	return out.String(), err == nil
+}
+
+// seedVersion applies a dictionary version with its stored dump.
+func seedVersion(t *testing.T, app core.App) {
+	t.Helper()
+	testutil.SeedDictionaries(t, app)
+	testutil.CreateDbDump(t, app, "hash1", "")
+	if _, err := models.UpsertAppSettings(app, "dictionaryVersion", "hash1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDoctorHealthy(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	seedVersion(t, app)
+
+	out, healthy := runDoctor(t, app)
+	if !healthy {
+		t.Fatalf("expected a healthy instance, got\n%s", out)
+	}
+	for _, check := range doctor.Checks {
+		if !strings.Contains(out, "ok    "+check.Name+"\n") {
+			t.Errorf("expected %s to pass, got\n%s", check.Name, out)
+		}
+	}
+}
+
+func TestDoctorSchemaDrift(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	seedVersion(t, app)
+	characters, err := app.FindCollectionByNameOrId(models.CHARACTERS_COLLECTION_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	characters.Fields.RemoveByName("aliases")
+	if err := app.Save(characters); err != nil {
+		t.Fatal(err)
+	}
+
+	out, healthy := runDoctor(t, app, "--fix")
+	if healthy {
+		t.Fatalf("expected the drifted schema to fail, got\n%s", out)
+	}
+	if !strings.Contains(out, "fail  seed schema\n") || !strings.Contains(out, "aliases") {
+		t.Errorf("expected the missing aliases field, got\n%s", out)
+	}
+	if !strings.Contains(out, "ok    dictionary version\n") {
+		t.Errorf("expected only the schema to fail, got\n%s", out)
+	}
+}
+
+func TestDoctorDictionaryVersion(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+
+	out, healthy := runDoctor(t, app)
+	if healthy || !strings.Contains(out, "no dictionary seeded yet") {
+		t.Errorf("expected the missing version, got\n%s", out)
+	}
+
+	if _, err := models.UpsertAppSettings(app, "dictionaryVersion", "hash1"); err != nil {
+		t.Fatal(err)
+	}
+	out, healthy = runDoctor(t, app)
+	if healthy || !strings.Contains(out, "the dump of the applied version hash1 isn't stored") {
+		t.Errorf("expected the missing dump, got\n%s", out)
+	}
+}
+
+func TestDoctorFixPlanOrders(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	seedVersion(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{
+		"user": user.Id, "character": "characterdiluc0", "order": 7,
+	})
+
+	out, healthy := runDoctor(t, app)
+	if healthy || !strings.Contains(out, "fail  plan orders\n") {
+		t.Fatalf("expected the gapped orders to fail, got\n%s", out)
+	}
+
+	out, healthy = runDoctor(t, app, "--fix")
+	if !healthy || !strings.Contains(out, "fixed plan orders: renumbered 1 plans") {
+		t.Fatalf("expected the orders to be repaired, got\n%s", out)
+	}
+	if problems, err := plans.FindOrderProblems(app); err != nil || len(problems) != 0 {
+		t.Errorf("expected no problems left, got %v %v", problems, err)
+	}
+	if out, healthy := runDoctor(t, app); !healthy {
+		t.Errorf("expected a healthy instance after the fix, got\n%s", out)
+	}
+}
@@ -0,0 +1,138 @@
+// Package fuzzy ranks the dictionary names against a search query, it
+// accepts the same subsequence matches as the fuzzysearch package of the ui
+// plus the single typos, and ranks the closer matches first.
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The scores of the match kinds, the longer candidates lose a point per
+// extra rune, up to 99, so "Raiden" ranks "Raiden Shogun" over
+// "Raiden Shogun's Blade".
+const (
+	scoreExact       = 1000
+	scorePrefix      = 800
+	scoreWordPrefix  = 600
+	scoreSubstring   = 400
+	scoreSubsequence = 200
+	scoreTypo        = 100
+)
+
+// minTypoQuery is the shortest query matched with a typo, shorter ones would
+// match most of the names.
+const minTypoQuery = 4
+
+// Normalize lowercases the text and drops the punctuation, so "Hu Tao",
+// "hutao" and "Hu-Tao" compare on the letters.
+func Normalize(text string) string {
+	var b strings.Builder
+	lastSpace := true
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			lastSpace = false
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			if !lastSpace {
+				b.WriteRune(' ')
+				lastSpace = true
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Score rates how well the candidate matches the query, zero for no match.
+// Both are normalized first.
+func Score(query string, candidate string) int {
+	query, candidate = Normalize(query), Normalize(candidate)
+	if query == "" || candidate == "" {
+		return 0
+	}
+	// bounded so a long name doesn't fall to the next match kind
+	penalty := min(max(len([]rune(candidate))-len([]rune(query)), 0), 99)
+	compactQuery := strings.ReplaceAll(query, " ", "")
+	compactCandidate := strings.ReplaceAll(candidate, " ", "")
+	switch {
+	case query == candidate || compactQuery == compactCandidate:
+		return scoreExact
+	case strings.HasPrefix(candidate, query) || strings.HasPrefix(compactCandidate, compactQuery):
+		return scorePrefix - penalty
+	case hasWordPrefix(candidate, query):
+		return scoreWordPrefix - penalty
+	case strings.Contains(compactCandidate, compactQuery):
+		return scoreSubstring - penalty
+	case isSubsequence(compactQuery, compactCandidate):
+		return scoreSubsequence - penalty
+	case len([]rune(compactQuery)) >= minTypoQuery && hasTypoMatch(compactQuery, candidate):
+		return scoreTypo - penalty
+	}
+	return 0
+}
+
+func hasWordPrefix(candidate string, query string) bool {
+	for i := range candidate {
+		if i > 0 && candidate[i-1] == ' ' && strings.HasPrefix(candidate[i:], query) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSubsequence(query string, candidate string) bool {
+	q := []rune(query)
+	i := 0
+	for _, r := range candidate {
+		if i < len(q) && q[i] == r {
+			i++
+		}
+	}
+	return i == len(q)
+}
+
+// hasTypoMatch reports whether the query is a single edit away from the
+// whole candidate, one of its words or a prefix of them of the query length.
+func hasTypoMatch(query string, candidate string) bool {
+	targets := append(strings.Fields(candidate), strings.ReplaceAll(candidate, " ", ""))
+	q := []rune(query)
+	for _, target := range targets {
+		t := []rune(target)
+		if distance(q, t) <= 1 {
+			return true
+		}
+		for _, n := range []int{len(q) - 1, len(q), len(q) + 1} {
+			if n > 0 && n < len(t) && distance(q, t[:n]) <= 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// distance is the optimal string alignment distance, the Levenshtein one
+// counting a swap of adjacent runes as a single edit.
+func distance(a []rune, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range b {
+		rows[0][j+1] = j + 1
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
@@ -0,0 +1,61 @@
+package fuzzy_test
+
+import (
+	"testing"
+
+	"github.com/qxuken/gbp/internals/fuzzy"
+)
+
+func TestScore(t *testing.T) {
+	noMatch := []struct{ query, candidate string }{
+		{"", "Diluc"},
+		{"xiao", "Diluc"},
+		{"dil", "Lu"},
+		// a typo needs a long enough query
+		{"dlk", "Diluc"},
+	}
+	for _, c := range noMatch {
+		if score := fuzzy.Score(c.query, c.candidate); score != 0 {
+			t.Errorf("%q in %q: expected no match, got %d", c.query, c.candidate, score)
+		}
+	}
+
+	// each query lists its candidates from the best match down
+	ranked := []struct {
+		query      string
+		candidates []string
+	}{
+		{"raiden", []string{"Raiden", "Raiden Shogun", "Raiden Shogun's Blade"}},
+		{"hutao", []string{"Hu Tao", "Hu Tao's Staff"}},
+		{"shogun", []string{"Shogunate", "Raiden Shogun", "Bakushogun"}},
+		{"ei", []string{"Ei", "Raiden Shogun Ei", "Reina", "Venti", "Keqing"}},
+		{"nahdia", []string{"Nahida", "Nahida's Catalyst"}},
+	}
+	for _, c := range ranked {
+		previous := -1
+		for i, candidate := range c.candidates {
+			score := fuzzy.Score(c.query, candidate)
+			if score == 0 {
+				t.Errorf("%q in %q: expected a match", c.query, candidate)
+			}
+			if i > 0 && score >= previous {
+				t.Errorf("%q: %q (%d) should rank below %q (%d)", c.query, candidate, score, c.candidates[i-1], previous)
+			}
+			previous = score
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for input, expected := range map[string]string{
+		"Hu Tao":             "hu tao",
+		"  Hu-Tao ":          "hu tao",
+		"Gladiator's Finale": "gladiators finale",
+		"Kamisato  Ayaka":    "kamisato ayaka",
+		"ディルック":              "ディルック",
+	} {
+		if got := fuzzy.Normalize(input); got != expected {
+			t.Errorf("%q: expected %q, got %q", input, expected, got)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package i18n localizes the names of the dictionary records from the
+// translations collection, falling back to the English names the records
+// themselves hold.
+package i18n
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// NormalizeLocale turns a language tag into the form the translations are
+// stored with, e.g. "zh_CN" into "zh-cn".
+func NormalizeLocale(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// withBases appends the primary language of the tag after it, so "pt-br"
+// still matches a "pt" translation.
+func withBases(locales []string, tag string) []string {
+	tag = NormalizeLocale(tag)
+	if tag == "" || tag == "*" {
+		return locales
+	}
+	candidates := []string{tag}
+	if base, _, ok := strings.Cut(tag, "-"); ok {
+		candidates = append(candidates, base)
+	}
+	for _, candidate := range candidates {
+		if !slices.Contains(locales, candidate) {
+			locales = append(locales, candidate)
+		}
+	}
+	return locales
+}
+
+// parseAcceptLanguage returns the tags of the header ordered by their quality,
+// the ones with a zero quality left out.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	tags := []weighted{}
+	for part := range strings.SplitSeq(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if tag = strings.TrimSpace(tag); tag != "" && quality > 0 {
+			tags = append(tags, weighted{tag, quality})
+		}
+	}
+	slices.SortStableFunc(tags, func(a, b weighted) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		}
+		return 0
+	})
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.tag
+	}
+	return out
+}
+
+// RequestLocales returns the locales the request asks for in the order of
+// preference, from the lang query param or else the Accept-Language header.
+// The list stops before English, the names are English already.
+func RequestLocales(r *http.Request) []string {
+	tags := []string{}
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		tags = append(tags, lang)
+	} else {
+		tags = parseAcceptLanguage(r.Header.Get("Accept-Language"))
+	}
+	locales := []string{}
+	for _, tag := range tags {
+		locales = withBases(locales, tag)
+	}
+	if idx := slices.IndexFunc(locales, func(l string) bool {
+		return l == models.DEFAULT_LOCALE || strings.HasPrefix(l, models.DEFAULT_LOCALE+"-")
+	}); idx >= 0 {
+		locales = locales[:idx]
+	}
+	return locales
+}
+
+// Localize replaces the names of the records of the collection with their
+// translation in the first of the locales having one, the records without
+// any keep their English name.
+func Localize(app core.App, collection string, records []*core.Record, locales []string) error {
+	if len(records) == 0 || len(locales) == 0 || !slices.Contains(models.TRANSLATED_COLLECTIONS, collection) {
+		return nil
+	}
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.Id
+	}
+	translations, err := models.FindTranslations(app, collection, ids, locales)
+	if err != nil {
+		return err
+	}
+	names := map[string]map[string]string{}
+	for _, t := range translations {
+		if names[t.RecordId()] == nil {
+			names[t.RecordId()] = map[string]string{}
+		}
+		names[t.RecordId()][t.Locale()] = t.Name()
+	}
+	for _, record := range records {
+		for _, locale := range locales {
+			if name, ok := names[record.Id][locale]; ok {
+				record.Set("name", name)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// requestLocales skips the superuser requests without an explicit lang, the
+// dashboard would otherwise save the translated names over the English ones.
+func requestLocales(e *core.RequestEvent) []string {
+	e.Response.Header().Add("Vary", "Accept-Language")
+	if e.HasSuperuserAuth() && e.Request.URL.Query().Get("lang") == "" {
+		return nil
+	}
+	return RequestLocales(e.Request)
+}
+
+// Bind localizes the list and view responses of the translated dictionary
+// collections, and drops the translations of the deleted records.
+func Bind(app core.App) {
+	app.OnRecordsListRequest(models.TRANSLATED_COLLECTIONS...).BindFunc(func(e *core.RecordsListRequestEvent) error {
+		if err := Localize(e.App, e.Collection.Name, e.Records, requestLocales(e.RequestEvent)); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordViewRequest(models.TRANSLATED_COLLECTIONS...).BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := Localize(e.App, e.Collection.Name, []*core.Record{e.Record}, requestLocales(e.RequestEvent)); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordDelete(models.TRANSLATED_COLLECTIONS...).BindFunc(func(e *core.RecordEvent) error {
+		if err := e.Next(); err != nil {
+			return err
+		}
+		translations, err := e.App.FindAllRecords(models.TRANSLATIONS_COLLECTION_NAME, dbx.HashExp{
+			"collection": e.Record.Collection().Name,
+			"record":     e.Record.Id,
+		})
+		if err != nil {
+			return err
+		}
+		for _, translation := range translations {
+			if err := e.App.Delete(translation); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
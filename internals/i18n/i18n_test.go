@@ -0,0 +1,118 @@
+package i18n_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/tests"
+
+	"github.com/qxuken/gbp/internals/i18n"
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+func TestRequestLocales(t *testing.T) {
+	cases := []struct {
+		url            string
+		acceptLanguage string
+		expected       []string
+	}{
+		{"/", "", []string{}},
+		{"/", "ja", []string{"ja"}},
+		{"/", "zh-CN,zh;q=0.9,en;q=0.8,ja;q=0.7", []string{"zh-cn", "zh"}},
+		{"/", "de;q=0.5, pt_BR", []string{"pt-br", "pt", "de"}},
+		{"/", "en-US,ru;q=0.9", []string{}},
+		{"/", "fr;q=0, *", []string{}},
+		{"/?lang=ja", "ru", []string{"ja"}},
+		{"/?lang=en", "ru", []string{}},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, c.url, nil)
+		if c.acceptLanguage != "" {
+			r.Header.Set("Accept-Language", c.acceptLanguage)
+		}
+		if locales := i18n.RequestLocales(r); !slices.Equal(locales, c.expected) {
+			t.Errorf("%s %q: expected %v, got %v", c.url, c.acceptLanguage, c.expected, locales)
+		}
+	}
+}
+
+func testApp(t testing.TB) *tests.TestApp {
+	app := testutil.NewTestAppWithoutCleanup(t)
+	i18n.Bind(app)
+	testutil.SeedDictionaries(t, app)
+	testutil.CreateRecord(t, app, models.TRANSLATIONS_COLLECTION_NAME, "trdilucja000000", map[string]any{
+		"collection": models.CHARACTERS_COLLECTION_NAME, "record": "characterdiluc0", "locale": "ja", "name": "ディルック",
+	})
+	testutil.CreateRecord(t, app, models.TRANSLATIONS_COLLECTION_NAME, "trpyrozh0000000", map[string]any{
+		"collection": models.ELEMENTS_COLLECTION_NAME, "record": "elementpyro0000", "locale": "zh", "name": "火",
+	})
+	return app
+}
+
+func TestLocalizedDictionaries(t *testing.T) {
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "lang param",
+			Method:          http.MethodGet,
+			URL:             "/api/collections/characters/records?lang=ja",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"name":"ディルック"`},
+			TestAppFactory:  testApp,
+		},
+		{
+			Name:            "english fallback",
+			Method:          http.MethodGet,
+			URL:             "/api/collections/characters/records?lang=de",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"name":"Diluc"`},
+			TestAppFactory:  testApp,
+		},
+		{
+			Name:            "accept language with the base language fallback",
+			Method:          http.MethodGet,
+			URL:             "/api/collections/elements/records/elementpyro0000",
+			Headers:         map[string]string{"Accept-Language": "zh-TW,en;q=0.5"},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"name":"火"`},
+			TestAppFactory:  testApp,
+		},
+		{
+			Name:            "accept language preferring english",
+			Method:          http.MethodGet,
+			URL:             "/api/collections/elements/records/elementpyro0000",
+			Headers:         map[string]string{"Accept-Language": "en,zh;q=0.5"},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"name":"Pyro"`},
+			TestAppFactory:  testApp,
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestDeletedRecordTranslations(t *testing.T) {
+	app := testApp(t)
+	defer app.Cleanup()
+
+	character, err := app.FindRecordById(models.CHARACTERS_COLLECTION_NAME, "characterdiluc0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Delete(character); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.FindRecordById(models.TRANSLATIONS_COLLECTION_NAME, "trdilucja000000"); err == nil {
+		t.Error("the translation of the deleted character is left behind")
+	}
+	if _, err := app.FindRecordById(models.TRANSLATIONS_COLLECTION_NAME, "trpyrozh0000000"); err != nil {
+		t.Errorf("the element translation: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package icons normalizes the dictionary icons: a supported image is
+// fitted into MAX_SIZE, re-encoded as a lossless webp along with a THUMB_SIZE
+// thumbnail, and named after a hash of its pixels, so the same picture gets
+// the same file name whatever format or collection it was uploaded to.
+package icons
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"regexp"
+	"strings"
+
+	"github.com/gen2brain/webp"
+	xdraw "golang.org/x/image/draw"
+	xwebp "golang.org/x/image/webp"
+)
+
+// MAX_SIZE bounds the longest side of a normalized icon, THUMB_SIZE the one
+// of its thumbnail. The images are never upscaled.
+const (
+	MAX_SIZE   = 256
+	THUMB_SIZE = 64
+)
+
+// maxSourcePixels rejects the images too large to be an icon before they are
+// decoded.
+const maxSourcePixels = 4096 * 4096
+
+// ErrUnsupportedImage is returned for content that isn't a png, jpeg or webp
+// image.
+var ErrUnsupportedImage = errors.New("unsupported image, expected a png, jpeg or webp")
+
+const (
+	fileExt   = ".webp"
+	thumbExt  = "_thumb.webp"
+	hashBytes = 16
+)
+
+var hashNameRegex = regexp.MustCompile(`^[0-9a-f]{32}\.webp$`)
+
+// FileName is the name of the normalized icon with the hash.
+func FileName(hash string) string {
+	return hash + fileExt
+}
+
+// ThumbName is the name of the thumbnail of the icon with the hash.
+func ThumbName(hash string) string {
+	return hash + thumbExt
+}
+
+// HashFromName returns the hash of a normalized icon file name, false for any
+// other name.
+func HashFromName(name string) (string, bool) {
+	if !hashNameRegex.MatchString(name) {
+		return "", false
+	}
+	return strings.TrimSuffix(name, fileExt), true
+}
+
+// Icon is a normalized icon.
+type Icon struct {
+	Hash   string
+	Width  int
+	Height int
+	// Image is the webp encoded icon, named FileName(Hash)
+	Image []byte
+
+	pixels *image.NRGBA
+}
+
+// Thumb encodes the thumbnail of the icon, named ThumbName(Hash).
+func (i *Icon) Thumb() ([]byte, error) {
+	return encode(fit(i.pixels, THUMB_SIZE))
+}
+
+// Normalize validates and normalizes the image content. A webp already
+// normalized is kept as is, so re-seeding a dump doesn't re-encode it.
+func Normalize(content []byte) (*Icon, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil || (format != "png" && format != "jpeg" && format != "webp") {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxSourcePixels {
+		return nil, fmt.Errorf("%w: %dx%d is out of bounds", ErrUnsupportedImage, config.Width, config.Height)
+	}
+	var src image.Image
+	if format == "webp" {
+		// the lossless webp decode of x/image is exact, the one of the
+		// encoder package converts to YCbCr
+		src, err = xwebp.Decode(bytes.NewReader(content))
+	} else {
+		src, _, err = image.Decode(bytes.NewReader(content))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+
+	normalized := fit(src, MAX_SIZE)
+	icon := &Icon{
+		Hash:   pixelsHash(normalized),
+		Width:  normalized.Rect.Dx(),
+		Height: normalized.Rect.Dy(),
+		pixels: normalized,
+	}
+	if format == "webp" && normalized == src {
+		icon.Image = content
+	} else if icon.Image, err = encode(normalized); err != nil {
+		return nil, err
+	}
+	return icon, nil
+}
+
+// fit scales the image down so its longest side is at most size. An image
+// already fitting is only converted, an *image.NRGBA one is returned as is.
+func fit(src image.Image, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		if nrgba, ok := src.(*image.NRGBA); ok && bounds.Min == (image.Point{}) {
+			return nrgba
+		}
+		dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(dst, dst.Rect, src, bounds.Min, draw.Src)
+		return dst
+	}
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Rect, src, bounds, draw.Src, nil)
+	return dst
+}
+
+// pixelsHash hashes the size and the pixels, unlike the encoded bytes they
+// don't depend on the encoder.
+func pixelsHash(img *image.NRGBA) string {
+	h := sha256.New()
+	binary.Write(h, binary.BigEndian, [2]uint32{uint32(img.Rect.Dx()), uint32(img.Rect.Dy())})
+	rowLen := img.Rect.Dx() * 4
+	for y := range img.Rect.Dy() {
+		offset := y * img.Stride
+		h.Write(img.Pix[offset : offset+rowLen])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:hashBytes])
+}
+
+// encode writes a lossless webp keeping the color of the transparent pixels,
+// decoding it gives back the hashed pixels.
+func encode(img *image.NRGBA) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := webp.Encode(buf, img, webp.Options{Lossless: true, Exact: true, Quality: 100, Method: 4}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
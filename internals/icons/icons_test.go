@@ -0,0 +1,125 @@
+package icons_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+
+	"github.com/qxuken/gbp/internals/icons"
+)
+
+func testImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), 0x80, 0xff})
+		}
+	}
+	return img
+}
+
+func encodePng(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeWebp(t *testing.T, content []byte) image.Image {
+	t.Helper()
+	img, err := webp.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("decode webp: %v", err)
+	}
+	return img
+}
+
+func TestNormalize(t *testing.T) {
+	icon, err := icons.Normalize(encodePng(t, testImage(512, 256)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if icon.Width != icons.MAX_SIZE || icon.Height != icons.MAX_SIZE/2 {
+		t.Errorf("size: expected %dx%d, got %dx%d", icons.MAX_SIZE, icons.MAX_SIZE/2, icon.Width, icon.Height)
+	}
+	if b := decodeWebp(t, icon.Image).Bounds(); b.Dx() != icon.Width || b.Dy() != icon.Height {
+		t.Errorf("image: expected %dx%d, got %v", icon.Width, icon.Height, b)
+	}
+	thumb, err := icon.Thumb()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := decodeWebp(t, thumb).Bounds(); b.Dx() != icons.THUMB_SIZE || b.Dy() != icons.THUMB_SIZE/2 {
+		t.Errorf("thumb: expected %dx%d, got %v", icons.THUMB_SIZE, icons.THUMB_SIZE/2, b)
+	}
+	if hash, ok := icons.HashFromName(icons.FileName(icon.Hash)); !ok || hash != icon.Hash {
+		t.Errorf("file name: %q doesn't round trip", icons.FileName(icon.Hash))
+	}
+}
+
+func TestNormalizeKeepsSmallImages(t *testing.T) {
+	icon, err := icons.Normalize(encodePng(t, testImage(16, 8)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if icon.Width != 16 || icon.Height != 8 {
+		t.Errorf("size: expected 16x8, got %dx%d", icon.Width, icon.Height)
+	}
+}
+
+func TestNormalizeHashIgnoresTheFormat(t *testing.T) {
+	img := testImage(32, 32)
+	fromPng, err := icons.Normalize(encodePng(t, img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the normalized webp hashes the same, and isn't re-encoded
+	fromWebp, err := icons.Normalize(fromPng.Image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromWebp.Hash != fromPng.Hash {
+		t.Errorf("hash: the normalized webp hashes to %s instead of %s", fromWebp.Hash, fromPng.Hash)
+	}
+	if !bytes.Equal(fromWebp.Image, fromPng.Image) {
+		t.Error("image: the normalized webp was re-encoded")
+	}
+
+	other, err := icons.Normalize(encodePng(t, testImage(32, 31)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Hash == fromPng.Hash {
+		t.Error("hash: different images share a hash")
+	}
+
+	jpegBuf := &bytes.Buffer{}
+	if err := jpeg.Encode(jpegBuf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := icons.Normalize(jpegBuf.Bytes()); err != nil {
+		t.Errorf("jpeg: %v", err)
+	}
+}
+
+func TestNormalizeRejectsUnsupported(t *testing.T) {
+	gif := []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")
+	for name, content := range map[string][]byte{
+		"empty":   nil,
+		"text":    []byte("not an image"),
+		"gif":     gif,
+		"too big": encodePng(t, image.NewNRGBA(image.Rect(0, 0, 8192, 8192))),
+	} {
+		if _, err := icons.Normalize(content); !errors.Is(err, icons.ErrUnsupportedImage) {
+			t.Errorf("%s: expected ErrUnsupportedImage, got %v", name, err)
+		}
+	}
+}
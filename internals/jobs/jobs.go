@@ -0,0 +1,233 @@
+// Package jobs runs the long dump and seed operations in the background.
+//
+// Every job is tracked by a _jobs record. The record is stored by the job
+// goroutine and the per collection progress only lives in memory while the job
+// runs (the seed holds the write connection for its whole transaction), the
+// runner answers for the running jobs, see Runner.Job. The progress is also
+// broadcast to the superusers subscribed to the "jobs/{id}" realtime topic.
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/subscriptions"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// ErrBusy is returned when an exclusive job is requested while another one runs.
+var ErrBusy = errors.New("a seed is already running")
+
+// TOPIC_PREFIX prefixes the realtime topic of a job, followed by its id.
+const TOPIC_PREFIX = "jobs/"
+
+// broadcastInterval throttles the progress messages of a single job.
+const broadcastInterval = 200 * time.Millisecond
+
+// CollectionProgress is the number of processed items out of the collection total.
+type CollectionProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Work is the job body, progress is meant to be handed to the seed package.
+// The returned result is stored on the job record.
+type Work func(app core.App, progress func(collection string, done int, total int)) (any, error)
+
+type runningJob struct {
+	job           *models.Job
+	mutex         sync.Mutex
+	progress      map[string]CollectionProgress
+	lastBroadcast time.Time
+	done          chan struct{}
+}
+
+func (j *runningJob) snapshot() map[string]CollectionProgress {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	progress := make(map[string]CollectionProgress, len(j.progress))
+	for k, v := range j.progress {
+		progress[k] = v
+	}
+	return progress
+}
+
+// Runner starts the jobs and keeps the live state of the running ones.
+type Runner struct {
+	app       core.App
+	mutex     sync.Mutex
+	running   map[string]*runningJob
+	exclusive string
+}
+
+func NewRunner(app core.App) *Runner {
+	return &Runner{
+		app:     app,
+		running: map[string]*runningJob{},
+	}
+}
+
+// Bind fails the jobs interrupted by the previous shutdown once the app serves.
+func (r *Runner) Bind() {
+	r.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		if err := models.FailRunningJobs(r.app, "interrupted by a restart"); err != nil {
+			r.app.Logger().Error("Failed to clean up the interrupted jobs", "error", err)
+		}
+		return se.Next()
+	})
+}
+
+// Start runs work in a goroutine, which stores the job record first. The
+// record may wait for the write connection held by a running seed, so the
+// returned job isn't stored yet. An exclusive job (i.e. one seeding the
+// dictionaries) is refused with ErrBusy while another exclusive one runs.
+func (r *Runner) Start(kind string, exclusive bool, work Work) (*models.Job, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if exclusive && r.exclusive != "" {
+		return nil, ErrBusy
+	}
+
+	job, err := models.NewJob(r.app, kind)
+	if err != nil {
+		return nil, err
+	}
+	job.Id = core.GenerateDefaultRandomId()
+
+	running := &runningJob{
+		job:      job,
+		progress: map[string]CollectionProgress{},
+		done:     make(chan struct{}),
+	}
+	r.running[job.Id] = running
+	if exclusive {
+		r.exclusive = job.Id
+	}
+
+	go r.run(running, exclusive, work)
+
+	return job, nil
+}
+
+func (r *Runner) run(running *runningJob, exclusive bool, work Work) {
+	defer close(running.done)
+	defer func() {
+		r.mutex.Lock()
+		delete(r.running, running.job.Id)
+		if exclusive {
+			r.exclusive = ""
+		}
+		r.mutex.Unlock()
+	}()
+
+	job := running.job
+	var result any
+	err := r.app.Save(job)
+	if err == nil {
+		result, err = r.safeRun(running, work)
+	}
+
+	job.SetProgress(running.snapshot())
+	if err != nil {
+		r.app.Logger().Error("Job failed", "job", job.Id, "kind", job.Kind(), "error", err)
+		job.SetStatus(models.JOB_STATUS_FAILED)
+		job.SetError(err.Error())
+	} else {
+		job.SetStatus(models.JOB_STATUS_DONE)
+		job.SetResult(result)
+	}
+	if err := r.app.Save(job); err != nil {
+		r.app.Logger().Error("Failed to save the job", "job", job.Id, "error", err)
+	}
+	r.broadcast(job.Id, Payload(job, nil))
+}
+
+func (r *Runner) safeRun(running *runningJob, work Work) (result any, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = errors.New("job panicked")
+			r.app.Logger().Error("Job panicked", "job", running.job.Id, "panic", rec)
+		}
+	}()
+	return work(r.app, func(collection string, done int, total int) {
+		running.mutex.Lock()
+		running.progress[collection] = CollectionProgress{Done: done, Total: total}
+		now := time.Now()
+		shouldBroadcast := done == total || now.Sub(running.lastBroadcast) >= broadcastInterval
+		if shouldBroadcast {
+			running.lastBroadcast = now
+		}
+		running.mutex.Unlock()
+		if shouldBroadcast {
+			r.broadcast(running.job.Id, Payload(running.job, running.snapshot()))
+		}
+	})
+}
+
+// Job returns a running job with its live progress, its record may not be
+// stored yet.
+func (r *Runner) Job(jobId string) (*models.Job, map[string]CollectionProgress, bool) {
+	r.mutex.Lock()
+	running, ok := r.running[jobId]
+	r.mutex.Unlock()
+	if !ok {
+		return nil, nil, false
+	}
+	return running.job, running.snapshot(), true
+}
+
+// Wait blocks until the job finishes, it returns right away for a job that
+// isn't running in this process.
+func (r *Runner) Wait(jobId string) {
+	r.mutex.Lock()
+	running, ok := r.running[jobId]
+	r.mutex.Unlock()
+	if ok {
+		<-running.done
+	}
+}
+
+// Payload is the json representation of a job shared by the api and the
+// realtime messages. A nil progress keeps the stored one.
+func Payload(job *models.Job, progress map[string]CollectionProgress) map[string]any {
+	payload := map[string]any{
+		"id":       job.Id,
+		"kind":     job.Kind(),
+		"status":   job.Status(),
+		"progress": job.Get("progress"),
+		"result":   job.Get("result"),
+		"error":    job.Error(),
+		"created":  job.GetDateTime("created"),
+		"updated":  job.GetDateTime("updated"),
+	}
+	if progress != nil {
+		payload["progress"] = progress
+	}
+	return payload
+}
+
+// broadcast sends the payload to the superusers subscribed to the job topic.
+func (r *Runner) broadcast(jobId string, payload any) {
+	topic := TOPIC_PREFIX + jobId
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		r.app.Logger().Error("Failed to encode the job progress", "job", jobId, "error", err)
+		return
+	}
+	message := subscriptions.Message{Name: topic, Data: raw}
+	for _, client := range r.app.SubscriptionsBroker().Clients() {
+		if !client.HasSubscription(topic) {
+			continue
+		}
+		auth, _ := client.Get(apis.RealtimeClientAuthKey).(*core.Record)
+		if auth == nil || !auth.IsSuperuser() {
+			continue
+		}
+		client.Send(message)
+	}
+}
@@ -0,0 +1,163 @@
+package jobs_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/jobs"
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+func TestRunner(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	runner := jobs.NewRunner(app)
+
+	release := make(chan struct{})
+	reported := make(chan struct{})
+	seedJob, err := runner.Start("restore", true, func(app core.App, progress func(string, int, int)) (any, error) {
+		progress("characters", 1, 2)
+		close(reported)
+		<-release
+		progress("characters", 2, 2)
+		return map[string]any{"seeded": 2}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-reported
+	_, progress, ok := runner.Job(seedJob.Id)
+	if !ok || progress["characters"] != (jobs.CollectionProgress{Done: 1, Total: 2}) {
+		t.Errorf("live progress: unexpected %v %v", progress, ok)
+	}
+
+	// a second seed is refused, any other job still runs
+	if _, err := runner.Start("upload", true, nil); !errors.Is(err, jobs.ErrBusy) {
+		t.Errorf("expected ErrBusy, got %v", err)
+	}
+	otherJob, err := runner.Start("generate", false, func(app core.App, progress func(string, int, int)) (any, error) {
+		return nil, errors.New("generate failed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	close(release)
+	runner.Wait(seedJob.Id)
+	runner.Wait(otherJob.Id)
+
+	done, err := models.FindJobById(app, seedJob.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status() != models.JOB_STATUS_DONE {
+		t.Errorf("seed job: expected done, got %q", done.Status())
+	}
+	stored := map[string]jobs.CollectionProgress{}
+	if err := done.UnmarshalJSONField("progress", &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored["characters"] != (jobs.CollectionProgress{Done: 2, Total: 2}) {
+		t.Errorf("stored progress: unexpected %v", stored)
+	}
+
+	failed, err := models.FindJobById(app, otherJob.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status() != models.JOB_STATUS_FAILED || failed.Error() != "generate failed" {
+		t.Errorf("generate job: unexpected %q %q", failed.Status(), failed.Error())
+	}
+
+	// the seed lock is released with the job
+	next, err := runner.Start("upload", true, func(app core.App, progress func(string, int, int)) (any, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("expected the next seed to start, got %v", err)
+	}
+	runner.Wait(next.Id)
+}
+
+// TestStartDuringSeed checks that a job starts while a seed holds the write
+// connection, its record is stored once the seed is over.
+func TestStartDuringSeed(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	runner := jobs.NewRunner(app)
+
+	release := make(chan struct{})
+	inTransaction := make(chan struct{})
+	seedJob, err := runner.Start("restore", true, func(app core.App, progress func(string, int, int)) (any, error) {
+		return nil, app.RunInTransaction(func(txApp core.App) error {
+			close(inTransaction)
+			<-release
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-inTransaction
+
+	started := make(chan *models.Job)
+	go func() {
+		job, err := runner.Start("generate", false, func(app core.App, progress func(string, int, int)) (any, error) {
+			return nil, nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		started <- job
+	}()
+	var job *models.Job
+	select {
+	case job = <-started:
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("expected the job to start during the seed")
+	}
+	if running, _, ok := runner.Job(job.Id); !ok || running.Status() != models.JOB_STATUS_RUNNING {
+		t.Errorf("expected the running job, got %v", ok)
+	}
+
+	close(release)
+	runner.Wait(seedJob.Id)
+	runner.Wait(job.Id)
+	stored, err := models.FindJobById(app, job.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status() != models.JOB_STATUS_DONE {
+		t.Errorf("expected done, got %q", stored.Status())
+	}
+}
+
+func TestFailRunningJobs(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	job, err := models.NewJob(app, "restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Save(job); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.FailRunningJobs(app, "interrupted"); err != nil {
+		t.Fatal(err)
+	}
+	job, err = models.FindJobById(app, job.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status() != models.JOB_STATUS_FAILED || job.Error() != "interrupted" {
+		t.Errorf("unexpected %q %q", job.Status(), job.Error())
+	}
+}
@@ -0,0 +1,4 @@
+package models
+
+// MAX_PLAN_PRIORITY is the highest priority of a character plan, 0 is none.
+const MAX_PLAN_PRIORITY = 3
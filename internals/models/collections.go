@@ -2,25 +2,33 @@ package models
 
 // NOTE: These constants are append-only
 const (
-	USERS_COLLECTION_NAME               = "users"
-	APP_SETTINGS_COLLECTION_NAME        = "_appSettings"
-	DB_DUMPS_COLLECTION_NAME            = "_dbDumps"
-	CHARACTER_ROLES_COLLECTION_NAME     = "characterRoles"
-	ELEMENTS_COLLECTION_NAME            = "elements"
-	WEAPON_TYPES_COLLECTION_NAME        = "weaponTypes"
-	SPECIALS_COLLECTION_NAME            = "specials"
-	CHARACTERS_COLLECTION_NAME          = "characters"
-	WEAPONS_COLLECTION_NAME             = "weapons"
-	ARTIFACT_SETS_COLLECTION_NAME       = "artifactSets"
-	DOMAINS_OF_BLESSING_COLLECTION_NAME = "domainsOfBlessing"
-	ARTIFACT_TYPES_COLLECTION_NAME      = "artifactTypes"
-	CHARACTER_PLANS_COLLECTION_NAME     = "characterPlans"
-	WEAPON_PLANS_COLLECTION_NAME        = "weaponPlans"
-	ARTIFACT_SETS_PLANS_COLLECTION_NAME = "artifactSetsPlans"
-	ARTIFACT_TYPE_PLANS_COLLECTION_NAME = "artifactTypePlans"
-	TEAM_PLANS_COLLECTION_NAME          = "teamPlans"
-	PLANS_VIEW_COLLECTION_NAME          = "plans"
-	PATCH_COLLECTION_NAME               = "patch"
+	USERS_COLLECTION_NAME                 = "users"
+	APP_SETTINGS_COLLECTION_NAME          = "_appSettings"
+	DB_DUMPS_COLLECTION_NAME              = "_dbDumps"
+	CHARACTER_ROLES_COLLECTION_NAME       = "characterRoles"
+	ELEMENTS_COLLECTION_NAME              = "elements"
+	WEAPON_TYPES_COLLECTION_NAME          = "weaponTypes"
+	SPECIALS_COLLECTION_NAME              = "specials"
+	CHARACTERS_COLLECTION_NAME            = "characters"
+	WEAPONS_COLLECTION_NAME               = "weapons"
+	ARTIFACT_SETS_COLLECTION_NAME         = "artifactSets"
+	DOMAINS_OF_BLESSING_COLLECTION_NAME   = "domainsOfBlessing"
+	ARTIFACT_TYPES_COLLECTION_NAME        = "artifactTypes"
+	CHARACTER_PLANS_COLLECTION_NAME       = "characterPlans"
+	WEAPON_PLANS_COLLECTION_NAME          = "weaponPlans"
+	ARTIFACT_SETS_PLANS_COLLECTION_NAME   = "artifactSetsPlans"
+	ARTIFACT_TYPE_PLANS_COLLECTION_NAME   = "artifactTypePlans"
+	TEAM_PLANS_COLLECTION_NAME            = "teamPlans"
+	PLANS_VIEW_COLLECTION_NAME            = "plans"
+	PATCH_COLLECTION_NAME                 = "patch"
+	JOBS_COLLECTION_NAME                  = "_jobs"
+	ICONS_COLLECTION_NAME                 = "_icons"
+	TRANSLATIONS_COLLECTION_NAME          = "translations"
+	GAME_PROFILES_COLLECTION_NAME         = "gameProfiles"
+	WORKSPACES_COLLECTION_NAME            = "workspaces"
+	WORKSPACE_MEMBERS_COLLECTION_NAME     = "workspaceMembers"
+	WORKSPACE_INVITATIONS_COLLECTION_NAME = "workspaceInvitations"
+	PLAN_SNAPSHOTS_COLLECTION_NAME        = "planSnapshots"
 )
 
 // PLANS_COLLECTIONS lists the collections backing the plans view, i.e. the ones
@@ -32,3 +40,15 @@ var PLANS_COLLECTIONS = []string{
 	ARTIFACT_TYPE_PLANS_COLLECTION_NAME,
 	TEAM_PLANS_COLLECTION_NAME,
 }
+
+// TRANSLATED_COLLECTIONS lists the dictionary collections whose names can be
+// translated, see TRANSLATIONS_COLLECTION_NAME.
+var TRANSLATED_COLLECTIONS = []string{
+	CHARACTERS_COLLECTION_NAME,
+	WEAPONS_COLLECTION_NAME,
+	ARTIFACT_SETS_COLLECTION_NAME,
+	DOMAINS_OF_BLESSING_COLLECTION_NAME,
+	SPECIALS_COLLECTION_NAME,
+	CHARACTER_ROLES_COLLECTION_NAME,
+	ELEMENTS_COLLECTION_NAME,
+}
@@ -23,6 +23,12 @@ func (d *DbDump) Notes() string {
 	return d.GetString("notes")
 }
 
+// Encoding is the Content-Encoding of the stored dump file,
+// empty for an uncompressed one.
+func (d *DbDump) Encoding() string {
+	return d.GetString("encoding")
+}
+
 func (d *DbDump) DumpFilename() string {
 	return d.GetString("dump")
 }
@@ -47,6 +53,16 @@ func FindDbDumpById(app core.App, id string) (*DbDump, error) {
 	return dump, nil
 }
 
+func FindDbDumpByHash(app core.App, hash string) (*DbDump, error) {
+	rec, err := app.FindFirstRecordByData(DB_DUMPS_COLLECTION_NAME, "hash", hash)
+	if err != nil {
+		return nil, err
+	}
+	dump := &DbDump{}
+	dump.SetProxyRecord(rec)
+	return dump, nil
+}
+
 // FindLatestDbDump returns the most recently created dump
 // or sql.ErrNoRows if there is none.
 func FindLatestDbDump(app core.App) (*DbDump, error) {
@@ -62,6 +78,20 @@ func FindLatestDbDump(app core.App) (*DbDump, error) {
 	return dump, nil
 }
 
+// FindAllDbDumps returns every dump, the most recent first.
+func FindAllDbDumps(app core.App) ([]*DbDump, error) {
+	records, err := app.FindRecordsByFilter(DB_DUMPS_COLLECTION_NAME, "", "-created", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	dumps := make([]*DbDump, len(records))
+	for i, record := range records {
+		dumps[i] = &DbDump{}
+		dumps[i].SetProxyRecord(record)
+	}
+	return dumps, nil
+}
+
 // LatestDbDumpCache keeps the latest dump record in memory so that the hot
 // read paths (seed download, seed info) don't hit the db on every request.
 // The cache is dropped whenever the dumps collection changes, see Bind.
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ensures that the GameProfile struct satisfy the core.RecordProxy interface
+var _ core.RecordProxy = (*GameProfile)(nil)
+
+// GAME_SERVER_REGIONS are the game servers a profile can play on.
+var GAME_SERVER_REGIONS = []string{"america", "europe", "asia", "tw_hk_mo", "china"}
+
+// DEFAULT_GAME_PROFILE_NAME names the profile the plans go to when none is
+// given.
+const DEFAULT_GAME_PROFILE_NAME = "Default"
+
+// GameProfile is a game account of a user, the character plans belong to one.
+type GameProfile struct {
+	core.BaseRecordProxy
+}
+
+func NewGameProfile(app core.App, userId string, name string) (*GameProfile, error) {
+	collection, err := app.FindCachedCollectionByNameOrId(GAME_PROFILES_COLLECTION_NAME)
+	if err != nil {
+		return nil, err
+	}
+	profile := &GameProfile{}
+	profile.SetProxyRecord(core.NewRecord(collection))
+	profile.Set("user", userId)
+	profile.Set("name", name)
+	return profile, nil
+}
+
+func (p *GameProfile) UserId() string {
+	return p.GetString("user")
+}
+
+func (p *GameProfile) Name() string {
+	return p.GetString("name")
+}
+
+func (p *GameProfile) ServerRegion() string {
+	return p.GetString("serverRegion")
+}
+
+func (p *GameProfile) Uid() string {
+	return p.GetString("uid")
+}
+
+func FindGameProfileById(app core.App, id string) (*GameProfile, error) {
+	record, err := app.FindRecordById(GAME_PROFILES_COLLECTION_NAME, id)
+	if err != nil {
+		return nil, err
+	}
+	profile := &GameProfile{}
+	profile.SetProxyRecord(record)
+	return profile, nil
+}
+
+// FindDefaultGameProfile returns the first profile of the user, creating the
+// default one when the user has none yet.
+func FindDefaultGameProfile(app core.App, userId string) (*GameProfile, error) {
+	records := []*core.Record{}
+	err := app.RecordQuery(GAME_PROFILES_COLLECTION_NAME).
+		AndWhere(dbx.HashExp{"user": userId}).
+		OrderBy("[[created]] ASC", "[[id]] ASC").
+		Limit(1).
+		All(&records)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		profile := &GameProfile{}
+		profile.SetProxyRecord(records[0])
+		return profile, nil
+	}
+	profile, err := NewGameProfile(app, userId, DEFAULT_GAME_PROFILE_NAME)
+	if err != nil {
+		return nil, err
+	}
+	return profile, app.Save(profile)
+}
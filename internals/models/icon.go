@@ -0,0 +1,54 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ensures that the Icon struct satisfy the core.RecordProxy interface
+var _ core.RecordProxy = (*Icon)(nil)
+
+// Icon holds the variants of a normalized dictionary icon, one record per
+// content hash whatever the collections using it, see internals/icons.
+type Icon struct {
+	core.BaseRecordProxy
+}
+
+func (i *Icon) Hash() string {
+	return i.GetString("hash")
+}
+
+func (i *Icon) ImageFilename() string {
+	return i.GetString("image")
+}
+
+func (i *Icon) ThumbFilename() string {
+	return i.GetString("thumb")
+}
+
+func (i *Icon) Width() int {
+	return i.GetInt("width")
+}
+
+func (i *Icon) Height() int {
+	return i.GetInt("height")
+}
+
+func NewIcon(app core.App) (*Icon, error) {
+	collection, err := app.FindCachedCollectionByNameOrId(ICONS_COLLECTION_NAME)
+	if err != nil {
+		return nil, err
+	}
+	icon := &Icon{}
+	icon.SetProxyRecord(core.NewRecord(collection))
+	return icon, nil
+}
+
+func FindIconByHash(app core.App, hash string) (*Icon, error) {
+	rec, err := app.FindFirstRecordByData(ICONS_COLLECTION_NAME, "hash", hash)
+	if err != nil {
+		return nil, err
+	}
+	icon := &Icon{}
+	icon.SetProxyRecord(rec)
+	return icon, nil
+}
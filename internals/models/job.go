@@ -0,0 +1,96 @@
+package models
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ensures that the Job struct satisfy the core.RecordProxy interface
+var _ core.RecordProxy = (*Job)(nil)
+
+const (
+	JOB_STATUS_RUNNING = "running"
+	JOB_STATUS_DONE    = "done"
+	JOB_STATUS_FAILED  = "failed"
+)
+
+// Job tracks a background dump or seed operation, see internals/jobs.
+type Job struct {
+	core.BaseRecordProxy
+}
+
+func (j *Job) Kind() string {
+	return j.GetString("kind")
+}
+
+func (j *Job) Status() string {
+	return j.GetString("status")
+}
+
+func (j *Job) SetStatus(status string) {
+	j.Set("status", status)
+}
+
+func (j *Job) SetProgress(progress any) {
+	j.Set("progress", progress)
+}
+
+func (j *Job) SetResult(result any) {
+	j.Set("result", result)
+}
+
+func (j *Job) Error() string {
+	return j.GetString("error")
+}
+
+func (j *Job) SetError(err string) {
+	j.Set("error", err)
+}
+
+// IsFinished reports whether the job is either done or failed.
+func (j *Job) IsFinished() bool {
+	status := j.Status()
+	return status == JOB_STATUS_DONE || status == JOB_STATUS_FAILED
+}
+
+// NewJob creates an unsaved running job of the given kind.
+func NewJob(app core.App, kind string) (*Job, error) {
+	collection, err := app.FindCollectionByNameOrId(JOBS_COLLECTION_NAME)
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{}
+	job.SetProxyRecord(core.NewRecord(collection))
+	job.Set("kind", kind)
+	job.SetStatus(JOB_STATUS_RUNNING)
+	return job, nil
+}
+
+func FindJobById(app core.App, id string) (*Job, error) {
+	rec, err := app.FindRecordById(JOBS_COLLECTION_NAME, id)
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{}
+	job.SetProxyRecord(rec)
+	return job, nil
+}
+
+// FailRunningJobs marks the jobs left running by a previous process as
+// failed, their goroutine is gone with it.
+func FailRunningJobs(app core.App, reason string) error {
+	records, err := app.FindAllRecords(JOBS_COLLECTION_NAME, dbx.HashExp{"status": JOB_STATUS_RUNNING})
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		job := &Job{}
+		job.SetProxyRecord(rec)
+		job.SetStatus(JOB_STATUS_FAILED)
+		job.SetError(reason)
+		if err := app.Save(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ensures that the Translation struct satisfy the core.RecordProxy interface
+var _ core.RecordProxy = (*Translation)(nil)
+
+// DEFAULT_LOCALE is the locale of the dictionary names themselves.
+const DEFAULT_LOCALE = "en"
+
+// Translation is the name of a dictionary record in a locale, the locales are
+// lowercase BCP 47 tags, e.g. "ja" or "zh-cn".
+type Translation struct {
+	core.BaseRecordProxy
+}
+
+func (t *Translation) Collection() string {
+	return t.GetString("collection")
+}
+
+func (t *Translation) RecordId() string {
+	return t.GetString("record")
+}
+
+func (t *Translation) Locale() string {
+	return t.GetString("locale")
+}
+
+func (t *Translation) Name() string {
+	return t.GetString("name")
+}
+
+// FindTranslations returns the translations of the records of the collection
+// in any of the locales.
+func FindTranslations(app core.App, collection string, recordIds []string, locales []string) ([]*Translation, error) {
+	if len(recordIds) == 0 || len(locales) == 0 {
+		return []*Translation{}, nil
+	}
+	records := []*core.Record{}
+	err := app.RecordQuery(TRANSLATIONS_COLLECTION_NAME).
+		AndWhere(dbx.HashExp{"collection": collection}).
+		AndWhere(dbx.In("record", toAny(recordIds)...)).
+		AndWhere(dbx.In("locale", toAny(locales)...)).
+		All(&records)
+	if err != nil {
+		return nil, err
+	}
+	translations := make([]*Translation, len(records))
+	for i, rec := range records {
+		translations[i] = &Translation{}
+		translations[i].SetProxyRecord(rec)
+	}
+	return translations, nil
+}
+
+func toAny(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// ensures that the workspace structs satisfy the core.RecordProxy interface
+var (
+	_ core.RecordProxy = (*WorkspaceMember)(nil)
+	_ core.RecordProxy = (*WorkspaceInvitation)(nil)
+)
+
+// The roles of the workspace members: the owners manage the workspace and its
+// members, the editors write the plans of the workspace, the viewers read
+// them.
+const (
+	WORKSPACE_ROLE_OWNER  = "owner"
+	WORKSPACE_ROLE_EDITOR = "editor"
+	WORKSPACE_ROLE_VIEWER = "viewer"
+)
+
+var WORKSPACE_ROLES = []string{WORKSPACE_ROLE_OWNER, WORKSPACE_ROLE_EDITOR, WORKSPACE_ROLE_VIEWER}
+
+// WORKSPACE_INVITATION_TTL is how long an invitation can be accepted.
+const WORKSPACE_INVITATION_TTL = 7 * 24 * time.Hour
+
+// WorkspaceMember is the membership of a user in a workspace.
+type WorkspaceMember struct {
+	core.BaseRecordProxy
+}
+
+func NewWorkspaceMember(app core.App, workspaceId string, userId string, role string) (*WorkspaceMember, error) {
+	collection, err := app.FindCachedCollectionByNameOrId(WORKSPACE_MEMBERS_COLLECTION_NAME)
+	if err != nil {
+		return nil, err
+	}
+	member := &WorkspaceMember{}
+	member.SetProxyRecord(core.NewRecord(collection))
+	member.Set("workspace", workspaceId)
+	member.Set("user", userId)
+	member.SetRole(role)
+	return member, nil
+}
+
+func (m *WorkspaceMember) WorkspaceId() string {
+	return m.GetString("workspace")
+}
+
+func (m *WorkspaceMember) UserId() string {
+	return m.GetString("user")
+}
+
+func (m *WorkspaceMember) Role() string {
+	return m.GetString("role")
+}
+
+func (m *WorkspaceMember) SetRole(role string) {
+	m.Set("role", role)
+}
+
+// CanWrite reports whether the member can write the plans of the workspace.
+func (m *WorkspaceMember) CanWrite() bool {
+	return m.Role() == WORKSPACE_ROLE_OWNER || m.Role() == WORKSPACE_ROLE_EDITOR
+}
+
+// FindWorkspaceMember returns the membership of the user in the workspace,
+// sql.ErrNoRows if the user isn't a member.
+func FindWorkspaceMember(app core.App, workspaceId string, userId string) (*WorkspaceMember, error) {
+	record, err := app.FindFirstRecordByFilter(
+		WORKSPACE_MEMBERS_COLLECTION_NAME,
+		"workspace = {:workspace} && user = {:user}",
+		dbx.Params{"workspace": workspaceId, "user": userId},
+	)
+	if err != nil {
+		return nil, err
+	}
+	member := &WorkspaceMember{}
+	member.SetProxyRecord(record)
+	return member, nil
+}
+
+func FindWorkspaceMembers(app core.App, workspaceId string) ([]*WorkspaceMember, error) {
+	records, err := app.FindAllRecords(WORKSPACE_MEMBERS_COLLECTION_NAME, dbx.HashExp{"workspace": workspaceId})
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*WorkspaceMember, len(records))
+	for i, record := range records {
+		members[i] = &WorkspaceMember{}
+		members[i].SetProxyRecord(record)
+	}
+	return members, nil
+}
+
+// WorkspaceInvitation lets whoever holds its token join the workspace with
+// the role, or only the user of the email when it is set.
+type WorkspaceInvitation struct {
+	core.BaseRecordProxy
+}
+
+func NewWorkspaceInvitation(app core.App, workspaceId string, role string, email string) (*WorkspaceInvitation, error) {
+	collection, err := app.FindCachedCollectionByNameOrId(WORKSPACE_INVITATIONS_COLLECTION_NAME)
+	if err != nil {
+		return nil, err
+	}
+	invitation := &WorkspaceInvitation{}
+	invitation.SetProxyRecord(core.NewRecord(collection))
+	invitation.Set("workspace", workspaceId)
+	invitation.Set("role", role)
+	invitation.Set("email", email)
+	invitation.Set("token", security.RandomString(40))
+	invitation.Set("expires", types.NowDateTime().Add(WORKSPACE_INVITATION_TTL))
+	return invitation, nil
+}
+
+func (i *WorkspaceInvitation) WorkspaceId() string {
+	return i.GetString("workspace")
+}
+
+func (i *WorkspaceInvitation) Role() string {
+	return i.GetString("role")
+}
+
+func (i *WorkspaceInvitation) Email() string {
+	return i.GetString("email")
+}
+
+func (i *WorkspaceInvitation) Token() string {
+	return i.GetString("token")
+}
+
+func (i *WorkspaceInvitation) Expires() types.DateTime {
+	return i.GetDateTime("expires")
+}
+
+func (i *WorkspaceInvitation) IsExpired() bool {
+	return i.Expires().Time().Before(time.Now())
+}
+
+func FindWorkspaceInvitationByToken(app core.App, token string) (*WorkspaceInvitation, error) {
+	record, err := app.FindFirstRecordByData(WORKSPACE_INVITATIONS_COLLECTION_NAME, "token", token)
+	if err != nil {
+		return nil, err
+	}
+	invitation := &WorkspaceInvitation{}
+	invitation.SetProxyRecord(record)
+	return invitation, nil
+}
@@ -0,0 +1,149 @@
+package plans
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// cacheKey names the plans of a user or of a workspace.
+type cacheKey struct {
+	field string
+	id    string
+}
+
+func userKey(userId string) cacheKey {
+	return cacheKey{"user", userId}
+}
+
+func workspaceKey(workspaceId string) cacheKey {
+	return cacheKey{"workspace", workspaceId}
+}
+
+// Cache keeps the assembled plans per user and per workspace in memory,
+// dropped whenever one of the plans collections changes for them, see Bind.
+// Only the changes saved through this process reach the hooks, a running
+// server keeps serving the plans it cached before a change made by another
+// process, e.g. the RepairOrders of `gbp doctor --fix`, until it restarts.
+type Cache struct {
+	mutex sync.RWMutex
+	plans map[cacheKey][]Plan
+	// owners maps the cached plan ids to the entries holding them, so that a
+	// change of a child plan finds the entries to drop without a query, even
+	// once the plan itself is deleted
+	owners map[string][]cacheKey
+	// generation is bumped on every invalidation, a load that raced one isn't
+	// stored
+	generation uint64
+}
+
+func NewCache() *Cache {
+	return &Cache{plans: map[cacheKey][]Plan{}, owners: map[string][]cacheKey{}}
+}
+
+// Bind subscribes the cache to the plans collections changes.
+func (c *Cache) Bind(app core.App) {
+	invalidate := func(e *core.RecordEvent) error {
+		c.invalidateRecord(e.Record)
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess(models.PLANS_COLLECTIONS...).BindFunc(invalidate)
+	app.OnRecordAfterUpdateSuccess(models.PLANS_COLLECTIONS...).BindFunc(invalidate)
+	app.OnRecordAfterDeleteSuccess(models.PLANS_COLLECTIONS...).BindFunc(invalidate)
+}
+
+func (c *Cache) invalidateRecord(record *core.Record) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	if record.Collection().Name == models.CHARACTER_PLANS_COLLECTION_NAME {
+		// the original is the saved state by now, the entries the plan moved
+		// out of are the ones holding it
+		for _, key := range slices.Clone(c.owners[record.Id]) {
+			c.dropLocked(key)
+		}
+		c.dropLocked(userKey(record.GetString("user")))
+		c.dropLocked(workspaceKey(record.GetString("workspace")))
+		return
+	}
+	for _, planId := range []string{record.GetString("characterPlan"), record.Original().GetString("characterPlan")} {
+		for _, key := range slices.Clone(c.owners[planId]) {
+			c.dropLocked(key)
+		}
+	}
+}
+
+func (c *Cache) dropLocked(key cacheKey) {
+	for _, plan := range c.plans[key] {
+		c.owners[plan.Id] = slices.DeleteFunc(c.owners[plan.Id], func(owner cacheKey) bool { return owner == key })
+		if len(c.owners[plan.Id]) == 0 {
+			delete(c.owners, plan.Id)
+		}
+	}
+	delete(c.plans, key)
+}
+
+// Invalidate drops the cached plans of the user.
+func (c *Cache) Invalidate(userId string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	c.dropLocked(userKey(userId))
+}
+
+// Get returns a copy of the cached plans of the user, loading them on the
+// first call after an invalidation. The copy is the caller's to modify.
+func (c *Cache) Get(app core.App, userId string) ([]Plan, error) {
+	return c.get(userKey(userId), func() ([]Plan, error) { return Load(app, userId) })
+}
+
+// GetWorkspace is Get for the plans of the workspace.
+func (c *Cache) GetWorkspace(app core.App, workspaceId string) ([]Plan, error) {
+	return c.get(workspaceKey(workspaceId), func() ([]Plan, error) { return LoadWorkspace(app, workspaceId) })
+}
+
+func (c *Cache) get(key cacheKey, load func() ([]Plan, error)) ([]Plan, error) {
+	c.mutex.RLock()
+	plans, ok := c.plans[key]
+	generation := c.generation
+	c.mutex.RUnlock()
+	if ok {
+		return clonePlans(plans), nil
+	}
+
+	plans, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	if c.generation == generation {
+		c.plans[key] = plans
+		for _, plan := range plans {
+			c.owners[plan.Id] = append(c.owners[plan.Id], key)
+		}
+	}
+	c.mutex.Unlock()
+	return clonePlans(plans), nil
+}
+
+func clonePlans(plans []Plan) []Plan {
+	plans = slices.Clone(plans)
+	for i := range plans {
+		plan := &plans[i]
+		plan.Substats = slices.Clone(plan.Substats)
+		plan.WeaponPlans = slices.Clone(plan.WeaponPlans)
+		plan.ArtifactTypePlans = slices.Clone(plan.ArtifactTypePlans)
+		plan.ArtifactSetsPlans = slices.Clone(plan.ArtifactSetsPlans)
+		for j := range plan.ArtifactSetsPlans {
+			plan.ArtifactSetsPlans[j].ArtifactSets = slices.Clone(plan.ArtifactSetsPlans[j].ArtifactSets)
+		}
+		plan.TeamPlans = slices.Clone(plan.TeamPlans)
+		for j := range plan.TeamPlans {
+			plan.TeamPlans[j].Characters = slices.Clone(plan.TeamPlans[j].Characters)
+		}
+	}
+	return plans
+}
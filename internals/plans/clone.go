@@ -0,0 +1,137 @@
+package plans
+
+import (
+	"slices"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// childCollections are the plans collections of the records hanging off a
+// character plan.
+var childCollections = []string{
+	models.WEAPON_PLANS_COLLECTION_NAME,
+	models.ARTIFACT_SETS_PLANS_COLLECTION_NAME,
+	models.ARTIFACT_TYPE_PLANS_COLLECTION_NAME,
+	models.TEAM_PLANS_COLLECTION_NAME,
+}
+
+// resetValues are the current values of a fresh plan in the ui, by
+// collection, see newCharacterPlan and the weapon plans mutation.
+var resetValues = map[string]map[string]any{
+	models.CHARACTER_PLANS_COLLECTION_NAME: {
+		"complete":             false,
+		"levelCurrent":         1,
+		"constellationCurrent": 0,
+		"talentAtkCurrent":     1,
+		"talentSkillCurrent":   1,
+		"talentBurstCurrent":   1,
+	},
+	models.WEAPON_PLANS_COLLECTION_NAME: {
+		"levelCurrent":      0,
+		"refinementCurrent": 1,
+	},
+}
+
+// copyRecord returns an unsaved copy of the record with a new id and the
+// overrides applied.
+func copyRecord(record *core.Record, overrides map[string]any) *core.Record {
+	clone := core.NewRecord(record.Collection())
+	for _, field := range record.Collection().Fields {
+		if _, ok := field.(*core.AutodateField); ok || field.GetName() == core.FieldNameId {
+			continue
+		}
+		clone.Set(field.GetName(), record.Get(field.GetName()))
+	}
+	for key, value := range overrides {
+		clone.Set(key, value)
+	}
+	return clone
+}
+
+// Clone copies the character plan with all its child plans in one
+// transaction and places the copy right after it, renumbering the plans of
+// its scope densely, see scopeOf. The plan is one of the user or of a
+// workspace the user can write to, the copy of a plan of another member
+// belongs to the user and stays in the workspace. With resetCurrent the copy
+// starts from the current values of a fresh plan, the targets kept. With a
+// profile id the copy goes to that game profile of the user instead of the one
+// of the plan. Returns the saved copy.
+func Clone(app core.App, userId string, planId string, profileId string, resetCurrent bool) (*core.Record, error) {
+	var clone *core.Record
+	err := app.RunInTransaction(func(txApp core.App) error {
+		plan, err := txApp.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, planId)
+		if err != nil {
+			return ErrPlanNotFound
+		}
+		if ok, err := canWrite(txApp, plan, userId); err != nil {
+			return err
+		} else if !ok {
+			return ErrPlanNotFound
+		}
+
+		overrides := map[string]any{}
+		if resetCurrent {
+			overrides = resetValues[models.CHARACTER_PLANS_COLLECTION_NAME]
+		}
+		clone = copyRecord(plan, overrides)
+		if plan.GetString("user") != userId {
+			// the profile belongs to the other member
+			profile, err := models.FindDefaultGameProfile(txApp, userId)
+			if err != nil {
+				return err
+			}
+			clone.Set("user", userId)
+			clone.Set("profile", profile.Id)
+		}
+		if profileId != "" {
+			if _, err := FindProfile(txApp, userId, profileId); err != nil {
+				return err
+			}
+			clone.Set("profile", profileId)
+		}
+		clone.Set("order", plan.GetInt("order")+1)
+		if err := txApp.Save(clone); err != nil {
+			return err
+		}
+
+		for _, collection := range childCollections {
+			children := []*core.Record{}
+			err := txApp.RecordQuery(collection).
+				AndWhere(dbx.HashExp{"characterPlan": plan.Id}).
+				All(&children)
+			if err != nil {
+				return err
+			}
+			overrides := map[string]any{}
+			if resetCurrent {
+				for key, value := range resetValues[collection] {
+					overrides[key] = value
+				}
+			}
+			overrides["characterPlan"] = clone.Id
+			for _, child := range children {
+				if err := txApp.Save(copyRecord(child, overrides)); err != nil {
+					return err
+				}
+			}
+		}
+
+		scope, scopeValue := scopeOf(models.CHARACTER_PLANS_COLLECTION_NAME, clone)
+		ordered, err := findScope(txApp, models.CHARACTER_PLANS_COLLECTION_NAME, scope, scopeValue)
+		if err != nil {
+			return err
+		}
+		ordered = slices.DeleteFunc(ordered, func(record *core.Record) bool { return record.Id == clone.Id })
+		at := slices.IndexFunc(ordered, func(record *core.Record) bool { return record.Id == plan.Id })
+		ordered = slices.Insert(ordered, at+1, clone)
+		_, err = renumber(txApp, ordered)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
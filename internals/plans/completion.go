@@ -0,0 +1,140 @@
+package plans
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// TaggedWeapon returns the weapon plan the progress of a plan follows, the
+// one tagged target, or else the one tagged current.
+func TaggedWeapon(weaponPlans []*core.Record) *core.Record {
+	for _, tag := range []string{"target", "current"} {
+		if i := slices.IndexFunc(weaponPlans, func(wp *core.Record) bool { return wp.GetString("tag") == tag }); i != -1 {
+			return weaponPlans[i]
+		}
+	}
+	return nil
+}
+
+// Completion returns how far the plan is from its targets in percent: the
+// mean progress of the level, the constellation, the talents and the level
+// and refinement of its tagged weapon, see TaggedWeapon, that have a
+// target. A plan without targets is at 0.
+func Completion(plan *core.Record, weaponPlans []*core.Record) int {
+	pairs := [][2]int{
+		{plan.GetInt("levelCurrent"), plan.GetInt("levelTarget")},
+		{plan.GetInt("constellationCurrent"), plan.GetInt("constellationTarget")},
+		{plan.GetInt("talentAtkCurrent"), plan.GetInt("talentAtkTarget")},
+		{plan.GetInt("talentSkillCurrent"), plan.GetInt("talentSkillTarget")},
+		{plan.GetInt("talentBurstCurrent"), plan.GetInt("talentBurstTarget")},
+	}
+	if weapon := TaggedWeapon(weaponPlans); weapon != nil {
+		pairs = append(pairs,
+			[2]int{weapon.GetInt("levelCurrent"), weapon.GetInt("levelTarget")},
+			[2]int{weapon.GetInt("refinementCurrent"), weapon.GetInt("refinementTarget")},
+		)
+	}
+	progress := 0.0
+	targets := 0
+	for _, pair := range pairs {
+		current, target := pair[0], pair[1]
+		if target <= 0 {
+			continue
+		}
+		progress += float64(min(max(current, 0), target)) / float64(target)
+		targets++
+	}
+	if targets == 0 {
+		return 0
+	}
+	return int(progress / float64(targets) * 100)
+}
+
+// setCompletion fills the completion fields of the plan from its current
+// state. Reaching 100 suggests completing the plan, or completes it when its
+// user has autoCompletePlans on.
+func setCompletion(app core.App, plan *core.Record) error {
+	weaponPlans := []*core.Record{}
+	if !plan.IsNew() {
+		err := app.RecordQuery(models.WEAPON_PLANS_COLLECTION_NAME).
+			AndWhere(dbx.HashExp{"characterPlan": plan.Id}).
+			OrderBy("[[order]] ASC", "[[created]] ASC").
+			All(&weaponPlans)
+		if err != nil {
+			return err
+		}
+	}
+	completion := Completion(plan, weaponPlans)
+	plan.Set("completion", completion)
+	if completion == 100 && plan.Original().GetInt("completion") < 100 && !plan.GetBool("complete") {
+		user, err := app.FindRecordById(models.USERS_COLLECTION_NAME, plan.GetString("user"))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if user != nil && user.GetBool("autoCompletePlans") {
+			plan.Set("complete", true)
+		}
+	}
+	plan.Set("completeSuggested", completion == 100 && !plan.GetBool("complete"))
+	return nil
+}
+
+// refreshCompletion saves the completion of the plan after a change of its
+// weapon plans, unless the plan is gone or its completion stays the same.
+func refreshCompletion(app core.App, planId string) error {
+	plan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, planId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := setCompletion(app, plan); err != nil {
+		return err
+	}
+	original := plan.Original()
+	if plan.GetInt("completion") == original.GetInt("completion") &&
+		plan.GetBool("completeSuggested") == original.GetBool("completeSuggested") &&
+		plan.GetBool("complete") == original.GetBool("complete") {
+		return nil
+	}
+	return app.Save(plan)
+}
+
+// BindCompletion keeps the completion and completeSuggested fields of the
+// character plans up to date with their values and their weapon plans.
+func BindCompletion(app core.App) {
+	compute := func(e *core.RecordEvent) error {
+		if err := setCompletion(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	}
+	app.OnRecordCreate(models.CHARACTER_PLANS_COLLECTION_NAME).BindFunc(compute)
+	app.OnRecordUpdate(models.CHARACTER_PLANS_COLLECTION_NAME).BindFunc(compute)
+
+	refresh := func(e *core.RecordEvent) error {
+		// the original is the saved state once Next returns
+		planIds := slices.Compact([]string{e.Record.GetString("characterPlan"), e.Record.Original().GetString("characterPlan")})
+		if err := e.Next(); err != nil {
+			return err
+		}
+		for _, planId := range planIds {
+			if planId == "" {
+				continue
+			}
+			if err := refreshCompletion(e.App, planId); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	app.OnRecordCreate(models.WEAPON_PLANS_COLLECTION_NAME).BindFunc(refresh)
+	app.OnRecordUpdate(models.WEAPON_PLANS_COLLECTION_NAME).BindFunc(refresh)
+	app.OnRecordDelete(models.WEAPON_PLANS_COLLECTION_NAME).BindFunc(refresh)
+}
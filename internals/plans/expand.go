@@ -0,0 +1,157 @@
+package plans
+
+import (
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/i18n"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// Dictionary holds the dictionary records the plans reference, by collection
+// and id.
+type Dictionary map[string]map[string]*core.Record
+
+func (d Dictionary) get(collection string, id string) *core.Record {
+	return d[collection][id]
+}
+
+func (d Dictionary) list(collection string, ids []string) []*core.Record {
+	records := make([]*core.Record, 0, len(ids))
+	for _, id := range ids {
+		if record := d.get(collection, id); record != nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// expandOne adds the record to the expand map unless it is missing, like the
+// expand of the record endpoints.
+func expandOne(expand map[string]any, key string, record *core.Record) {
+	if record != nil {
+		expand[key] = record
+	}
+}
+
+// LoadDictionary loads the dictionary records the plans reference, with the
+// names localized, the characters and the weapons get their own relations
+// expanded.
+func LoadDictionary(app core.App, plans []Plan, locales []string) (Dictionary, error) {
+	ids := map[string][]string{}
+	add := func(collection string, values ...string) {
+		for _, id := range values {
+			if id != "" && !slices.Contains(ids[collection], id) {
+				ids[collection] = append(ids[collection], id)
+			}
+		}
+	}
+	for _, plan := range plans {
+		add(models.CHARACTERS_COLLECTION_NAME, plan.Character)
+		add(models.CHARACTER_ROLES_COLLECTION_NAME, plan.CharacterRole)
+		add(models.PATCH_COLLECTION_NAME, plan.TargetPatch)
+		add(models.SPECIALS_COLLECTION_NAME, plan.Substats...)
+		for _, wp := range plan.WeaponPlans {
+			add(models.WEAPONS_COLLECTION_NAME, wp.Weapon)
+		}
+		for _, atp := range plan.ArtifactTypePlans {
+			add(models.ARTIFACT_TYPES_COLLECTION_NAME, atp.ArtifactType)
+			add(models.SPECIALS_COLLECTION_NAME, atp.Special)
+		}
+		for _, asp := range plan.ArtifactSetsPlans {
+			add(models.ARTIFACT_SETS_COLLECTION_NAME, asp.ArtifactSets...)
+		}
+		for _, tp := range plan.TeamPlans {
+			add(models.CHARACTERS_COLLECTION_NAME, tp.Characters...)
+		}
+	}
+
+	dictionary := Dictionary{}
+	load := func(collection string) error {
+		records, err := app.FindRecordsByIds(collection, ids[collection])
+		if err != nil {
+			return err
+		}
+		if err := i18n.Localize(app, collection, records, locales); err != nil {
+			return err
+		}
+		dictionary[collection] = map[string]*core.Record{}
+		for _, record := range records {
+			dictionary[collection][record.Id] = record
+		}
+		return nil
+	}
+	// the characters and the weapons first, their relations are added to
+	// the ids to load
+	for _, collection := range []string{models.CHARACTERS_COLLECTION_NAME, models.WEAPONS_COLLECTION_NAME} {
+		if err := load(collection); err != nil {
+			return nil, err
+		}
+		for _, record := range dictionary[collection] {
+			add(models.ELEMENTS_COLLECTION_NAME, record.GetString("element"))
+			add(models.WEAPON_TYPES_COLLECTION_NAME, record.GetString("weaponType"))
+			add(models.SPECIALS_COLLECTION_NAME, record.GetString("special"))
+		}
+	}
+	for _, collection := range []string{
+		models.CHARACTER_ROLES_COLLECTION_NAME,
+		models.SPECIALS_COLLECTION_NAME,
+		models.ARTIFACT_TYPES_COLLECTION_NAME,
+		models.ARTIFACT_SETS_COLLECTION_NAME,
+		models.ELEMENTS_COLLECTION_NAME,
+		models.WEAPON_TYPES_COLLECTION_NAME,
+		models.PATCH_COLLECTION_NAME,
+	} {
+		if err := load(collection); err != nil {
+			return nil, err
+		}
+	}
+	for _, collection := range []string{models.CHARACTERS_COLLECTION_NAME, models.WEAPONS_COLLECTION_NAME} {
+		for _, record := range dictionary[collection] {
+			expand := map[string]any{}
+			expandOne(expand, "element", dictionary.get(models.ELEMENTS_COLLECTION_NAME, record.GetString("element")))
+			expandOne(expand, "weaponType", dictionary.get(models.WEAPON_TYPES_COLLECTION_NAME, record.GetString("weaponType")))
+			expandOne(expand, "special", dictionary.get(models.SPECIALS_COLLECTION_NAME, record.GetString("special")))
+			record.SetExpand(expand)
+		}
+	}
+	return dictionary, nil
+}
+
+// Characters returns the loaded characters by id.
+func (d Dictionary) Characters() map[string]*core.Record {
+	return d[models.CHARACTERS_COLLECTION_NAME]
+}
+
+// Expand sets the expand of the plans and their child plans from the
+// dictionary.
+func (d Dictionary) Expand(plans []Plan) {
+	for i := range plans {
+		plan := &plans[i]
+		plan.Expand = map[string]any{}
+		expandOne(plan.Expand, "character", d.get(models.CHARACTERS_COLLECTION_NAME, plan.Character))
+		expandOne(plan.Expand, "characterRole", d.get(models.CHARACTER_ROLES_COLLECTION_NAME, plan.CharacterRole))
+		expandOne(plan.Expand, "targetPatch", d.get(models.PATCH_COLLECTION_NAME, plan.TargetPatch))
+		plan.Expand["substats"] = d.list(models.SPECIALS_COLLECTION_NAME, plan.Substats)
+		for j := range plan.WeaponPlans {
+			wp := &plan.WeaponPlans[j]
+			wp.Expand = map[string]any{}
+			expandOne(wp.Expand, "weapon", d.get(models.WEAPONS_COLLECTION_NAME, wp.Weapon))
+		}
+		for j := range plan.ArtifactTypePlans {
+			atp := &plan.ArtifactTypePlans[j]
+			atp.Expand = map[string]any{}
+			expandOne(atp.Expand, "artifactType", d.get(models.ARTIFACT_TYPES_COLLECTION_NAME, atp.ArtifactType))
+			expandOne(atp.Expand, "special", d.get(models.SPECIALS_COLLECTION_NAME, atp.Special))
+		}
+		for j := range plan.ArtifactSetsPlans {
+			asp := &plan.ArtifactSetsPlans[j]
+			asp.Expand = map[string]any{"artifactSets": d.list(models.ARTIFACT_SETS_COLLECTION_NAME, asp.ArtifactSets)}
+		}
+		for j := range plan.TeamPlans {
+			tp := &plan.TeamPlans[j]
+			tp.Expand = map[string]any{"characters": d.list(models.CHARACTERS_COLLECTION_NAME, tp.Characters)}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package plans
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/fuzzy"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// Filters mirror the plans filters of the ui, see ui/src/store/plans/filters.tsx.
+type Filters struct {
+	Name string
+	// Complete includes the complete plans, they are left out otherwise
+	Complete     bool
+	Elements     []string
+	WeaponTypes  []string
+	Characters   []string
+	ArtifactSets []string
+	// SpecialsByArtifactType requires, per artifact type, a plan of one of
+	// the specials
+	SpecialsByArtifactType map[string][]string
+	// Priority is the lowest priority kept
+	Priority      int
+	TargetPatches []string
+}
+
+// queryList reads a list param, repeated or comma separated.
+func queryList(values url.Values, key string) []string {
+	list := []string{}
+	for _, value := range values[key] {
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" && !slices.Contains(list, item) {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// ParseFilters reads the filters from the query params:
+//
+//	name=dil&complete=true&elements=a,b&weaponTypes=a&characters=a
+//	artifactSets=a,b&specials[<artifactType>]=a,b&priority=2&targetPatches=a,b
+func ParseFilters(values url.Values) (Filters, error) {
+	f := Filters{
+		Name:                   strings.TrimSpace(values.Get("name")),
+		Elements:               queryList(values, "elements"),
+		WeaponTypes:            queryList(values, "weaponTypes"),
+		Characters:             queryList(values, "characters"),
+		ArtifactSets:           queryList(values, "artifactSets"),
+		SpecialsByArtifactType: map[string][]string{},
+		TargetPatches:          queryList(values, "targetPatches"),
+	}
+	if raw := values.Get("complete"); raw != "" {
+		complete, err := strconv.ParseBool(raw)
+		if err != nil {
+			return f, fmt.Errorf("complete: %q isn't a boolean", raw)
+		}
+		f.Complete = complete
+	}
+	if raw := values.Get("priority"); raw != "" {
+		priority, err := strconv.Atoi(raw)
+		if err != nil || priority < 0 || priority > models.MAX_PLAN_PRIORITY {
+			return f, fmt.Errorf("priority: %q isn't a priority from 0 to %d", raw, models.MAX_PLAN_PRIORITY)
+		}
+		f.Priority = priority
+	}
+	for key := range values {
+		artifactType, ok := strings.CutPrefix(key, "specials[")
+		if !ok {
+			continue
+		}
+		artifactType, ok = strings.CutSuffix(artifactType, "]")
+		if !ok || artifactType == "" {
+			return f, fmt.Errorf("%s: expected specials[<artifactType>]", key)
+		}
+		if specials := queryList(values, key); len(specials) > 0 {
+			f.SpecialsByArtifactType[artifactType] = specials
+		}
+	}
+	return f, nil
+}
+
+// Enabled reports whether any filter beside Complete is set.
+func (f Filters) Enabled() bool {
+	return f.Name != "" ||
+		len(f.Elements) > 0 ||
+		len(f.WeaponTypes) > 0 ||
+		len(f.Characters) > 0 ||
+		len(f.ArtifactSets) > 0 ||
+		len(f.SpecialsByArtifactType) > 0 ||
+		f.Priority > 0 ||
+		len(f.TargetPatches) > 0
+}
+
+func nameMatches(query string, character *core.Record) bool {
+	for _, name := range append([]string{character.GetString("name")}, character.GetStringSlice("aliases")...) {
+		if fuzzy.Score(query, name) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether the plan of the character passes the filters.
+func (f Filters) Match(plan Plan, character *core.Record) bool {
+	if !f.Complete && plan.Complete {
+		return false
+	}
+	if !f.Enabled() {
+		return true
+	}
+	if character == nil {
+		return false
+	}
+	if len(f.Elements) > 0 && !slices.Contains(f.Elements, character.GetString("element")) {
+		return false
+	}
+	if len(f.WeaponTypes) > 0 && !slices.Contains(f.WeaponTypes, character.GetString("weaponType")) {
+		return false
+	}
+	if len(f.Characters) > 0 && !slices.Contains(f.Characters, plan.Character) {
+		return false
+	}
+	if plan.Priority < f.Priority {
+		return false
+	}
+	if len(f.TargetPatches) > 0 && !slices.Contains(f.TargetPatches, plan.TargetPatch) {
+		return false
+	}
+	for artifactType, specials := range f.SpecialsByArtifactType {
+		if !slices.ContainsFunc(plan.ArtifactTypePlans, func(atp ArtifactTypePlan) bool {
+			return atp.ArtifactType == artifactType && slices.Contains(specials, atp.Special)
+		}) {
+			return false
+		}
+	}
+	if len(f.ArtifactSets) > 0 && !slices.ContainsFunc(plan.ArtifactSetsPlans, func(asp ArtifactSetsPlan) bool {
+		return slices.ContainsFunc(asp.ArtifactSets, func(id string) bool { return slices.Contains(f.ArtifactSets, id) })
+	}) {
+		return false
+	}
+	return f.Name == "" || nameMatches(f.Name, character)
+}
+
+// Facets are the filter values the plans offer, the selected ones included.
+type Facets struct {
+	Elements               []string            `json:"elements"`
+	WeaponTypes            []string            `json:"weaponTypes"`
+	Characters             []string            `json:"characters"`
+	ArtifactSets           []string            `json:"artifactSets"`
+	SpecialsByArtifactType map[string][]string `json:"specialsByArtifactType"`
+	TargetPatches          []string            `json:"targetPatches"`
+}
+
+type set map[string]struct{}
+
+func newSet(values ...string) set {
+	s := set{}
+	for _, v := range values {
+		s.add(v)
+	}
+	return s
+}
+
+func (s set) add(value string) {
+	if value != "" {
+		s[value] = struct{}{}
+	}
+}
+
+func (s set) sorted() []string {
+	return slices.Sorted(maps.Keys(s))
+}
+
+// NewFacets lists the filter values of the plans the way the ui does: the
+// dictionaries of every plan the Complete filter keeps, and the specials of
+// the plans passing all the filters.
+func NewFacets(plans []Plan, characters map[string]*core.Record, f Filters) Facets {
+	elements := newSet(f.Elements...)
+	weaponTypes := newSet(f.WeaponTypes...)
+	charactersSet := newSet(f.Characters...)
+	artifactSets := newSet(f.ArtifactSets...)
+	targetPatches := newSet(f.TargetPatches...)
+	specials := map[string]set{}
+	for artifactType, selected := range f.SpecialsByArtifactType {
+		specials[artifactType] = newSet(selected...)
+	}
+
+	for _, plan := range plans {
+		if !f.Complete && plan.Complete {
+			continue
+		}
+		character := characters[plan.Character]
+		if character == nil {
+			continue
+		}
+		elements.add(character.GetString("element"))
+		weaponTypes.add(character.GetString("weaponType"))
+		charactersSet.add(character.Id)
+		targetPatches.add(plan.TargetPatch)
+		for _, asp := range plan.ArtifactSetsPlans {
+			for _, id := range asp.ArtifactSets {
+				artifactSets.add(id)
+			}
+		}
+		if f.Match(plan, character) {
+			for _, atp := range plan.ArtifactTypePlans {
+				if specials[atp.ArtifactType] == nil {
+					specials[atp.ArtifactType] = set{}
+				}
+				specials[atp.ArtifactType].add(atp.Special)
+			}
+		}
+	}
+
+	facets := Facets{
+		Elements:               elements.sorted(),
+		WeaponTypes:            weaponTypes.sorted(),
+		Characters:             charactersSet.sorted(),
+		ArtifactSets:           artifactSets.sorted(),
+		SpecialsByArtifactType: map[string][]string{},
+		TargetPatches:          targetPatches.sorted(),
+	}
+	for artifactType, s := range specials {
+		facets.SpecialsByArtifactType[artifactType] = s.sorted()
+	}
+	return facets
+}
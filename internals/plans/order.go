@@ -0,0 +1,252 @@
+package plans
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/workspaces"
+)
+
+var (
+	ErrNotOrdered   = errors.New("the collection has no order")
+	ErrEmptyOrder   = errors.New("no ids to order")
+	ErrDuplicateId  = errors.New("duplicate id")
+	ErrPlanNotFound = errors.New("plan not found")
+	ErrMixedParents = errors.New("the plans belong to different character plans")
+)
+
+// orderScopes are the collections with an order field, by the field the
+// order is dense within. The character plans put in a workspace are ordered
+// within the workspace instead, see scopeOf.
+var orderScopes = map[string]string{
+	models.CHARACTER_PLANS_COLLECTION_NAME:     "user",
+	models.WEAPON_PLANS_COLLECTION_NAME:        "characterPlan",
+	models.ARTIFACT_SETS_PLANS_COLLECTION_NAME: "characterPlan",
+}
+
+// OrderedCollections lists the plans collections Reorder accepts.
+func OrderedCollections() []string {
+	return []string{
+		models.CHARACTER_PLANS_COLLECTION_NAME,
+		models.WEAPON_PLANS_COLLECTION_NAME,
+		models.ARTIFACT_SETS_PLANS_COLLECTION_NAME,
+	}
+}
+
+// scopeOf returns the field and the value the order of the record is dense
+// within: the character plans of a workspace share the order of the
+// workspace, whichever member they belong to.
+func scopeOf(collection string, record *core.Record) (string, string) {
+	scope := orderScopes[collection]
+	if scope == "user" {
+		if workspaceId := record.GetString("workspace"); workspaceId != "" {
+			return "workspace", workspaceId
+		}
+	}
+	return scope, record.GetString(scope)
+}
+
+// findScope returns the records sharing the scope value, in their current
+// order. The user scope leaves out the plans put in a workspace.
+func findScope(app core.App, collection string, scope string, value string) ([]*core.Record, error) {
+	records := []*core.Record{}
+	query := app.RecordQuery(collection).AndWhere(dbx.HashExp{scope: value})
+	if scope == "user" {
+		query.AndWhere(dbx.HashExp{"workspace": ""})
+	}
+	err := query.
+		OrderBy("[[order]] ASC", "[[created]] ASC", "[[id]] ASC").
+		All(&records)
+	return records, err
+}
+
+// canWrite reports whether the user may change the character plan: their own
+// plan, or a plan of a workspace they can write to.
+func canWrite(app core.App, plan *core.Record, userId string) (bool, error) {
+	workspaceId := plan.GetString("workspace")
+	if workspaceId == "" {
+		return plan.GetString("user") == userId, nil
+	}
+	member, err := workspaces.FindMember(app, workspaceId, userId)
+	if errors.Is(err, workspaces.ErrWorkspaceNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return member.CanWrite(), nil
+}
+
+// renumber saves the orders 1..n on the records, returns the number of
+// records it changed.
+func renumber(app core.App, records []*core.Record) (int, error) {
+	changed := 0
+	for i, record := range records {
+		if record.GetInt("order") == i+1 {
+			continue
+		}
+		record.Set("order", i+1)
+		if err := app.Save(record); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// Reorder moves the records of the collection to the given order and
+// renumbers their scope, the plans of the user or of a workspace, or the child
+// plans of a character plan, densely from 1 in one transaction. The ids may be
+// a part of the scope, they take the places the listed records held, so a
+// reorder of a filtered list keeps the other records where they are. The
+// workspace plans can be reordered by the members allowed to write them. With
+// a profile id the plans outside of a workspace have to be in that game
+// profile. Returns the scope in its new order.
+func Reorder(app core.App, userId string, profileId string, collection string, ids []string) ([]*core.Record, error) {
+	if _, ok := orderScopes[collection]; !ok {
+		return nil, ErrNotOrdered
+	}
+	if len(ids) == 0 {
+		return nil, ErrEmptyOrder
+	}
+	for i, id := range ids {
+		if slices.Contains(ids[:i], id) {
+			return nil, fmt.Errorf("%w %q", ErrDuplicateId, id)
+		}
+	}
+
+	var ordered []*core.Record
+	err := app.RunInTransaction(func(txApp core.App) error {
+		records, err := txApp.FindRecordsByIds(collection, ids)
+		if err != nil {
+			return err
+		}
+		if len(records) != len(ids) {
+			return ErrPlanNotFound
+		}
+		scope, scopeValue := scopeOf(collection, records[0])
+		for _, record := range records {
+			if recordScope, value := scopeOf(collection, record); recordScope != scope || value != scopeValue {
+				if scope == "characterPlan" {
+					return ErrMixedParents
+				}
+				return ErrPlanNotFound
+			}
+		}
+		owningPlans := records
+		if scope == "characterPlan" {
+			plan, err := txApp.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, scopeValue)
+			if err != nil {
+				return ErrPlanNotFound
+			}
+			owningPlans = []*core.Record{plan}
+		}
+		for _, plan := range owningPlans {
+			ok, err := canWrite(txApp, plan, userId)
+			if err != nil {
+				return err
+			}
+			if !ok || plan.GetString("workspace") == "" && profileId != "" && plan.GetString("profile") != profileId {
+				return ErrPlanNotFound
+			}
+		}
+
+		ordered, err = findScope(txApp, collection, scope, scopeValue)
+		if err != nil {
+			return err
+		}
+		slots := []int{}
+		for i, record := range ordered {
+			if slices.Contains(ids, record.Id) {
+				slots = append(slots, i)
+			}
+		}
+		byId := map[string]*core.Record{}
+		for _, record := range ordered {
+			byId[record.Id] = record
+		}
+		for i, slot := range slots {
+			ordered[slot] = byId[ids[i]]
+		}
+		_, err = renumber(txApp, ordered)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ordered, nil
+}
+
+// orderGroups lists the scopes of the ordered collections with their records
+// in their current order.
+func orderGroups(app core.App, visit func(collection string, scope string, scopeValue string, records []*core.Record) error) error {
+	for _, collection := range OrderedCollections() {
+		orderBy := []string{"[[" + orderScopes[collection] + "]] ASC", "[[order]] ASC", "[[created]] ASC", "[[id]] ASC"}
+		if orderScopes[collection] == "user" {
+			// the workspace plans group by workspace whatever their user
+			orderBy[0] = "(CASE WHEN [[workspace]] = '' THEN [[user]] ELSE '' END) ASC"
+			orderBy = slices.Insert(orderBy, 0, "[[workspace]] ASC")
+		}
+		records := []*core.Record{}
+		err := app.RecordQuery(collection).OrderBy(orderBy...).All(&records)
+		if err != nil {
+			return fmt.Errorf("%s: %w", collection, err)
+		}
+		for start := 0; start < len(records); {
+			scope, scopeValue := scopeOf(collection, records[start])
+			end := start + 1
+			for end < len(records) {
+				if nextScope, value := scopeOf(collection, records[end]); nextScope != scope || value != scopeValue {
+					break
+				}
+				end++
+			}
+			if err := visit(collection, scope, scopeValue, records[start:end]); err != nil {
+				return err
+			}
+			start = end
+		}
+	}
+	return nil
+}
+
+func isDense(records []*core.Record) bool {
+	for i, record := range records {
+		if record.GetInt("order") != i+1 {
+			return false
+		}
+	}
+	return true
+}
+
+// FindOrderProblems lists the scopes of the ordered plans collections whose
+// orders have duplicates or gaps.
+func FindOrderProblems(app core.App) ([]string, error) {
+	problems := []string{}
+	err := orderGroups(app, func(collection string, scope string, scopeValue string, records []*core.Record) error {
+		if !isDense(records) {
+			problems = append(problems, fmt.Sprintf("%s of %s %s have duplicate or gapped orders", collection, scope, scopeValue))
+		}
+		return nil
+	})
+	return problems, err
+}
+
+// RepairOrders renumbers the ordered plans collections densely from 1 within
+// their scopes, keeping the current order, in one transaction. Returns the
+// number of records it changed.
+func RepairOrders(app core.App) (int, error) {
+	changed := 0
+	err := app.RunInTransaction(func(txApp core.App) error {
+		return orderGroups(txApp, func(collection string, scope string, scopeValue string, records []*core.Record) error {
+			n, err := renumber(txApp, records)
+			changed += n
+			return err
+		})
+	})
+	return changed, err
+}
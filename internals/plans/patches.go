@@ -0,0 +1,96 @@
+package plans
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// CURRENT_PATCH_SETTING_KEY is the app setting pinning the current patch by
+// its version, like "5.1". The latest patch of the dictionary is the current
+// one without it.
+const CURRENT_PATCH_SETTING_KEY = "currentPatch"
+
+// Patches are the patch records in release order.
+type Patches []*core.Record
+
+func patchVersion(record *core.Record) string {
+	return fmt.Sprintf("%d.%d", record.GetInt("major"), record.GetInt("patch"))
+}
+
+// LoadPatches loads every patch in release order.
+func LoadPatches(app core.App) (Patches, error) {
+	records := []*core.Record{}
+	err := app.RecordQuery(models.PATCH_COLLECTION_NAME).
+		OrderBy("[[major]] ASC", "[[patch]] ASC").
+		All(&records)
+	return records, err
+}
+
+// index returns the release position of the patch, -1 if unknown.
+func (p Patches) index(id string) int {
+	if id == "" {
+		return -1
+	}
+	return slices.IndexFunc(p, func(record *core.Record) bool { return record.Id == id })
+}
+
+// compare orders the patch ids by release, the unknown ones last.
+func (p Patches) compare(a string, b string) int {
+	i, j := p.index(a), p.index(b)
+	switch {
+	case i == j:
+		return 0
+	case i == -1:
+		return 1
+	case j == -1:
+		return -1
+	}
+	return i - j
+}
+
+// Current returns the current patch, see CURRENT_PATCH_SETTING_KEY, and the
+// one after it. Both are nil without patches, the next one is nil for the
+// latest patch.
+func (p Patches) Current(app core.App) (current *core.Record, next *core.Record, err error) {
+	if len(p) == 0 {
+		return nil, nil, nil
+	}
+	i := len(p) - 1
+	setting, err := models.FindAppSettingsByKey(app, CURRENT_PATCH_SETTING_KEY)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+	if err == nil && setting.Value() != "" {
+		i = slices.IndexFunc(p, func(record *core.Record) bool { return patchVersion(record) == setting.Value() })
+		if i == -1 {
+			return nil, nil, fmt.Errorf("%s: no patch %q", CURRENT_PATCH_SETTING_KEY, setting.Value())
+		}
+	}
+	if i+1 < len(p) {
+		next = p[i+1]
+	}
+	return p[i], next, nil
+}
+
+// Due returns the incomplete plans targeting the patch or an earlier one,
+// the earliest target patches first, then by priority and order.
+func (p Patches) Due(plans []Plan, patchId string) []Plan {
+	cutoff := p.index(patchId)
+	due := []Plan{}
+	if cutoff == -1 {
+		return due
+	}
+	for _, plan := range plans {
+		if i := p.index(plan.TargetPatch); !plan.Complete && i != -1 && i <= cutoff {
+			due = append(due, plan)
+		}
+	}
+	SortPlans(due, []SortField{{Field: "targetPatch"}, {Field: "priority", Desc: true}, {Field: "order"}}, p)
+	return due
+}
@@ -0,0 +1,333 @@
+// Package plans assembles the character plans of a user with their child
+// plans, and filters them the way the plans page of the ui does.
+package plans
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// Plan is a character plan with its child plans, the shape of the plans view.
+type Plan struct {
+	Id                   string             `json:"id"`
+	User                 string             `json:"user"`
+	Profile              string             `json:"profile"`
+	Workspace            string             `json:"workspace"`
+	Character            string             `json:"character"`
+	CharacterRole        string             `json:"characterRole"`
+	Complete             bool               `json:"complete"`
+	Order                int                `json:"order"`
+	Priority             int                `json:"priority"`
+	TargetPatch          string             `json:"targetPatch"`
+	Completion           int                `json:"completion"`
+	CompleteSuggested    bool               `json:"completeSuggested"`
+	ConstellationCurrent int                `json:"constellationCurrent"`
+	ConstellationTarget  int                `json:"constellationTarget"`
+	LevelCurrent         int                `json:"levelCurrent"`
+	LevelTarget          int                `json:"levelTarget"`
+	TalentAtkCurrent     int                `json:"talentAtkCurrent"`
+	TalentAtkTarget      int                `json:"talentAtkTarget"`
+	TalentSkillCurrent   int                `json:"talentSkillCurrent"`
+	TalentSkillTarget    int                `json:"talentSkillTarget"`
+	TalentBurstCurrent   int                `json:"talentBurstCurrent"`
+	TalentBurstTarget    int                `json:"talentBurstTarget"`
+	Substats             []string           `json:"substats"`
+	Note                 string             `json:"note"`
+	Created              types.DateTime     `json:"created"`
+	Updated              types.DateTime     `json:"updated"`
+	WeaponPlans          []WeaponPlan       `json:"weaponPlans"`
+	ArtifactTypePlans    []ArtifactTypePlan `json:"artifactTypePlans"`
+	ArtifactSetsPlans    []ArtifactSetsPlan `json:"artifactSetsPlans"`
+	TeamPlans            []TeamPlan         `json:"teamPlans"`
+	Expand               map[string]any     `json:"expand,omitempty"`
+}
+
+type WeaponPlan struct {
+	Id                string         `json:"id"`
+	CharacterPlan     string         `json:"characterPlan"`
+	Weapon            string         `json:"weapon"`
+	LevelCurrent      int            `json:"levelCurrent"`
+	LevelTarget       int            `json:"levelTarget"`
+	RefinementCurrent int            `json:"refinementCurrent"`
+	RefinementTarget  int            `json:"refinementTarget"`
+	Tag               string         `json:"tag"`
+	Order             int            `json:"order"`
+	Created           types.DateTime `json:"created"`
+	Updated           types.DateTime `json:"updated"`
+	Expand            map[string]any `json:"expand,omitempty"`
+}
+
+type ArtifactTypePlan struct {
+	Id            string         `json:"id"`
+	CharacterPlan string         `json:"characterPlan"`
+	ArtifactType  string         `json:"artifactType"`
+	Special       string         `json:"special"`
+	Created       types.DateTime `json:"created"`
+	Updated       types.DateTime `json:"updated"`
+	Expand        map[string]any `json:"expand,omitempty"`
+}
+
+type ArtifactSetsPlan struct {
+	Id            string         `json:"id"`
+	CharacterPlan string         `json:"characterPlan"`
+	ArtifactSets  idList         `json:"artifactSets"`
+	Order         int            `json:"order"`
+	Created       types.DateTime `json:"created"`
+	Updated       types.DateTime `json:"updated"`
+	Expand        map[string]any `json:"expand,omitempty"`
+}
+
+type TeamPlan struct {
+	Id            string         `json:"id"`
+	CharacterPlan string         `json:"characterPlan"`
+	Characters    idList         `json:"characters"`
+	Created       types.DateTime `json:"created"`
+	Updated       types.DateTime `json:"updated"`
+	Expand        map[string]any `json:"expand,omitempty"`
+}
+
+// idList is a multi relation, the plans view nests it as a json encoded
+// string.
+type idList []string
+
+func (l *idList) UnmarshalJSON(raw []byte) error {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		if encoded == "" {
+			*l = idList{}
+			return nil
+		}
+		raw = []byte(encoded)
+	}
+	ids := []string{}
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return err
+	}
+	*l = ids
+	return nil
+}
+
+func (l idList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(l))
+}
+
+func unmarshalChildren[T any](record *core.Record, field string) ([]T, error) {
+	children := []T{}
+	raw, ok := record.Get(field).(types.JSONRaw)
+	if !ok || len(raw) == 0 || string(raw) == "null" {
+		return children, nil
+	}
+	if err := json.Unmarshal(raw, &children); err != nil {
+		return nil, fmt.Errorf("plan %s %s: %w", record.Id, field, err)
+	}
+	return children, nil
+}
+
+// planFromViewRecord reads a record of the plans view.
+func planFromViewRecord(record *core.Record) (Plan, error) {
+	plan := planFromRecord(record)
+	var err error
+	if plan.WeaponPlans, err = unmarshalChildren[WeaponPlan](record, "weaponPlans"); err != nil {
+		return plan, err
+	}
+	if plan.ArtifactTypePlans, err = unmarshalChildren[ArtifactTypePlan](record, "artifactTypePlans"); err != nil {
+		return plan, err
+	}
+	if plan.ArtifactSetsPlans, err = unmarshalChildren[ArtifactSetsPlan](record, "artifactSetsPlans"); err != nil {
+		return plan, err
+	}
+	if plan.TeamPlans, err = unmarshalChildren[TeamPlan](record, "teamPlans"); err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
+
+// LoadFromView reads the plans of the user from the plans view. The view is
+// kept for the older clients listing it, Load assembles the same plans
+// without it.
+func LoadFromView(app core.App, userId string) ([]Plan, error) {
+	records, err := app.FindRecordsByFilter(models.PLANS_VIEW_COLLECTION_NAME, "user = {:user}", "order", 0, 0, dbx.Params{"user": userId})
+	if err != nil {
+		return nil, err
+	}
+	plans := make([]Plan, len(records))
+	for i, record := range records {
+		if plans[i], err = planFromViewRecord(record); err != nil {
+			return nil, err
+		}
+	}
+	return plans, nil
+}
+
+// findChildren loads the records of a child plans collection of the plans,
+// grouped by plan, through the characterPlan index.
+func findChildren(app core.App, collection string, planIds []any, orderBy string) (map[string][]*core.Record, error) {
+	children := map[string][]*core.Record{}
+	if len(planIds) == 0 {
+		return children, nil
+	}
+	records := []*core.Record{}
+	err := app.RecordQuery(collection).
+		AndWhere(dbx.In("characterPlan", planIds...)).
+		OrderBy(orderBy).
+		All(&records)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", collection, err)
+	}
+	for _, record := range records {
+		planId := record.GetString("characterPlan")
+		children[planId] = append(children[planId], record)
+	}
+	return children, nil
+}
+
+func assembleChildren[T any](records []*core.Record, read func(*core.Record) T) []T {
+	children := make([]T, len(records))
+	for i, record := range records {
+		children[i] = read(record)
+	}
+	return children
+}
+
+func planFromRecord(record *core.Record) Plan {
+	return Plan{
+		Id:                   record.Id,
+		User:                 record.GetString("user"),
+		Profile:              record.GetString("profile"),
+		Workspace:            record.GetString("workspace"),
+		Character:            record.GetString("character"),
+		CharacterRole:        record.GetString("characterRole"),
+		Complete:             record.GetBool("complete"),
+		Order:                record.GetInt("order"),
+		Priority:             record.GetInt("priority"),
+		TargetPatch:          record.GetString("targetPatch"),
+		Completion:           record.GetInt("completion"),
+		CompleteSuggested:    record.GetBool("completeSuggested"),
+		ConstellationCurrent: record.GetInt("constellationCurrent"),
+		ConstellationTarget:  record.GetInt("constellationTarget"),
+		LevelCurrent:         record.GetInt("levelCurrent"),
+		LevelTarget:          record.GetInt("levelTarget"),
+		TalentAtkCurrent:     record.GetInt("talentAtkCurrent"),
+		TalentAtkTarget:      record.GetInt("talentAtkTarget"),
+		TalentSkillCurrent:   record.GetInt("talentSkillCurrent"),
+		TalentSkillTarget:    record.GetInt("talentSkillTarget"),
+		TalentBurstCurrent:   record.GetInt("talentBurstCurrent"),
+		TalentBurstTarget:    record.GetInt("talentBurstTarget"),
+		Substats:             record.GetStringSlice("substats"),
+		Note:                 record.GetString("note"),
+		Created:              record.GetDateTime("created"),
+		Updated:              record.GetDateTime("updated"),
+	}
+}
+
+func weaponPlanFromRecord(record *core.Record) WeaponPlan {
+	return WeaponPlan{
+		Id:                record.Id,
+		CharacterPlan:     record.GetString("characterPlan"),
+		Weapon:            record.GetString("weapon"),
+		LevelCurrent:      record.GetInt("levelCurrent"),
+		LevelTarget:       record.GetInt("levelTarget"),
+		RefinementCurrent: record.GetInt("refinementCurrent"),
+		RefinementTarget:  record.GetInt("refinementTarget"),
+		Tag:               record.GetString("tag"),
+		Order:             record.GetInt("order"),
+		Created:           record.GetDateTime("created"),
+		Updated:           record.GetDateTime("updated"),
+	}
+}
+
+func artifactTypePlanFromRecord(record *core.Record) ArtifactTypePlan {
+	return ArtifactTypePlan{
+		Id:            record.Id,
+		CharacterPlan: record.GetString("characterPlan"),
+		ArtifactType:  record.GetString("artifactType"),
+		Special:       record.GetString("special"),
+		Created:       record.GetDateTime("created"),
+		Updated:       record.GetDateTime("updated"),
+	}
+}
+
+func artifactSetsPlanFromRecord(record *core.Record) ArtifactSetsPlan {
+	return ArtifactSetsPlan{
+		Id:            record.Id,
+		CharacterPlan: record.GetString("characterPlan"),
+		ArtifactSets:  record.GetStringSlice("artifactSets"),
+		Order:         record.GetInt("order"),
+		Created:       record.GetDateTime("created"),
+		Updated:       record.GetDateTime("updated"),
+	}
+}
+
+func teamPlanFromRecord(record *core.Record) TeamPlan {
+	return TeamPlan{
+		Id:            record.Id,
+		CharacterPlan: record.GetString("characterPlan"),
+		Characters:    record.GetStringSlice("characters"),
+		Created:       record.GetDateTime("created"),
+		Updated:       record.GetDateTime("updated"),
+	}
+}
+
+// Load returns the plans of the user in their order, assembled from a query
+// per plans collection instead of the correlated subqueries of the view.
+func Load(app core.App, userId string) ([]Plan, error) {
+	return loadWhere(app, dbx.HashExp{"user": userId})
+}
+
+// LoadWorkspace returns the plans put in the workspace, of all its members,
+// in their order.
+func LoadWorkspace(app core.App, workspaceId string) ([]Plan, error) {
+	return loadWhere(app, dbx.HashExp{"workspace": workspaceId})
+}
+
+func loadWhere(app core.App, where dbx.Expression) ([]Plan, error) {
+	records := []*core.Record{}
+	err := app.RecordQuery(models.CHARACTER_PLANS_COLLECTION_NAME).
+		AndWhere(where).
+		OrderBy("[[order]] ASC", "[[created]] ASC").
+		All(&records)
+	if err != nil {
+		return nil, err
+	}
+	planIds := make([]any, len(records))
+	for i, record := range records {
+		planIds[i] = record.Id
+	}
+
+	weaponPlans, err := findChildren(app, models.WEAPON_PLANS_COLLECTION_NAME, planIds, "[[order]] ASC")
+	if err != nil {
+		return nil, err
+	}
+	artifactTypePlans, err := findChildren(app, models.ARTIFACT_TYPE_PLANS_COLLECTION_NAME, planIds, "[[created]] ASC")
+	if err != nil {
+		return nil, err
+	}
+	artifactSetsPlans, err := findChildren(app, models.ARTIFACT_SETS_PLANS_COLLECTION_NAME, planIds, "[[order]] ASC")
+	if err != nil {
+		return nil, err
+	}
+	teamPlans, err := findChildren(app, models.TEAM_PLANS_COLLECTION_NAME, planIds, "[[created]] ASC")
+	if err != nil {
+		return nil, err
+	}
+
+	plans := make([]Plan, len(records))
+	for i, record := range records {
+		plan := planFromRecord(record)
+		plan.WeaponPlans = assembleChildren(weaponPlans[record.Id], weaponPlanFromRecord)
+		plan.ArtifactTypePlans = assembleChildren(artifactTypePlans[record.Id], artifactTypePlanFromRecord)
+		plan.ArtifactSetsPlans = assembleChildren(artifactSetsPlans[record.Id], artifactSetsPlanFromRecord)
+		plan.TeamPlans = assembleChildren(teamPlans[record.Id], teamPlanFromRecord)
+		plans[i] = plan
+	}
+	return plans, nil
+}
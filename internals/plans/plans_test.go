@@ -0,0 +1,924 @@
+package plans_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
+	"github.com/qxuken/gbp/internals/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+func TestLoad(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	other, _ := testutil.CreateUser(t, app, "other@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+
+	userPlans, err := plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userPlans) != 2 || userPlans[0].Id != "characterplan00" || userPlans[1].Id != "characterplan01" {
+		t.Fatalf("expected the two plans in order, got %+v", userPlans)
+	}
+	plan := userPlans[0]
+	if plan.LevelCurrent != 80 || plan.CharacterRole != "charrolemaindps" || !slices.Equal(plan.Substats, []string{"spcritrate00000"}) {
+		t.Errorf("plan fields: unexpected %+v", plan)
+	}
+	if len(plan.WeaponPlans) != 1 || plan.WeaponPlans[0].Weapon != "weaponaquila000" || plan.WeaponPlans[0].Tag != "current" {
+		t.Errorf("weapon plans: unexpected %+v", plan.WeaponPlans)
+	}
+	if len(plan.ArtifactSetsPlans) != 1 || !slices.Equal(plan.ArtifactSetsPlans[0].ArtifactSets, []string{"artsetgladiator"}) {
+		t.Errorf("artifact sets plans: unexpected %+v", plan.ArtifactSetsPlans)
+	}
+	if len(plan.ArtifactTypePlans) != 1 || plan.ArtifactTypePlans[0].Special != "spcritrate00000" {
+		t.Errorf("artifact type plans: unexpected %+v", plan.ArtifactTypePlans)
+	}
+	if len(plan.TeamPlans) != 1 || !slices.Equal(plan.TeamPlans[0].Characters, []string{"characterdiluc0"}) {
+		t.Errorf("team plans: unexpected %+v", plan.TeamPlans)
+	}
+	if len(userPlans[1].WeaponPlans) != 0 || userPlans[1].WeaponPlans == nil {
+		t.Errorf("childless plan: expected empty child lists, got %+v", userPlans[1].WeaponPlans)
+	}
+
+	otherPlans, err := plans.Load(app, other.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(otherPlans) != 0 {
+		t.Errorf("other user: expected no plans, got %d", len(otherPlans))
+	}
+}
+
+func TestLoadMatchesView(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+
+	assembled, err := plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromView, err := plans.LoadFromView(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assembledJson, _ := json.Marshal(assembled)
+	fromViewJson, _ := json.Marshal(fromView)
+	if string(assembledJson) != string(fromViewJson) {
+		t.Errorf("expected the view plans\n%s\ngot\n%s", fromViewJson, assembledJson)
+	}
+}
+
+func TestCache(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	other, _ := testutil.CreateUser(t, app, "other@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	cache := plans.NewCache()
+	cache.Bind(app)
+
+	cached, err := cache.Get(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the copies are the caller's to modify
+	cached[0].WeaponPlans[0].Weapon = "changed"
+	cached[0].ArtifactSetsPlans[0].ArtifactSets[0] = "changed"
+	if _, err := cache.Get(app, other.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	// changes made behind the cache aren't visible until an invalidation
+	if _, err := app.DB().NewQuery("UPDATE weaponPlans SET levelCurrent = 75").Execute(); err != nil {
+		t.Fatal(err)
+	}
+	cached, err = cache.Get(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached[0].WeaponPlans[0].Weapon != "weaponaquila000" || cached[0].ArtifactSetsPlans[0].ArtifactSets[0] != "artsetgladiator" {
+		t.Errorf("expected an unchanged copy, got %+v", cached[0])
+	}
+	if cached[0].WeaponPlans[0].LevelCurrent != 70 {
+		t.Errorf("expected the cached weapon level, got %d", cached[0].WeaponPlans[0].LevelCurrent)
+	}
+
+	// a child plan save drops the entry of the plan owner
+	weaponPlan, err := app.FindRecordById(models.WEAPON_PLANS_COLLECTION_NAME, "weaponplan00000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weaponPlan.Set("levelCurrent", 80)
+	if err := app.Save(weaponPlan); err != nil {
+		t.Fatal(err)
+	}
+	cached, err = cache.Get(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached[0].WeaponPlans[0].LevelCurrent != 80 {
+		t.Errorf("expected the saved weapon level, got %d", cached[0].WeaponPlans[0].LevelCurrent)
+	}
+
+	// so does a plan delete, its children going with it
+	plan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Delete(plan); err != nil {
+		t.Fatal(err)
+	}
+	cached, err = cache.Get(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 1 || cached[0].Id != "characterplan01" {
+		t.Errorf("expected the remaining plan, got %+v", cached)
+	}
+
+	// and a plan create for a user without cached plans yet
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{
+		"user": other.Id, "character": "characterdiluc0", "order": 1,
+	})
+	cached, err = cache.Get(app, other.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 1 || cached[0].Id != "characterplan02" {
+		t.Errorf("other user: expected the created plan, got %+v", cached)
+	}
+
+	// the workspace entries follow the plans moved in and out of it
+	testutil.CreateRecord(t, app, models.WORKSPACES_COLLECTION_NAME, "workspace000000", map[string]any{
+		"name": "Abyss",
+	})
+	cached, err = cache.GetWorkspace(app, "workspace000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 0 {
+		t.Errorf("expected an empty workspace, got %+v", cached)
+	}
+	plan, err = app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Set("workspace", "workspace000000")
+	if err := app.Save(plan); err != nil {
+		t.Fatal(err)
+	}
+	cached, err = cache.GetWorkspace(app, "workspace000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 1 || cached[0].Id != "characterplan02" || cached[0].Workspace != "workspace000000" {
+		t.Errorf("expected the moved plan, got %+v", cached)
+	}
+	plan.Set("workspace", "")
+	if err := app.Save(plan); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err = cache.GetWorkspace(app, "workspace000000"); err != nil || len(cached) != 0 {
+		t.Errorf("expected the plan moved out, got %+v %v", cached, err)
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	values, _ := url.ParseQuery("name=dil&complete=1&elements=a,b&elements=c&specials[flower]=x,y&artifactSets=s")
+	filters, err := plans.ParseFilters(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filters.Name != "dil" || !filters.Complete || !slices.Equal(filters.Elements, []string{"a", "b", "c"}) {
+		t.Errorf("unexpected %+v", filters)
+	}
+	if !slices.Equal(filters.SpecialsByArtifactType["flower"], []string{"x", "y"}) || !slices.Equal(filters.ArtifactSets, []string{"s"}) {
+		t.Errorf("unexpected %+v", filters)
+	}
+
+	for _, query := range []string{"complete=maybe", "specials[]=a", "specials[flower=a"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := plans.ParseFilters(values); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	userPlans, err := plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dictionary, err := plans.LoadDictionary(app, userPlans, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matching := func(query string) []string {
+		t.Helper()
+		values, _ := url.ParseQuery(query)
+		filters, err := plans.ParseFilters(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, plan := range userPlans {
+			if filters.Match(plan, dictionary.Characters()[plan.Character]) {
+				ids = append(ids, plan.Id)
+			}
+		}
+		return ids
+	}
+
+	cases := map[string][]string{
+		"":                                 {"characterplan00"},
+		"complete=true":                    {"characterplan00", "characterplan01"},
+		"name=dlc":                         {"characterplan00"},
+		"name=xiao":                        {},
+		"elements=elementpyro0000":         {"characterplan00"},
+		"elements=elementhydro000":         {},
+		"weaponTypes=weapontypesword":      {"characterplan00"},
+		"characters=characterdiluc0":       {"characterplan00"},
+		"artifactSets=artsetgladiator":     {"characterplan00"},
+		"complete=true&artifactSets=other": {},
+		"specials[arttypeflower00]=spcritrate00000": {"characterplan00"},
+		"specials[arttypeflower00]=spatkpercent000": {},
+		"complete=true&characters=characterdiluc0":  {"characterplan00", "characterplan01"},
+	}
+	for query, expected := range cases {
+		if got := matching(query); !slices.Equal(got, expected) {
+			t.Errorf("%q: expected %v, got %v", query, expected, got)
+		}
+	}
+}
+
+func TestPrioritiesAndPatches(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	testutil.CreateRecord(t, app, models.PATCH_COLLECTION_NAME, "patch5dot200000", map[string]any{"major": 5, "patch": 2})
+	testutil.CreateRecord(t, app, models.PATCH_COLLECTION_NAME, "patch5dot000000", map[string]any{"major": 5, "patch": 0})
+	for id, data := range map[string]map[string]any{
+		"characterplan00": {"priority": 2, "targetPatch": "patch5dot100000"},
+		"characterplan01": {"targetPatch": "patch5dot000000"},
+	} {
+		plan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan.Load(data)
+		if err := app.Save(plan); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{
+		"user": user.Id, "character": "characterdiluc0", "order": 3, "priority": 3, "targetPatch": "patch5dot200000",
+	})
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan03", map[string]any{
+		"user": user.Id, "character": "characterdiluc0", "order": 4, "priority": 1, "targetPatch": "patch5dot100000",
+	})
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan04", map[string]any{
+		"user": user.Id, "character": "characterdiluc0", "order": 5, "priority": 3,
+	})
+	userPlans, err := plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := func(plans []plans.Plan) []string {
+		ids := []string{}
+		for _, plan := range plans {
+			ids = append(ids, plan.Id)
+		}
+		return ids
+	}
+
+	dictionary, err := plans.LoadDictionary(app, userPlans, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for query, expected := range map[string][]string{
+		"priority=2":                    {"characterplan00", "characterplan02", "characterplan04"},
+		"targetPatches=patch5dot100000": {"characterplan00", "characterplan03"},
+		"priority=2&targetPatches=patch5dot100000,patch5dot200000": {"characterplan00", "characterplan02"},
+	} {
+		values, _ := url.ParseQuery(query)
+		filters, err := plans.ParseFilters(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		matched := []plans.Plan{}
+		for _, plan := range userPlans {
+			if filters.Match(plan, dictionary.Characters()[plan.Character]) {
+				matched = append(matched, plan)
+			}
+		}
+		if got := ids(matched); !slices.Equal(got, expected) {
+			t.Errorf("%q: expected %v, got %v", query, expected, got)
+		}
+	}
+	if _, err := plans.ParseFilters(url.Values{"priority": {"4"}}); err == nil {
+		t.Error("expected a priority out of range rejected")
+	}
+
+	patches, err := plans.LoadPatches(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for raw, expected := range map[string][]string{
+		"-priority":              {"characterplan02", "characterplan04", "characterplan00", "characterplan03", "characterplan01"},
+		"targetPatch,-priority":  {"characterplan01", "characterplan00", "characterplan03", "characterplan02", "characterplan04"},
+		"-targetPatch,-priority": {"characterplan02", "characterplan00", "characterplan03", "characterplan01", "characterplan04"},
+	} {
+		sort, err := plans.ParseSort(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorted := slices.Clone(userPlans)
+		plans.SortPlans(sorted, sort, patches)
+		if got := ids(sorted); !slices.Equal(got, expected) {
+			t.Errorf("sort %q: expected %v, got %v", raw, expected, got)
+		}
+	}
+	if _, err := plans.ParseSort("name"); err == nil {
+		t.Error("expected an unknown sort field rejected")
+	}
+
+	current, next, err := patches.Current(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Id != "patch5dot200000" || next != nil {
+		t.Errorf("expected the latest patch current, got %v %v", current, next)
+	}
+	if _, err := models.CreateAppSettings(app, plans.CURRENT_PATCH_SETTING_KEY, "5.1"); err != nil {
+		t.Fatal(err)
+	}
+	current, next, err = patches.Current(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Id != "patch5dot100000" || next == nil || next.Id != "patch5dot200000" {
+		t.Errorf("expected the pinned patch current, got %v %v", current, next)
+	}
+	if got := ids(patches.Due(userPlans, current.Id)); !slices.Equal(got, []string{"characterplan00", "characterplan03"}) {
+		t.Errorf("due by the current patch: got %v", got)
+	}
+	if got := ids(patches.Due(userPlans, next.Id)); !slices.Equal(got, []string{"characterplan00", "characterplan03", "characterplan02"}) {
+		t.Errorf("due by the next patch: got %v", got)
+	}
+}
+
+func TestFacetsAndExpand(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	testutil.CreateRecord(t, app, models.TRANSLATIONS_COLLECTION_NAME, "trdilucja000000", map[string]any{
+		"collection": models.CHARACTERS_COLLECTION_NAME, "record": "characterdiluc0", "locale": "ja", "name": "ディルック",
+	})
+	userPlans, err := plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dictionary, err := plans.LoadDictionary(app, userPlans, []string{"ja"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facets := plans.NewFacets(userPlans, dictionary.Characters(), plans.Filters{ArtifactSets: []string{"selected0000000"}})
+	if !slices.Equal(facets.Elements, []string{"elementpyro0000"}) || !slices.Equal(facets.Characters, []string{"characterdiluc0"}) {
+		t.Errorf("facets: unexpected %+v", facets)
+	}
+	// the selected values stay available even without a plan using them
+	if !slices.Equal(facets.ArtifactSets, []string{"artsetgladiator", "selected0000000"}) {
+		t.Errorf("facets artifact sets: unexpected %v", facets.ArtifactSets)
+	}
+	// no plan passes the artifact set filter, so none offers its specials
+	if len(facets.SpecialsByArtifactType) != 0 {
+		t.Errorf("facets specials: unexpected %v", facets.SpecialsByArtifactType)
+	}
+	facets = plans.NewFacets(userPlans, dictionary.Characters(), plans.Filters{})
+	if !slices.Equal(facets.SpecialsByArtifactType["arttypeflower00"], []string{"spcritrate00000"}) {
+		t.Errorf("facets specials: unexpected %v", facets.SpecialsByArtifactType)
+	}
+
+	dictionary.Expand(userPlans)
+	plan := userPlans[0]
+	character, ok := plan.Expand["character"].(interface{ GetString(string) string })
+	if !ok || character.GetString("name") != "ディルック" {
+		t.Errorf("expand character: unexpected %v", plan.Expand["character"])
+	}
+	if _, ok := plan.WeaponPlans[0].Expand["weapon"]; !ok {
+		t.Error("expand weapon: missing")
+	}
+	if _, ok := plan.ArtifactTypePlans[0].Expand["special"]; !ok {
+		t.Error("expand artifact type special: missing")
+	}
+}
+
+// seedBenchmarkPlans saves the plans of a large roster for a user and as
+// many for another one, each with a few child plans of every kind.
+func seedBenchmarkPlans(b *testing.B, count int) (core.App, string) {
+	app := testutil.NewTestApp(b)
+	testutil.SeedDictionaries(b, app)
+	user, _ := testutil.CreateUser(b, app, "user@test.com")
+	other, _ := testutil.CreateUser(b, app, "other@test.com")
+	for u, userId := range []string{user.Id, other.Id} {
+		for i := range count {
+			planId := fmt.Sprintf("benchplan%d%05d", u, i)
+			testutil.CreateRecord(b, app, models.CHARACTER_PLANS_COLLECTION_NAME, planId, map[string]any{
+				"user": userId, "character": "characterdiluc0", "order": i,
+				"substats": []string{"spcritrate00000", "spatkpercent000"},
+			})
+			for j := range 3 {
+				testutil.CreateRecord(b, app, models.WEAPON_PLANS_COLLECTION_NAME, fmt.Sprintf("benchweap%d%04d%d", u, i, j), map[string]any{
+					"characterPlan": planId, "weapon": "weaponaquila000", "order": j,
+				})
+				testutil.CreateRecord(b, app, models.ARTIFACT_SETS_PLANS_COLLECTION_NAME, fmt.Sprintf("benchaset%d%04d%d", u, i, j), map[string]any{
+					"characterPlan": planId, "artifactSets": []string{"artsetgladiator"}, "order": j,
+				})
+			}
+			testutil.CreateRecord(b, app, models.ARTIFACT_TYPE_PLANS_COLLECTION_NAME, fmt.Sprintf("benchatyp%d%05d", u, i), map[string]any{
+				"characterPlan": planId, "artifactType": "arttypeflower00", "special": "spcritrate00000",
+			})
+			testutil.CreateRecord(b, app, models.TEAM_PLANS_COLLECTION_NAME, fmt.Sprintf("benchteam%d%05d", u, i), map[string]any{
+				"characterPlan": planId, "characters": []string{"characterdiluc0"},
+			})
+		}
+	}
+	return app, user.Id
+}
+
+func BenchmarkLoadFromView(b *testing.B) {
+	app, userId := seedBenchmarkPlans(b, 100)
+	for b.Loop() {
+		if _, err := plans.LoadFromView(app, userId); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	app, userId := seedBenchmarkPlans(b, 100)
+	for b.Loop() {
+		if _, err := plans.Load(app, userId); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCacheGet(b *testing.B) {
+	app, userId := seedBenchmarkPlans(b, 100)
+	cache := plans.NewCache()
+	cache.Bind(app)
+	for b.Loop() {
+		if _, err := cache.Get(app, userId); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func orders(t *testing.T, app core.App, collection string, ids ...string) []int {
+	t.Helper()
+	result := make([]int, len(ids))
+	for i, id := range ids {
+		record, err := app.FindRecordById(collection, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result[i] = record.GetInt("order")
+	}
+	return result
+}
+
+func TestReorder(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	other, _ := testutil.CreateUser(t, app, "other@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{
+		"user": user.Id, "character": "characterdiluc0", "order": 2,
+	})
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "otherplan000000", map[string]any{
+		"user": other.Id, "character": "characterdiluc0", "order": 1,
+	})
+	for i, id := range []string{"weaponplan00001", "weaponplan00002"} {
+		testutil.CreateRecord(t, app, models.WEAPON_PLANS_COLLECTION_NAME, id, map[string]any{
+			"characterPlan": "characterplan00", "weapon": "weaponaquila000", "order": 5 + i,
+		})
+	}
+	testutil.CreateRecord(t, app, models.WEAPON_PLANS_COLLECTION_NAME, "weaponplan00003", map[string]any{
+		"characterPlan": "characterplan01", "weapon": "weaponaquila000", "order": 1,
+	})
+
+	// the duplicate order of 01 and 02 goes, the listed plans swap places
+	ordered, err := plans.Reorder(app, user.Id, "", models.CHARACTER_PLANS_COLLECTION_NAME, []string{"characterplan02", "characterplan00", "characterplan01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ordered) != 3 || ordered[0].Id != "characterplan02" {
+		t.Errorf("expected the new order, got %v", ordered)
+	}
+	if got := orders(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", "characterplan00", "characterplan01"); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected dense orders, got %v", got)
+	}
+
+	// a part of the scope takes the places the listed records held
+	if _, err := plans.Reorder(app, user.Id, "", models.WEAPON_PLANS_COLLECTION_NAME, []string{"weaponplan00002", "weaponplan00000"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := orders(t, app, models.WEAPON_PLANS_COLLECTION_NAME, "weaponplan00002", "weaponplan00001", "weaponplan00000"); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected the partial reorder, got %v", got)
+	}
+
+	failures := []struct {
+		name       string
+		userId     string
+		collection string
+		ids        []string
+		err        error
+	}{
+		{"unordered collection", user.Id, models.TEAM_PLANS_COLLECTION_NAME, []string{"teamplan0000000"}, plans.ErrNotOrdered},
+		{"no ids", user.Id, models.CHARACTER_PLANS_COLLECTION_NAME, nil, plans.ErrEmptyOrder},
+		{"duplicate", user.Id, models.CHARACTER_PLANS_COLLECTION_NAME, []string{"characterplan00", "characterplan00"}, plans.ErrDuplicateId},
+		{"missing", user.Id, models.CHARACTER_PLANS_COLLECTION_NAME, []string{"characterplan00", "missing00000000"}, plans.ErrPlanNotFound},
+		{"other user plan", user.Id, models.CHARACTER_PLANS_COLLECTION_NAME, []string{"characterplan00", "otherplan000000"}, plans.ErrPlanNotFound},
+		{"other user children", other.Id, models.WEAPON_PLANS_COLLECTION_NAME, []string{"weaponplan00000"}, plans.ErrPlanNotFound},
+		{"mixed parents", user.Id, models.WEAPON_PLANS_COLLECTION_NAME, []string{"weaponplan00000", "weaponplan00003"}, plans.ErrMixedParents},
+	}
+	for _, failure := range failures {
+		if _, err := plans.Reorder(app, failure.userId, "", failure.collection, failure.ids); !errors.Is(err, failure.err) {
+			t.Errorf("%s: expected %v, got %v", failure.name, failure.err, err)
+		}
+	}
+}
+
+func TestRepairOrders(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{
+		"user": user.Id, "character": "characterdiluc0", "order": 7,
+	})
+	testutil.CreateRecord(t, app, models.ARTIFACT_SETS_PLANS_COLLECTION_NAME, "artsetsplan0001", map[string]any{
+		"characterPlan": "characterplan00", "artifactSets": []string{"artsetgladiator"}, "order": 1,
+	})
+
+	problems, err := plans.FindOrderProblems(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 {
+		t.Errorf("expected the gapped plans and the duplicate artifact sets plans, got %v", problems)
+	}
+	changed, err := plans.RepairOrders(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("expected 2 renumbered records, got %d", changed)
+	}
+	if got := orders(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00", "characterplan01", "characterplan02"); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected dense plan orders, got %v", got)
+	}
+	if problems, err := plans.FindOrderProblems(app); err != nil || len(problems) != 0 {
+		t.Errorf("expected no problems left, got %v %v", problems, err)
+	}
+}
+
+// TestWorkspaceOrder covers the plans of several members put in a workspace,
+// ordered and cloned within the workspace by its writers.
+func TestWorkspaceOrder(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	owner, _ := testutil.CreateUser(t, app, "owner@test.com")
+	editor, _ := testutil.CreateUser(t, app, "editor@test.com")
+	viewer, _ := testutil.CreateUser(t, app, "viewer@test.com")
+	testutil.SeedPlans(t, app, owner.Id)
+	testutil.CreateRecord(t, app, models.WORKSPACES_COLLECTION_NAME, "workspace000000", map[string]any{"name": "Abyss"})
+	for id, member := range map[string]struct {
+		user string
+		role string
+	}{
+		"memberowner0000": {owner.Id, models.WORKSPACE_ROLE_OWNER},
+		"membereditor000": {editor.Id, models.WORKSPACE_ROLE_EDITOR},
+		"memberviewer000": {viewer.Id, models.WORKSPACE_ROLE_VIEWER},
+	} {
+		testutil.CreateRecord(t, app, models.WORKSPACE_MEMBERS_COLLECTION_NAME, id, map[string]any{
+			"workspace": "workspace000000", "user": member.user, "role": member.role,
+		})
+	}
+	plan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Set("workspace", "workspace000000")
+	if err := app.Save(plan); err != nil {
+		t.Fatal(err)
+	}
+	// the plans of two members colliding on their order within the workspace
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "editorplan00000", map[string]any{
+		"user": editor.Id, "character": "characterdiluc0", "order": 1, "workspace": "workspace000000",
+	})
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "editorplan00001", map[string]any{
+		"user": editor.Id, "character": "characterdiluc0", "order": 1,
+	})
+
+	if _, err := plans.Reorder(app, viewer.Id, "", models.CHARACTER_PLANS_COLLECTION_NAME, []string{"editorplan00000", "characterplan00"}); !errors.Is(err, plans.ErrPlanNotFound) {
+		t.Errorf("viewer: expected not found, got %v", err)
+	}
+	ordered, err := plans.Reorder(app, editor.Id, "", models.CHARACTER_PLANS_COLLECTION_NAME, []string{"editorplan00000", "characterplan00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ordered) != 2 {
+		t.Errorf("expected the workspace scope, got %v", ordered)
+	}
+	if got := orders(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "editorplan00000", "characterplan00", "editorplan00001"); !slices.Equal(got, []int{1, 2, 1}) {
+		t.Errorf("expected the workspace renumbered apart from the personal plans, got %v", got)
+	}
+	if _, err := plans.Reorder(app, editor.Id, "", models.CHARACTER_PLANS_COLLECTION_NAME, []string{"editorplan00000", "editorplan00001"}); !errors.Is(err, plans.ErrPlanNotFound) {
+		t.Errorf("mixed scopes: expected not found, got %v", err)
+	}
+	if _, err := plans.Reorder(app, editor.Id, "", models.WEAPON_PLANS_COLLECTION_NAME, []string{"weaponplan00000"}); err != nil {
+		t.Errorf("child plans: %v", err)
+	}
+
+	if _, err := plans.Clone(app, viewer.Id, "characterplan00", "", false); !errors.Is(err, plans.ErrPlanNotFound) {
+		t.Errorf("viewer clone: expected not found, got %v", err)
+	}
+	clone, err := plans.Clone(app, editor.Id, "characterplan00", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.GetString("user") != editor.Id || clone.GetString("workspace") != "workspace000000" {
+		t.Errorf("expected a workspace copy of the editor, got user %q workspace %q", clone.GetString("user"), clone.GetString("workspace"))
+	}
+	profile, err := models.FindDefaultGameProfile(app, editor.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.GetString("profile") != profile.Id {
+		t.Errorf("expected the profile of the editor, got %q", clone.GetString("profile"))
+	}
+	if got := orders(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "editorplan00000", "characterplan00", clone.Id, "editorplan00001"); !slices.Equal(got, []int{1, 2, 3, 1}) {
+		t.Errorf("expected the copy after the original within the workspace, got %v", got)
+	}
+	problems, err := plans.FindOrderProblems(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the owner plans have a gap where the plan put in the workspace was
+	if len(problems) != 1 || !strings.Contains(problems[0], "of user "+owner.Id) {
+		t.Errorf("expected a dense workspace, got %v", problems)
+	}
+}
+
+func TestClone(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	other, _ := testutil.CreateUser(t, app, "other@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+
+	clone, err := plans.Clone(app, user.Id, "characterplan00", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userPlans, err := plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userPlans) != 3 || userPlans[1].Id != clone.Id {
+		t.Fatalf("expected the copy after the original, got %+v", userPlans)
+	}
+	if got := []int{userPlans[0].Order, userPlans[1].Order, userPlans[2].Order}; !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected dense orders, got %v", got)
+	}
+	original, copied := userPlans[0], userPlans[1]
+	if copied.LevelCurrent != 80 || copied.CharacterRole != original.CharacterRole || !slices.Equal(copied.Substats, original.Substats) {
+		t.Errorf("expected the plan fields copied, got %+v", copied)
+	}
+	if len(copied.WeaponPlans) != 1 || copied.WeaponPlans[0].Id == original.WeaponPlans[0].Id || copied.WeaponPlans[0].LevelCurrent != 70 {
+		t.Errorf("expected a copied weapon plan, got %+v", copied.WeaponPlans)
+	}
+	if len(copied.ArtifactSetsPlans) != 1 || !slices.Equal(copied.ArtifactSetsPlans[0].ArtifactSets, []string{"artsetgladiator"}) ||
+		len(copied.ArtifactTypePlans) != 1 || len(copied.TeamPlans) != 1 {
+		t.Errorf("expected every child plan copied, got %+v", copied)
+	}
+
+	reset, err := plans.Clone(app, user.Id, "characterplan00", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userPlans, err = plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userPlans[1].Id != reset.Id || userPlans[2].Id != clone.Id {
+		t.Fatalf("expected the second copy right after the original, got %+v", userPlans)
+	}
+	if userPlans[1].LevelCurrent != 1 || userPlans[1].LevelTarget != 90 || userPlans[1].WeaponPlans[0].LevelCurrent != 0 {
+		t.Errorf("expected the current values reset, got %+v", userPlans[1])
+	}
+
+	if _, err := plans.Clone(app, other.Id, "characterplan00", "", false); !errors.Is(err, plans.ErrPlanNotFound) {
+		t.Errorf("other user: expected not found, got %v", err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	plans.BindProfiles(app)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	other, _ := testutil.CreateUser(t, app, "other@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	defaultProfile, err := models.FindDefaultGameProfile(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alt := testutil.CreateRecord(t, app, models.GAME_PROFILES_COLLECTION_NAME, "profilealt00000", map[string]any{
+		"user": user.Id, "name": "Alt", "serverRegion": "europe", "uid": "700000001",
+	})
+	otherProfile := testutil.CreateRecord(t, app, models.GAME_PROFILES_COLLECTION_NAME, "profileother000", map[string]any{
+		"user": other.Id, "name": "Main",
+	})
+
+	// a plan without a profile goes to the default one
+	plan := testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{
+		"user": user.Id, "character": "characterdiluc0", "order": 3,
+	})
+	if plan.GetString("profile") != defaultProfile.Id {
+		t.Errorf("expected the default profile %s, got %q", defaultProfile.Id, plan.GetString("profile"))
+	}
+	// created for a user without any profile yet
+	otherPlan := testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "otherplan000000", map[string]any{
+		"user": other.Id, "character": "characterdiluc0", "order": 1,
+	})
+	if otherPlan.GetString("profile") != otherProfile.Id {
+		t.Errorf("other user: expected the profile %s, got %q", otherProfile.Id, otherPlan.GetString("profile"))
+	}
+
+	// the profile of another user is rejected
+	plan.Set("profile", otherProfile.Id)
+	if err := app.Save(plan); err == nil {
+		t.Error("expected the profile of another user rejected")
+	}
+	plan.Set("profile", alt.Id)
+	if err := app.Save(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	userPlans, err := plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inAlt := plans.InProfile(userPlans, alt.Id); len(inAlt) != 1 || inAlt[0].Id != "characterplan02" {
+		t.Errorf("expected the alt plan, got %+v", inAlt)
+	}
+	if inMain := plans.InProfile(userPlans, defaultProfile.Id); len(inMain) != 2 {
+		t.Errorf("expected the two main plans, got %+v", inMain)
+	}
+
+	if _, err := plans.Reorder(app, user.Id, alt.Id, models.CHARACTER_PLANS_COLLECTION_NAME, []string{"characterplan01", "characterplan00"}); !errors.Is(err, plans.ErrPlanNotFound) {
+		t.Errorf("reorder out of the profile: expected not found, got %v", err)
+	}
+	if _, err := plans.Reorder(app, user.Id, defaultProfile.Id, models.CHARACTER_PLANS_COLLECTION_NAME, []string{"characterplan01", "characterplan00"}); err != nil {
+		t.Errorf("reorder in the profile: %v", err)
+	}
+
+	clone, err := plans.Clone(app, user.Id, "characterplan00", alt.Id, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.GetString("profile") != alt.Id {
+		t.Errorf("expected the copy in the alt profile, got %q", clone.GetString("profile"))
+	}
+	if _, err := plans.Clone(app, user.Id, "characterplan00", otherProfile.Id, false); !errors.Is(err, plans.ErrProfileNotFound) {
+		t.Errorf("clone to another user profile: expected not found, got %v", err)
+	}
+
+	// the plans go with their profile
+	if err := app.Delete(alt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02"); err == nil {
+		t.Error("expected the alt plans deleted with the profile")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	plans.BindCompletion(app)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	save := func(collection string, id string, data map[string]any) {
+		t.Helper()
+		record, err := app.FindRecordById(collection, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		record.Load(data)
+		if err := app.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(id string, completion int, suggested bool, complete bool) {
+		t.Helper()
+		plan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plan.GetInt("completion") != completion || plan.GetBool("completeSuggested") != suggested || plan.GetBool("complete") != complete {
+			t.Errorf("%s: expected %d%% suggested %v complete %v, got %d%% suggested %v complete %v", id, completion, suggested, complete,
+				plan.GetInt("completion"), plan.GetBool("completeSuggested"), plan.GetBool("complete"))
+		}
+	}
+
+	// level 80/90, the current weapon at level 70/90 and refinement 1/1
+	expect("characterplan00", 88, false, false)
+	save(models.WEAPON_PLANS_COLLECTION_NAME, "weaponplan00000", map[string]any{"levelCurrent": 90})
+	expect("characterplan00", 96, false, false)
+	save(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00", map[string]any{"levelCurrent": 90})
+	expect("characterplan00", 100, true, false)
+
+	// the weapon tagged target goes before the current one
+	target := testutil.CreateRecord(t, app, models.WEAPON_PLANS_COLLECTION_NAME, "weaponplan00001", map[string]any{
+		"characterPlan": "characterplan00", "weapon": "weaponaquila000", "order": 2, "tag": "target",
+		"levelCurrent": 1, "levelTarget": 90,
+	})
+	expect("characterplan00", 50, false, false)
+	if err := app.Delete(target); err != nil {
+		t.Fatal(err)
+	}
+	expect("characterplan00", 100, true, false)
+	save(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00", map[string]any{"complete": true})
+	expect("characterplan00", 100, false, true)
+
+	// a plan without targets has nothing to complete
+	expect("characterplan01", 0, false, true)
+
+	// with the user setting on, reaching the targets completes the plan
+	save(models.USERS_COLLECTION_NAME, user.Id, map[string]any{"autoCompletePlans": true})
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{
+		"user": user.Id, "character": "characterdiluc0", "order": 3, "levelCurrent": 80, "levelTarget": 90,
+	})
+	expect("characterplan02", 88, false, false)
+	save(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{"levelCurrent": 90})
+	expect("characterplan02", 100, false, true)
+	// unchecked by hand it stays so
+	save(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan02", map[string]any{"complete": false})
+	expect("characterplan02", 100, true, false)
+
+	// the migration fills the fields of the plans of before them
+	testutil.CreateRecord(t, app, models.WEAPON_PLANS_COLLECTION_NAME, "weaponplan00002", map[string]any{
+		"characterPlan": "characterplan02", "weapon": "weaponaquila000", "order": 1, "tag": "current",
+		"levelCurrent": 45, "levelTarget": 90, "refinementCurrent": 1, "refinementTarget": 5,
+	})
+	expect("characterplan02", 56, false, false)
+	i := slices.IndexFunc(core.AppMigrations.Items(), func(m *core.Migration) bool { return m.File == "1792400800_plan_completion.go" })
+	if i == -1 {
+		t.Fatal("expected the completion migration")
+	}
+	migration := core.AppMigrations.Items()[i]
+	if err := migration.Down(app); err != nil {
+		t.Fatal(err)
+	}
+	if err := migration.Up(app); err != nil {
+		t.Fatal(err)
+	}
+	expect("characterplan00", 100, false, true)
+	expect("characterplan01", 0, false, true)
+	expect("characterplan02", 56, false, false)
+	userPlans, err := plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userPlans[2].Completion != 56 || userPlans[2].CompleteSuggested {
+		t.Errorf("expected the completion loaded, got %+v", userPlans[2])
+	}
+}
@@ -0,0 +1,76 @@
+package plans
+
+import (
+	"errors"
+
+	validation "github.com/pocketbase/ozzo-validation/v4"
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+var ErrProfileNotFound = errors.New("game profile not found")
+
+// BindProfiles keeps the character plans in a game profile of their user:
+// the plans saved without one go to the default profile of the user, the
+// ones pointing to a profile of another user are rejected.
+func BindProfiles(app core.App) {
+	app.OnRecordCreate(models.CHARACTER_PLANS_COLLECTION_NAME).BindFunc(func(e *core.RecordEvent) error {
+		if e.Record.GetString("profile") == "" && e.Record.GetString("user") != "" {
+			profile, err := models.FindDefaultGameProfile(e.App, e.Record.GetString("user"))
+			if err != nil {
+				return err
+			}
+			e.Record.Set("profile", profile.Id)
+		}
+		if err := checkProfile(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdate(models.CHARACTER_PLANS_COLLECTION_NAME).BindFunc(func(e *core.RecordEvent) error {
+		if err := checkProfile(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+}
+
+func checkProfile(app core.App, plan *core.Record) error {
+	profileId := plan.GetString("profile")
+	unchanged := !plan.IsNew() &&
+		profileId == plan.Original().GetString("profile") &&
+		plan.GetString("user") == plan.Original().GetString("user")
+	if profileId == "" || unchanged {
+		return nil
+	}
+	if _, err := FindProfile(app, plan.GetString("user"), profileId); err != nil {
+		return validation.Errors{"profile": validation.NewError("validation_invalid_profile", "The profile has to be a game profile of the plan user.")}
+	}
+	return nil
+}
+
+// FindProfile returns the game profile of the user, ErrProfileNotFound if
+// the user has no such profile.
+func FindProfile(app core.App, userId string, profileId string) (*models.GameProfile, error) {
+	profile, err := models.FindGameProfileById(app, profileId)
+	if err != nil || profile.UserId() != userId {
+		return nil, ErrProfileNotFound
+	}
+	return profile, nil
+}
+
+// InProfile returns the plans of the game profile, all of them for an empty
+// profile id.
+func InProfile(plans []Plan, profileId string) []Plan {
+	if profileId == "" {
+		return plans
+	}
+	inProfile := []Plan{}
+	for _, plan := range plans {
+		if plan.Profile == profileId {
+			inProfile = append(inProfile, plan)
+		}
+	}
+	return inProfile
+}
@@ -0,0 +1,75 @@
+package plans
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortField is a plan field to sort by, see ParseSort.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+var sortFields = []string{"order", "priority", "targetPatch", "created", "updated"}
+
+// ParseSort reads the sort param the way the record endpoints do, comma
+// separated fields with a - prefix for the descending ones:
+//
+//	sort=-priority,targetPatch
+//
+// The plans without a target patch go last either way.
+func ParseSort(raw string) ([]SortField, error) {
+	fields := []SortField{}
+	for item := range strings.SplitSeq(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		field := SortField{}
+		field.Field, field.Desc = strings.CutPrefix(item, "-")
+		if !slices.Contains(sortFields, field.Field) {
+			return nil, fmt.Errorf("sort: unknown field %q, expected one of %s", field.Field, strings.Join(sortFields, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// SortPlans sorts the plans by the fields, the ties keep their order. The
+// patches order the target patches by release.
+func SortPlans(plans []Plan, fields []SortField, patches Patches) {
+	slices.SortStableFunc(plans, func(a Plan, b Plan) int {
+		for _, field := range fields {
+			var c int
+			switch field.Field {
+			case "order":
+				c = cmp.Compare(a.Order, b.Order)
+			case "priority":
+				c = cmp.Compare(a.Priority, b.Priority)
+			case "targetPatch":
+				c = patches.compare(a.TargetPatch, b.TargetPatch)
+				if a.TargetPatch == "" || b.TargetPatch == "" {
+					// without a target patch last in both directions
+					if c != 0 {
+						return c
+					}
+					continue
+				}
+			case "created":
+				c = a.Created.Time().Compare(b.Created.Time())
+			case "updated":
+				c = a.Updated.Time().Compare(b.Updated.Time())
+			}
+			if field.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
@@ -0,0 +1,112 @@
+package progress
+
+import (
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
+)
+
+// Week sums the progress of the plans over the week starting on its Monday,
+// in UTC.
+type Week struct {
+	Week           types.DateTime `json:"week"`
+	LevelsGained   int            `json:"levelsGained"`
+	TalentsRaised  int            `json:"talentsRaised"`
+	PlansCompleted int            `json:"plansCompleted"`
+}
+
+// Timeline are the states of a plan over the period, the first one is the
+// state it started the period with.
+type Timeline struct {
+	Plan      string  `json:"plan"`
+	Character string  `json:"character"`
+	Points    []State `json:"points"`
+}
+
+type History struct {
+	From  types.DateTime `json:"from"`
+	To    types.DateTime `json:"to"`
+	Weeks []Week         `json:"weeks"`
+	Plans []Timeline     `json:"plans"`
+}
+
+func weekStart(t time.Time) time.Time {
+	t = t.UTC().Truncate(24 * time.Hour)
+	return t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+}
+
+func toDateTime(t time.Time) types.DateTime {
+	dt, _ := types.ParseDateTime(t)
+	return dt
+}
+
+// LoadHistory aggregates the snapshots of the plans between from and to. A
+// plan starts the period with its latest snapshot before it, the gains are
+// counted between its consecutive snapshots, in the week of the later one.
+func LoadHistory(app core.App, userPlans []plans.Plan, from time.Time, to time.Time) (History, error) {
+	history := History{
+		From:  toDateTime(from),
+		To:    toDateTime(to),
+		Weeks: []Week{},
+		Plans: []Timeline{},
+	}
+	weekIndex := map[time.Time]int{}
+	for week := weekStart(from); !week.After(to); week = week.AddDate(0, 0, 7) {
+		weekIndex[week] = len(history.Weeks)
+		history.Weeks = append(history.Weeks, Week{Week: toDateTime(week)})
+	}
+	if len(userPlans) == 0 {
+		return history, nil
+	}
+
+	planIds := make([]any, len(userPlans))
+	for i, plan := range userPlans {
+		planIds[i] = plan.Id
+	}
+	records := []*core.Record{}
+	err := app.RecordQuery(models.PLAN_SNAPSHOTS_COLLECTION_NAME).
+		AndWhere(dbx.In("characterPlan", planIds...)).
+		AndWhere(dbx.NewExp("[[taken]] <= {:to}", dbx.Params{"to": history.To.String()})).
+		OrderBy("[[taken]] ASC", "[[id]] ASC").
+		All(&records)
+	if err != nil {
+		return history, err
+	}
+	byPlan := map[string][]State{}
+	for _, record := range records {
+		planId := record.GetString("characterPlan")
+		state := stateOfSnapshot(record)
+		if state.Time.Time().Before(from) {
+			// only the latest state before the period is kept
+			state.Time = history.From
+			byPlan[planId] = []State{state}
+			continue
+		}
+		byPlan[planId] = append(byPlan[planId], state)
+	}
+
+	for _, plan := range userPlans {
+		points := byPlan[plan.Id]
+		if len(points) == 0 {
+			continue
+		}
+		history.Plans = append(history.Plans, Timeline{Plan: plan.Id, Character: plan.Character, Points: points})
+		for i := 1; i < len(points); i++ {
+			prev, cur := points[i-1], points[i]
+			week := &history.Weeks[weekIndex[weekStart(cur.Time.Time())]]
+			week.LevelsGained += max(cur.Level-prev.Level, 0)
+			week.TalentsRaised += max(cur.TalentAtk-prev.TalentAtk, 0) +
+				max(cur.TalentSkill-prev.TalentSkill, 0) +
+				max(cur.TalentBurst-prev.TalentBurst, 0)
+			if cur.Complete && !prev.Complete {
+				week.PlansCompleted++
+			}
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,208 @@
+// Package progress keeps the history of the current values of the character
+// plans in snapshots, taken on every change and periodically, and aggregates
+// them into weekly series and per plan timelines.
+package progress
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
+)
+
+const (
+	SNAPSHOT_SCHEDULE_ENV     = "GBP_PROGRESS_SNAPSHOT_SCHEDULE"
+	DEFAULT_SNAPSHOT_SCHEDULE = "0 3 * * *"
+)
+
+const snapshotJobId = "progressSnapshot"
+
+// The reasons a snapshot is taken for.
+const (
+	REASON_CHANGE   = "change"
+	REASON_PERIODIC = "periodic"
+)
+
+// State is the progress of a plan at a point in time.
+type State struct {
+	Time             types.DateTime `json:"time"`
+	Level            int            `json:"level"`
+	Constellation    int            `json:"constellation"`
+	TalentAtk        int            `json:"talentAtk"`
+	TalentSkill      int            `json:"talentSkill"`
+	TalentBurst      int            `json:"talentBurst"`
+	WeaponLevel      int            `json:"weaponLevel"`
+	WeaponRefinement int            `json:"weaponRefinement"`
+	Complete         bool           `json:"complete"`
+	Completion       int            `json:"completion"`
+}
+
+// sameValues reports whether the states differ only in time.
+func (s State) sameValues(other State) bool {
+	other.Time = s.Time
+	return s == other
+}
+
+func stateOfSnapshot(record *core.Record) State {
+	return State{
+		Time:             record.GetDateTime("taken"),
+		Level:            record.GetInt("level"),
+		Constellation:    record.GetInt("constellation"),
+		TalentAtk:        record.GetInt("talentAtk"),
+		TalentSkill:      record.GetInt("talentSkill"),
+		TalentBurst:      record.GetInt("talentBurst"),
+		WeaponLevel:      record.GetInt("weaponLevel"),
+		WeaponRefinement: record.GetInt("weaponRefinement"),
+		Complete:         record.GetBool("complete"),
+		Completion:       record.GetInt("completion"),
+	}
+}
+
+// stateOfPlan reads the current values of the plan and of its tagged weapon,
+// see plans.TaggedWeapon.
+func stateOfPlan(app core.App, plan *core.Record) (State, error) {
+	state := State{
+		Time:          types.NowDateTime(),
+		Level:         plan.GetInt("levelCurrent"),
+		Constellation: plan.GetInt("constellationCurrent"),
+		TalentAtk:     plan.GetInt("talentAtkCurrent"),
+		TalentSkill:   plan.GetInt("talentSkillCurrent"),
+		TalentBurst:   plan.GetInt("talentBurstCurrent"),
+		Complete:      plan.GetBool("complete"),
+		Completion:    plan.GetInt("completion"),
+	}
+	weaponPlans := []*core.Record{}
+	err := app.RecordQuery(models.WEAPON_PLANS_COLLECTION_NAME).
+		AndWhere(dbx.HashExp{"characterPlan": plan.Id}).
+		OrderBy("[[order]] ASC", "[[created]] ASC").
+		All(&weaponPlans)
+	if err != nil {
+		return state, err
+	}
+	if weapon := plans.TaggedWeapon(weaponPlans); weapon != nil {
+		state.WeaponLevel = weapon.GetInt("levelCurrent")
+		state.WeaponRefinement = weapon.GetInt("refinementCurrent")
+	}
+	return state, nil
+}
+
+func findLatestSnapshot(app core.App, planId string) (*core.Record, error) {
+	record := &core.Record{}
+	err := app.RecordQuery(models.PLAN_SNAPSHOTS_COLLECTION_NAME).
+		AndWhere(dbx.HashExp{"characterPlan": planId}).
+		OrderBy("[[taken]] DESC", "[[id]] DESC").
+		Limit(1).
+		One(record)
+	return record, err
+}
+
+// Snapshot records the current values of the plan, unless its latest
+// snapshot holds them already. Returns whether it took one.
+func Snapshot(app core.App, plan *core.Record, reason string) (bool, error) {
+	state, err := stateOfPlan(app, plan)
+	if err != nil {
+		return false, err
+	}
+	latest, err := findLatestSnapshot(app, plan.Id)
+	if err == nil && stateOfSnapshot(latest).sameValues(state) {
+		return false, nil
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	collection, err := app.FindCachedCollectionByNameOrId(models.PLAN_SNAPSHOTS_COLLECTION_NAME)
+	if err != nil {
+		return false, err
+	}
+	snapshot := core.NewRecord(collection)
+	snapshot.Load(map[string]any{
+		"user":             plan.GetString("user"),
+		"characterPlan":    plan.Id,
+		"reason":           reason,
+		"taken":            state.Time,
+		"level":            state.Level,
+		"constellation":    state.Constellation,
+		"talentAtk":        state.TalentAtk,
+		"talentSkill":      state.TalentSkill,
+		"talentBurst":      state.TalentBurst,
+		"weaponLevel":      state.WeaponLevel,
+		"weaponRefinement": state.WeaponRefinement,
+		"complete":         state.Complete,
+		"completion":       state.Completion,
+	})
+	return true, app.Save(snapshot)
+}
+
+// SnapshotAll snapshots every character plan, see Snapshot. Returns the
+// number of snapshots taken.
+func SnapshotAll(app core.App, reason string) (int, error) {
+	records := []*core.Record{}
+	if err := app.RecordQuery(models.CHARACTER_PLANS_COLLECTION_NAME).All(&records); err != nil {
+		return 0, err
+	}
+	taken := 0
+	for _, record := range records {
+		ok, err := Snapshot(app, record, reason)
+		if err != nil {
+			return taken, err
+		}
+		if ok {
+			taken++
+		}
+	}
+	return taken, nil
+}
+
+// Bind snapshots the character plans once their changes, or the changes of
+// their weapon plans, are saved.
+func Bind(app core.App) {
+	snapshot := func(e *core.RecordEvent, planId string) {
+		plan, err := e.App.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, planId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return
+		}
+		if err == nil {
+			_, err = Snapshot(e.App, plan, REASON_CHANGE)
+		}
+		// the change is saved already, a missing snapshot is caught up by
+		// the periodic ones
+		if err != nil {
+			e.App.Logger().Error("Plan snapshot failed", "characterPlan", planId, "error", err)
+		}
+	}
+	onPlan := func(e *core.RecordEvent) error {
+		snapshot(e, e.Record.Id)
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess(models.CHARACTER_PLANS_COLLECTION_NAME).BindFunc(onPlan)
+	app.OnRecordAfterUpdateSuccess(models.CHARACTER_PLANS_COLLECTION_NAME).BindFunc(onPlan)
+
+	onWeapon := func(e *core.RecordEvent) error {
+		snapshot(e, e.Record.GetString("characterPlan"))
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess(models.WEAPON_PLANS_COLLECTION_NAME).BindFunc(onWeapon)
+	app.OnRecordAfterUpdateSuccess(models.WEAPON_PLANS_COLLECTION_NAME).BindFunc(onWeapon)
+	app.OnRecordAfterDeleteSuccess(models.WEAPON_PLANS_COLLECTION_NAME).BindFunc(onWeapon)
+}
+
+// BindSnapshotJob schedules the periodic snapshots with a cron expression,
+// they catch the changes made behind the record hooks.
+func BindSnapshotJob(app core.App, schedule string) error {
+	if schedule == "" {
+		return nil
+	}
+	return app.Cron().Add(snapshotJobId, schedule, func() {
+		taken, err := SnapshotAll(app, REASON_PERIODIC)
+		if err != nil {
+			app.Logger().Error("Periodic plan snapshots failed", "error", err)
+			return
+		}
+		app.Logger().Info("Periodic plan snapshots", "taken", taken)
+	})
+}
@@ -0,0 +1,156 @@
+package progress_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/plans"
+	"github.com/qxuken/gbp/internals/progress"
+	"github.com/qxuken/gbp/internals/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+func snapshots(t *testing.T, app core.App, planId string) []*core.Record {
+	t.Helper()
+	records, err := app.FindAllRecords(models.PLAN_SNAPSHOTS_COLLECTION_NAME, dbx.HashExp{"characterPlan": planId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestSnapshots(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	plans.BindCompletion(app)
+	progress.Bind(app)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+
+	// the plan create, then its weapon plan
+	taken := snapshots(t, app, "characterplan00")
+	if len(taken) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(taken))
+	}
+	latest := taken[1]
+	if latest.GetInt("level") != 80 || latest.GetInt("weaponLevel") != 70 || latest.GetInt("weaponRefinement") != 1 ||
+		latest.GetInt("completion") != 88 || latest.GetString("reason") != progress.REASON_CHANGE || latest.GetString("user") != user.Id {
+		t.Errorf("unexpected snapshot %v", latest.PublicExport())
+	}
+
+	// a save without a change of the values takes none
+	plan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Set("note", "later")
+	if err := app.Save(plan); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(snapshots(t, app, "characterplan00")); n != 2 {
+		t.Errorf("note change: expected 2 snapshots, got %d", n)
+	}
+	plan.Set("talentBurstCurrent", 6)
+	if err := app.Save(plan); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(snapshots(t, app, "characterplan00")); n != 3 {
+		t.Errorf("talent change: expected 3 snapshots, got %d", n)
+	}
+
+	// the periodic ones catch the changes made behind the hooks
+	if n, err := progress.SnapshotAll(app, progress.REASON_PERIODIC); err != nil || n != 0 {
+		t.Errorf("unchanged: expected no snapshots, got %d %v", n, err)
+	}
+	if _, err := app.DB().NewQuery("UPDATE characterPlans SET levelCurrent = 85 WHERE id = 'characterplan00'").Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := progress.SnapshotAll(app, progress.REASON_PERIODIC); err != nil || n != 1 {
+		t.Errorf("changed: expected 1 snapshot, got %d %v", n, err)
+	}
+
+	// they go with their plan
+	if err := app.Delete(plan); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(snapshots(t, app, "characterplan00")); n != 0 {
+		t.Errorf("deleted plan: expected no snapshots, got %d", n)
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	user, _ := testutil.CreateUser(t, app, "user@test.com")
+	testutil.SeedPlans(t, app, user.Id)
+	// no hooks bound, the snapshots are the ones below
+	monday := time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC)
+	snapshot := func(planId string, taken time.Time, level int, talentAtk int, complete bool) {
+		t.Helper()
+		testutil.CreateRecord(t, app, models.PLAN_SNAPSHOTS_COLLECTION_NAME, fmt.Sprintf("snap%s%s000", planId[len(planId)-2:], taken.Format("010215")), map[string]any{
+			"user": user.Id, "characterPlan": planId, "reason": progress.REASON_CHANGE, "taken": taken,
+			"level": level, "talentAtk": talentAtk, "complete": complete,
+		})
+	}
+	// before the period, only the latest one is the starting state
+	snapshot("characterplan00", monday.AddDate(0, 0, -10), 60, 1, false)
+	snapshot("characterplan00", monday.AddDate(0, 0, -3), 70, 2, false)
+	snapshot("characterplan00", monday.AddDate(0, 0, 1), 80, 4, false)
+	snapshot("characterplan00", monday.AddDate(0, 0, 9), 90, 4, true)
+	// created in the period, its first state gains nothing
+	snapshot("characterplan01", monday.AddDate(0, 0, 2), 50, 1, false)
+	snapshot("characterplan01", monday.AddDate(0, 0, 3), 40, 1, false)
+	// after the period
+	snapshot("characterplan01", monday.AddDate(0, 0, 30), 90, 10, true)
+
+	userPlans, err := plans.Load(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := monday.AddDate(0, 0, -1)
+	to := monday.AddDate(0, 0, 13)
+	history, err := progress.LoadHistory(app, userPlans, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.Weeks) != 3 {
+		t.Fatalf("expected 3 weeks, got %+v", history.Weeks)
+	}
+	expected := []progress.Week{
+		{LevelsGained: 0, TalentsRaised: 0, PlansCompleted: 0},
+		{LevelsGained: 10, TalentsRaised: 2, PlansCompleted: 0},
+		{LevelsGained: 10, TalentsRaised: 0, PlansCompleted: 1},
+	}
+	for i, week := range history.Weeks {
+		if start := monday.AddDate(0, 0, 7*(i-1)); !week.Week.Time().Equal(start) {
+			t.Errorf("week %d: expected to start %v, got %v", i, start, week.Week)
+		}
+		week.Week = expected[i].Week
+		if week != expected[i] {
+			t.Errorf("week %d: expected %+v, got %+v", i, expected[i], week)
+		}
+	}
+
+	if len(history.Plans) != 2 {
+		t.Fatalf("expected 2 timelines, got %+v", history.Plans)
+	}
+	timeline := history.Plans[0]
+	if timeline.Plan != "characterplan00" || timeline.Character != "characterdiluc0" || len(timeline.Points) != 3 {
+		t.Fatalf("unexpected timeline %+v", timeline)
+	}
+	if first := timeline.Points[0]; first.Level != 70 || !first.Time.Time().Equal(from) {
+		t.Errorf("expected the starting state at the period start, got %+v", first)
+	}
+	if n := len(history.Plans[1].Points); n != 2 {
+		t.Errorf("expected the snapshots of the period only, got %d", n)
+	}
+}
@@ -0,0 +1,84 @@
+package seed
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// collectionLabel returns the human readable name of the dictionary
+// collection for the changelog.
+func collectionLabel(name string) string {
+	for _, dictionary := range dictionaryCollections {
+		if dictionary.name == name {
+			return dictionary.label
+		}
+	}
+	return name
+}
+
+func joinItemNames(items []ItemRef) string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+		if names[i] == "" {
+			names[i] = item.Id
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// ChangelogNotes renders the diff as a markdown list, e.g.
+//
+//   - Added characters: Bennett
+//   - Updated weapons: Aquila Favonia
+func ChangelogNotes(diff *DumpDiff) string {
+	if diff.IsEmpty() {
+		return "- No dictionary changes"
+	}
+	lines := []string{}
+	for _, collection := range diff.Collections {
+		label := collectionLabel(collection.Collection)
+		if len(collection.Added) > 0 {
+			lines = append(lines, fmt.Sprintf("- Added %s: %s", label, joinItemNames(collection.Added)))
+		}
+		if len(collection.Changed) > 0 {
+			changed := make([]ItemRef, len(collection.Changed))
+			for i, item := range collection.Changed {
+				changed[i] = item.ItemRef
+			}
+			lines = append(lines, fmt.Sprintf("- Updated %s: %s", label, joinItemNames(changed)))
+		}
+		if len(collection.Removed) > 0 {
+			lines = append(lines, fmt.Sprintf("- Removed %s: %s", label, joinItemNames(collection.Removed)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// generateNotes diffs the freshly written dump against the latest stored one
+// and prepends the hand written notes, if any.
+func generateNotes(app core.App, path string, notes string) (string, error) {
+	var generated string
+	latest, err := models.FindLatestDbDump(app)
+	switch {
+	case err == sql.ErrNoRows:
+		generated = "- Initial dictionary"
+	case err != nil:
+		return "", err
+	default:
+		diff, err := DiffDumps(latest.DumpPath(app), path)
+		if err != nil {
+			return "", err
+		}
+		generated = ChangelogNotes(diff)
+	}
+	if notes = strings.TrimSpace(notes); notes != "" {
+		return notes + "\n\n" + generated, nil
+	}
+	return generated, nil
+}
@@ -0,0 +1,267 @@
+package seed
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the encoding a seed file is stored with. The names match the
+// http Content-Encoding tokens so that a stored dump can be served as is.
+type Compression string
+
+const (
+	COMPRESSION_NONE Compression = ""
+	COMPRESSION_GZIP Compression = "gzip"
+	COMPRESSION_ZSTD Compression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ParseCompression validates a user provided compression name, "none" and an
+// empty string both mean an uncompressed file.
+func ParseCompression(name string) (Compression, error) {
+	switch name {
+	case "", "none":
+		return COMPRESSION_NONE, nil
+	case string(COMPRESSION_GZIP):
+		return COMPRESSION_GZIP, nil
+	case string(COMPRESSION_ZSTD):
+		return COMPRESSION_ZSTD, nil
+	default:
+		return COMPRESSION_NONE, fmt.Errorf("unknown compression %q, expected one of none, gzip, zstd", name)
+	}
+}
+
+// Ext is the file extension appended to the compressed seed files.
+func (c Compression) Ext() string {
+	switch c {
+	case COMPRESSION_GZIP:
+		return ".gz"
+	case COMPRESSION_ZSTD:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+func sniffCompression(header []byte) Compression {
+	switch {
+	case bytes.HasPrefix(header, zstdMagic):
+		return COMPRESSION_ZSTD
+	case bytes.HasPrefix(header, gzipMagic):
+		return COMPRESSION_GZIP
+	default:
+		return COMPRESSION_NONE
+	}
+}
+
+// DetectCompression reads the magic bytes of the file at path.
+func DetectCompression(path string) (Compression, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return COMPRESSION_NONE, err
+	}
+	defer f.Close()
+	header := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return COMPRESSION_NONE, err
+	}
+	return sniffCompression(header[:n]), nil
+}
+
+type decompressReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *decompressReader) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if cErr := c.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+type zstdCloser struct {
+	d *zstd.Decoder
+}
+
+func (c zstdCloser) Close() error {
+	c.d.Close()
+	return nil
+}
+
+// NewDecompressReader wraps r with the decoder matching its magic bytes, an
+// uncompressed stream is passed through untouched.
+func NewDecompressReader(r io.Reader) (io.ReadCloser, Compression, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, COMPRESSION_NONE, err
+	}
+	compression := sniffCompression(header)
+	switch compression {
+	case COMPRESSION_GZIP:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, compression, err
+		}
+		return &decompressReader{Reader: gz, closers: []io.Closer{gz}}, compression, nil
+	case COMPRESSION_ZSTD:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, compression, err
+		}
+		return &decompressReader{Reader: zr, closers: []io.Closer{zstdCloser{zr}}}, compression, nil
+	default:
+		return io.NopCloser(br), compression, nil
+	}
+}
+
+// OpenSeedFile opens a possibly compressed seed file for reading its
+// uncompressed content.
+func OpenSeedFile(path string) (io.ReadCloser, Compression, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, COMPRESSION_NONE, err
+	}
+	r, compression, err := NewDecompressReader(f)
+	if err != nil {
+		f.Close()
+		return nil, compression, err
+	}
+	if rc, ok := r.(*decompressReader); ok {
+		rc.closers = append(rc.closers, f)
+		return rc, compression, nil
+	}
+	return &decompressReader{Reader: r, closers: []io.Closer{f}}, compression, nil
+}
+
+// CompressFile rewrites the file at path with the given compression.
+func CompressFile(path string, compression Compression) error {
+	if compression == COMPRESSION_NONE {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return writeCompressed(path, src, compression)
+}
+
+// writeCompressed writes the content of src into the file at path with the
+// given compression, through a temporary file so that a failure leaves the
+// path untouched.
+func writeCompressed(path string, src io.Reader, compression Compression) error {
+	dst, err := os.CreateTemp(filepath.Dir(path), "*"+compression.Ext())
+	if err != nil {
+		return err
+	}
+	dstPath := dst.Name()
+	defer os.Remove(dstPath)
+
+	var w io.WriteCloser
+	switch compression {
+	case COMPRESSION_GZIP:
+		w, err = gzip.NewWriterLevel(dst, gzip.BestCompression)
+	case COMPRESSION_ZSTD:
+		w, err = zstd.NewWriter(dst, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	default:
+		err = fmt.Errorf("unknown compression %q", compression)
+	}
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		w.Close()
+		dst.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Rename(dstPath, path)
+}
+
+// DumpVariants are the compressions a stored dump is kept in for the
+// download, the preferred first.
+var DumpVariants = []Compression{COMPRESSION_ZSTD, COMPRESSION_GZIP}
+
+// writeDumpVariants writes the dump at path in the other DumpVariants next to
+// it, named with the compression extension appended.
+func writeDumpVariants(path string) error {
+	stored, err := DetectCompression(path)
+	if err != nil {
+		return err
+	}
+	for _, compression := range DumpVariants {
+		if compression == stored {
+			continue
+		}
+		r, _, err := OpenSeedFile(path)
+		if err != nil {
+			return err
+		}
+		err = writeCompressed(path+compression.Ext(), r, compression)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decompressToTemp materializes a compressed seed file, sqlite can only open
+// regular files. The returned cleanup removes the temporary copy, for an
+// uncompressed file the original path is returned with a no-op cleanup.
+func decompressToTemp(path string) (string, func(), error) {
+	compression, err := DetectCompression(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if compression == COMPRESSION_NONE {
+		return path, func() {}, nil
+	}
+
+	r, _, err := OpenSeedFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+
+	tmpFile, err := os.CreateTemp("", "*-seed.db")
+	if err != nil {
+		return "", nil, err
+	}
+	tmpPath := tmpFile.Name()
+	cleanup := func() { os.Remove(tmpPath) }
+	if _, err := io.Copy(tmpFile, r); err != nil {
+		tmpFile.Close()
+		cleanup()
+		return "", nil, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tmpPath, cleanup, nil
+}
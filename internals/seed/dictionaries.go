@@ -5,24 +5,108 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/filesystem"
+
 	"github.com/qxuken/gbp/internals/models"
 	"github.com/spf13/cobra"
 )
 
+// dictionaryCollection binds a dictionary collection to the struct mapping
+// its seed table.
+type dictionaryCollection struct {
+	name   string
+	label  string // the human readable name of the changelog
+	fields func() []pbFieldInfo
+	seed   func(app core.App, db dbx.Builder, progress Progress) error
+	dump   func(app core.App, fsys *filesystem.System, db dbx.Builder, progress Progress) error
+	diff   func(oldDb dbx.Builder, newDb dbx.Builder) (*CollectionDiff, error)
+	// export and build convert the seed table from and to the source dir
+	export func(db dbx.Builder, dir string) error
+	build  func(app core.App, dir string, db dbx.Builder) error
+	// relationIds lists the ids the seed table references in a field
+	relationIds func(db dbx.Builder, field string) ([]string, error)
+}
+
+func dictionary[T any](name string, label string) dictionaryCollection {
+	return dictionaryCollection{
+		name:   name,
+		label:  label,
+		fields: mustGetFieldInfo[T],
+		seed: func(app core.App, db dbx.Builder, progress Progress) error {
+			return seedCollection[T](app, db, name, progress)
+		},
+		dump: func(app core.App, fsys *filesystem.System, db dbx.Builder, progress Progress) error {
+			if err := createTableFromStruct[T](db, name); err != nil {
+				return err
+			}
+			return dumpCollection[T](app, fsys, db, name, progress)
+		},
+		diff: func(oldDb dbx.Builder, newDb dbx.Builder) (*CollectionDiff, error) {
+			return diffCollection[T](oldDb, newDb, name)
+		},
+		export: func(db dbx.Builder, dir string) error {
+			return exportCollection[T](db, dir, name)
+		},
+		build: func(app core.App, dir string, db dbx.Builder) error {
+			return buildCollection[T](app, dir, db, name)
+		},
+		relationIds: func(db dbx.Builder, field string) ([]string, error) {
+			return relationIdsCollection[T](db, name, field)
+		},
+	}
+}
+
+// dictionaryCollections are listed in the seeding order, a collection only
+// relates to the ones before it.
+var dictionaryCollections = []dictionaryCollection{
+	dictionary[Special](models.SPECIALS_COLLECTION_NAME, "specials"),
+	dictionary[Element](models.ELEMENTS_COLLECTION_NAME, "elements"),
+	dictionary[CharacterRole](models.CHARACTER_ROLES_COLLECTION_NAME, "character roles"),
+	dictionary[Patch](models.PATCH_COLLECTION_NAME, "patches"),
+	dictionary[ArtifactSet](models.ARTIFACT_SETS_COLLECTION_NAME, "artifact sets"),
+	dictionary[ArtifactType](models.ARTIFACT_TYPES_COLLECTION_NAME, "artifact types"),
+	dictionary[DomainOfBlessing](models.DOMAINS_OF_BLESSING_COLLECTION_NAME, "domains"),
+	dictionary[WeaponType](models.WEAPON_TYPES_COLLECTION_NAME, "weapon types"),
+	dictionary[Weapon](models.WEAPONS_COLLECTION_NAME, "weapons"),
+	dictionary[Character](models.CHARACTERS_COLLECTION_NAME, "characters"),
+	dictionary[Translation](models.TRANSLATIONS_COLLECTION_NAME, "translations"),
+}
+
+// DictionaryCollections returns the names of the seeded collections in the
+// seeding order.
+func DictionaryCollections() []string {
+	names := make([]string, len(dictionaryCollections))
+	for i, dictionary := range dictionaryCollections {
+		names[i] = dictionary.name
+	}
+	return names
+}
+
 func NewCobraSeedCommand(app core.App) *cobra.Command {
-	return &cobra.Command{
+	var lockTimeout time.Duration
+	var only, exclude []string
+	command := &cobra.Command{
 		Use:     "seed seed_file",
 		Aliases: []string{"s"},
 		Short:   "Seed command",
 		Args:    cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return Seed(app, args[0])
+			return SeedWithOptions(app, args[0], SeedOptions{
+				LockTimeout: lockTimeout,
+				Only:        only,
+				Exclude:     exclude,
+			})
 		},
 	}
+	command.Flags().DurationVar(&lockTimeout, "lock-timeout", DEFAULT_SEED_LOCK_TIMEOUT, "how long to wait for a seed already in progress")
+	command.Flags().StringSliceVar(&only, "only", nil, "seed only these collections, e.g. characters,weapons")
+	command.Flags().StringSliceVar(&exclude, "exclude", nil, "skip these collections")
+	command.AddCommand(NewCobraSeedPullCommand(app))
+	return command
 }
 
 func NewCobraSeedHashCommand() *cobra.Command {
@@ -39,29 +123,33 @@ func NewCobraSeedHashCommand() *cobra.Command {
 			if len(args) <= 1 {
 				return nil
 			}
-			fd, err := os.Create(args[1])
-			if err != nil {
-				return err
-			}
-			_, err = fd.WriteString(hash)
-			return err
+			return os.WriteFile(args[1], []byte(hash), 0644)
 		},
 	}
 }
 
+// GetSeedHash hashes the uncompressed content of the seed file, so a dump
+// keeps its hash whichever compression it is stored with.
 func GetSeedHash(path string) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return "", err
 	}
 	defer f.Close()
-	h := sha256.New()
-	_, err = io.Copy(h, f)
+	return hashSeedReader(f)
+}
+
+func hashSeedReader(r io.Reader) (string, error) {
+	dr, _, err := NewDecompressReader(r)
 	if err != nil {
 		return "", err
 	}
-	hashStr := fmt.Sprintf("%x", h.Sum(nil))
-	return hashStr, err
+	defer dr.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, dr); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
 func UpdateDictionaryVersion(app core.App, path string) error {
@@ -70,90 +158,117 @@ func UpdateDictionaryVersion(app core.App, path string) error {
 		return err
 	}
 	app.Logger().Debug("Seed Hash " + hash)
+	if err := recordDictionaryVersion(app, hash); err != nil {
+		return err
+	}
 	if _, err := models.UpsertAppSettings(app, "dictionaryVersion", hash); err != nil {
 		return err
 	}
 	return nil
 }
 
+// SeedOptions tune a Seed run, the zero value seeds every dictionary.
+type SeedOptions struct {
+	Progress Progress
+	// Only and Exclude narrow the seeded collections, a partial seed leaves
+	// the dictionary version untouched
+	Only    []string
+	Exclude []string
+	// LockTimeout bounds the wait for a running seed,
+	// DEFAULT_SEED_LOCK_TIMEOUT when zero
+	LockTimeout time.Duration
+}
+
 func Seed(app core.App, path string) error {
+	return SeedWithOptions(app, path, SeedOptions{})
+}
+
+func SeedWithOptions(app core.App, path string, opts SeedOptions) error {
 	app.Logger().Info("Seeding")
 	app.Logger().Debug(fmt.Sprintf("seed db path %#v", path))
 
-	db, err := core.DefaultDBConnect(path)
+	selected, err := selectDictionaries(opts.Only, opts.Exclude)
 	if err != nil {
 		return err
 	}
-	defer db.Close()
-
-	err = app.RunInTransaction(func(txApp core.App) error {
-		if err := seedCollection[Special](txApp, db, models.SPECIALS_COLLECTION_NAME); err != nil {
-			return err
-		}
-
-		if err := seedCollection[Element](txApp, db, models.ELEMENTS_COLLECTION_NAME); err != nil {
-			return err
-		}
-
-		if err := seedCollection[CharacterRole](txApp, db, models.CHARACTER_ROLES_COLLECTION_NAME); err != nil {
-			return err
-		}
 
-		if err := seedCollection[Patch](txApp, db, models.PATCH_COLLECTION_NAME); err != nil {
-			return err
-		}
-
-		if err := seedCollection[ArtifactSet](txApp, db, models.ARTIFACT_SETS_COLLECTION_NAME); err != nil {
-			return err
-		}
+	lock, err := AcquireSeedLock(app, opts.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
 
-		if err := seedCollection[ArtifactType](txApp, db, models.ARTIFACT_TYPES_COLLECTION_NAME); err != nil {
-			return err
+	err = seedFile(app, path, selected, opts.Progress, func(txApp core.App) error {
+		// the app no longer matches a single dump after a partial seed
+		if isPartialSelection(selected) {
+			return nil
 		}
+		return UpdateDictionaryVersion(txApp, path)
+	})
+	if err == nil {
+		app.Logger().Info("Seed Completed")
+	}
 
-		if err := seedCollection[DomainOfBlessing](txApp, db, models.DOMAINS_OF_BLESSING_COLLECTION_NAME); err != nil {
-			return err
-		}
+	return err
+}
 
-		if err := seedCollection[WeaponType](txApp, db, models.WEAPON_TYPES_COLLECTION_NAME); err != nil {
-			return err
-		}
+// seedFile seeds the selected collections of the seed file and runs finish
+// within the same transaction. The caller holds the seed lock.
+func seedFile(app core.App, path string, selected []dictionaryCollection, progress Progress, finish func(txApp core.App) error) error {
+	dbPath, cleanup, err := decompressToTemp(path)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 
-		if err := seedCollection[Weapon](txApp, db, models.WEAPONS_COLLECTION_NAME); err != nil {
-			return err
-		}
+	db, err := core.DefaultDBConnect(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
 
-		if err := seedCollection[Character](txApp, db, models.CHARACTERS_COLLECTION_NAME); err != nil {
+	return app.RunInTransaction(func(txApp core.App) error {
+		if err := checkSelection(txApp, db, selected); err != nil {
 			return err
 		}
-
-		if err := UpdateDictionaryVersion(txApp, path); err != nil {
-			return err
+		for _, dictionary := range selected {
+			if err := dictionary.seed(txApp, db, progress); err != nil {
+				return err
+			}
 		}
-		return nil
+		return finish(txApp)
 	})
-
-	if err == nil {
-		app.Logger().Info("Seed Completed")
-	}
-
-	return err
 }
 
 func NewCobraDumpCommand(app core.App) *cobra.Command {
-	return &cobra.Command{
+	var compress string
+	var generateNotes bool
+	command := &cobra.Command{
 		Use:     "dump seed_file",
 		Aliases: []string{"d"},
 		Short:   "Dump command",
 		Args:    cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			compression, err := ParseCompression(compress)
+			if err != nil {
+				return err
+			}
 			var notes string
 			if len(args) > 1 {
 				notes = args[1]
 			}
-			return Dump(app, args[0], notes)
+			return DumpWithOptions(app, args[0], DumpOptions{
+				Notes:         notes,
+				GenerateNotes: generateNotes,
+				Compression:   compression,
+			})
 		},
 	}
+	command.Flags().StringVar(&compress, "compress", "none", "compress the dump file (none, gzip, zstd)")
+	command.Flags().BoolVar(&generateNotes, "generate-notes", false, "append the changes since the latest dump to the notes")
+	command.AddCommand(NewCobraDumpDiffCommand())
+	command.AddCommand(NewCobraDumpRollbackCommand(app))
+	return command
 }
 
 func SaveDump(app core.App, path string, notes string) error {
@@ -175,14 +290,38 @@ func SaveDump(app core.App, path string, notes string) error {
 	}
 	record.Set("dump", dumpfile)
 	record.Set("notes", notes)
+	compression, err := DetectCompression(path)
+	if err != nil {
+		return err
+	}
+	record.Set("encoding", string(compression))
+	if err := app.Save(record); err != nil {
+		return err
+	}
 
-	return app.Save(record)
+	// the download falls back to the stored file without the variants
+	dump := &models.DbDump{}
+	dump.SetProxyRecord(record)
+	if err := writeDumpVariants(dump.DumpPath(app)); err != nil {
+		app.Logger().Warn("Failed to write the dump variants", "error", err)
+	}
+	return nil
 }
 
-func Dump(app core.App, path string, notes string) error {
-	app.Logger().Info("Dumping db")
-	app.Logger().Debug(fmt.Sprintf("seed db path %#v", path))
+// DumpOptions tune a Dump run.
+type DumpOptions struct {
+	Notes string
+	// GenerateNotes appends the changes since the latest stored dump to Notes
+	GenerateNotes bool
+	Compression   Compression
+	Progress      Progress
+	// LockTimeout bounds the wait for a running seed or dump,
+	// DEFAULT_SEED_LOCK_TIMEOUT when zero
+	LockTimeout time.Duration
+}
 
+// writeDump writes the dictionaries into a new seed file without storing it.
+func writeDump(app core.App, path string, progress Progress) error {
 	fsys, err := app.NewFilesystem()
 	if err != nil {
 		return err
@@ -193,87 +332,51 @@ func Dump(app core.App, path string, notes string) error {
 	if err != nil {
 		return err
 	}
+	defer db.Close()
 
-	err = app.RunInTransaction(func(txApp core.App) error {
+	return app.RunInTransaction(func(txApp core.App) error {
 		return db.Transactional(func(txDb *dbx.Tx) error {
-			if err := createTableFromStruct[Special](txDb, models.SPECIALS_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[Special](txApp, fsys, txDb, models.SPECIALS_COLLECTION_NAME); err != nil {
-				return err
-			}
-
-			if err := createTableFromStruct[Element](txDb, models.ELEMENTS_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[Element](txApp, fsys, txDb, models.ELEMENTS_COLLECTION_NAME); err != nil {
-				return err
-			}
-
-			if err := createTableFromStruct[CharacterRole](txDb, models.CHARACTER_ROLES_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[CharacterRole](txApp, fsys, txDb, models.CHARACTER_ROLES_COLLECTION_NAME); err != nil {
-				return err
-			}
-
-			if err := createTableFromStruct[Patch](txDb, models.PATCH_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[Patch](txApp, fsys, txDb, models.PATCH_COLLECTION_NAME); err != nil {
-				return err
-			}
-
-			if err := createTableFromStruct[ArtifactSet](txDb, models.ARTIFACT_SETS_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[ArtifactSet](txApp, fsys, txDb, models.ARTIFACT_SETS_COLLECTION_NAME); err != nil {
-				return err
+			for _, dictionary := range dictionaryCollections {
+				if err := dictionary.dump(txApp, fsys, txDb, progress); err != nil {
+					return err
+				}
 			}
+			return nil
+		})
+	})
+}
 
-			if err := createTableFromStruct[ArtifactType](txDb, models.ARTIFACT_TYPES_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[ArtifactType](txApp, fsys, txDb, models.ARTIFACT_TYPES_COLLECTION_NAME); err != nil {
-				return err
-			}
+func Dump(app core.App, path string, notes string) error {
+	return DumpWithOptions(app, path, DumpOptions{Notes: notes})
+}
 
-			if err := createTableFromStruct[DomainOfBlessing](txDb, models.DOMAINS_OF_BLESSING_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[DomainOfBlessing](txApp, fsys, txDb, models.DOMAINS_OF_BLESSING_COLLECTION_NAME); err != nil {
-				return err
-			}
+func DumpWithOptions(app core.App, path string, opts DumpOptions) error {
+	app.Logger().Info("Dumping db")
+	app.Logger().Debug(fmt.Sprintf("seed db path %#v", path))
 
-			if err := createTableFromStruct[WeaponType](txDb, models.WEAPON_TYPES_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[WeaponType](txApp, fsys, txDb, models.WEAPON_TYPES_COLLECTION_NAME); err != nil {
-				return err
-			}
+	// the lock also covers SaveDump, two dumps of the same data would
+	// otherwise race on the unique hash
+	lock, err := AcquireDumpLock(app, opts.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
 
-			if err := createTableFromStruct[Weapon](txDb, models.WEAPONS_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[Weapon](txApp, fsys, txDb, models.WEAPONS_COLLECTION_NAME); err != nil {
-				return err
-			}
+	if err := writeDump(app, path, opts.Progress); err != nil {
+		return err
+	}
+	app.Logger().Info("Dump Completed")
 
-			if err := createTableFromStruct[Character](txDb, models.CHARACTERS_COLLECTION_NAME); err != nil {
-				return err
-			}
-			if err := dumpCollection[Character](txApp, fsys, txDb, models.CHARACTERS_COLLECTION_NAME); err != nil {
-				return err
-			}
+	notes := opts.Notes
+	if opts.GenerateNotes {
+		if notes, err = generateNotes(app, path, notes); err != nil {
+			return err
+		}
+	}
 
-			return nil
-		})
-	})
-	if err != nil {
+	if err := CompressFile(path, opts.Compression); err != nil {
 		return err
 	}
-	app.Logger().Info("Dump Completed")
-	db.Close()
 
 	return SaveDump(app, path, notes)
 }
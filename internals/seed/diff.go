@@ -0,0 +1,306 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// ItemRef identifies a dictionary item in a diff.
+type ItemRef struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// FieldChange is a single changed field of a dictionary item.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// ItemChange lists the differences of an item present in both dumps. The
+// icons are only compared, their content is not part of the diff.
+type ItemChange struct {
+	ItemRef
+	Fields      []FieldChange `json:"fields,omitempty"`
+	IconChanged bool          `json:"iconChanged,omitempty"`
+}
+
+// CollectionDiff is the difference of a single dictionary collection.
+type CollectionDiff struct {
+	Collection string       `json:"collection"`
+	Added      []ItemRef    `json:"added,omitempty"`
+	Removed    []ItemRef    `json:"removed,omitempty"`
+	Changed    []ItemChange `json:"changed,omitempty"`
+}
+
+func (d *CollectionDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DumpDiff is the difference between two seed files, only the collections
+// with changes are listed.
+type DumpDiff struct {
+	Collections []CollectionDiff `json:"collections"`
+}
+
+func (d *DumpDiff) IsEmpty() bool {
+	return len(d.Collections) == 0
+}
+
+// Collection returns the diff of the named collection, nil when unchanged.
+func (d *DumpDiff) Collection(name string) *CollectionDiff {
+	for i := range d.Collections {
+		if d.Collections[i].Collection == name {
+			return &d.Collections[i]
+		}
+	}
+	return nil
+}
+
+// WriteText prints the diff in a human readable form.
+func (d *DumpDiff) WriteText(w io.Writer) error {
+	if d.IsEmpty() {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+	for _, collection := range d.Collections {
+		fmt.Fprintln(w, collection.Collection)
+		for _, item := range collection.Added {
+			fmt.Fprintf(w, "  + %s (%s)\n", item.Name, item.Id)
+		}
+		for _, item := range collection.Removed {
+			fmt.Fprintf(w, "  - %s (%s)\n", item.Name, item.Id)
+		}
+		for _, item := range collection.Changed {
+			fmt.Fprintf(w, "  ~ %s (%s)\n", item.Name, item.Id)
+			for _, field := range item.Fields {
+				fmt.Fprintf(w, "      %s: %v -> %v\n", field.Field, formatDiffValue(field.Old), formatDiffValue(field.New))
+			}
+			if item.IconChanged {
+				fmt.Fprintln(w, "      icon changed")
+			}
+		}
+	}
+	return nil
+}
+
+func formatDiffValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(raw)
+	}
+}
+
+// displayNamer names a dictionary item in the diff when its name field is
+// missing or doesn't describe it, like a translation or a patch.
+type displayNamer interface {
+	DisplayName() string
+}
+
+func (t Translation) DisplayName() string {
+	return fmt.Sprintf("%s %s: %s", t.Locale, t.Record, t.Name)
+}
+
+func (p Patch) DisplayName() string {
+	return fmt.Sprintf("%d.%d", p.Major, p.Patch)
+}
+
+func itemRef(rv reflect.Value, fields []pbFieldInfo) ItemRef {
+	ref := ItemRef{}
+	for _, fd := range fields {
+		switch {
+		case fd.isPK:
+			ref.Id = rv.Field(fd.structIdx).String()
+		case fd.pbKey == "name" && fd.goType.Kind() == reflect.String:
+			ref.Name = rv.Field(fd.structIdx).String()
+		}
+	}
+	if namer, ok := rv.Interface().(displayNamer); ok {
+		ref.Name = namer.DisplayName()
+	}
+	return ref
+}
+
+func hasTable(db dbx.Builder, table string) (bool, error) {
+	var count int
+	err := db.NewQuery("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = {:name}").
+		Bind(dbx.Params{"name": table}).
+		Row(&count)
+	return count > 0, err
+}
+
+// loadDumpItems reads a dump table, a table missing from an older dump is
+// treated as an empty one.
+func loadDumpItems[T any](db dbx.Builder, table string) ([]T, error) {
+	items := []T{}
+	exists, err := hasTable(db, table)
+	if err != nil || !exists {
+		return items, err
+	}
+	err = db.NewQuery(fmt.Sprintf("select * from %v", table)).All(&items)
+	return items, err
+}
+
+func diffCollection[T any](oldDb dbx.Builder, newDb dbx.Builder, table string) (*CollectionDiff, error) {
+	oldItems, err := loadDumpItems[T](oldDb, table)
+	if err != nil {
+		return nil, err
+	}
+	newItems, err := loadDumpItems[T](newDb, table)
+	if err != nil {
+		return nil, err
+	}
+	fields := mustGetFieldInfo[T]()
+
+	oldById := make(map[string]reflect.Value, len(oldItems))
+	for _, item := range oldItems {
+		rv := reflect.ValueOf(item)
+		oldById[itemRef(rv, fields).Id] = rv
+	}
+
+	diff := &CollectionDiff{Collection: table}
+	seen := make(map[string]bool, len(newItems))
+	for _, item := range newItems {
+		newRv := reflect.ValueOf(item)
+		ref := itemRef(newRv, fields)
+		seen[ref.Id] = true
+		oldRv, ok := oldById[ref.Id]
+		if !ok {
+			diff.Added = append(diff.Added, ref)
+			continue
+		}
+		change := ItemChange{ItemRef: ref}
+		for _, fd := range fields {
+			// the icon filename is derived from the item name
+			if fd.isPK || fd.isFileExt {
+				continue
+			}
+			oldValue := oldRv.Field(fd.structIdx).Interface()
+			newValue := newRv.Field(fd.structIdx).Interface()
+			if fd.isFile {
+				if !bytes.Equal(oldRv.Field(fd.structIdx).Bytes(), newRv.Field(fd.structIdx).Bytes()) {
+					change.IconChanged = true
+				}
+				continue
+			}
+			// the file contents stay out of the diff, only their names are listed
+			if fd.isFiles {
+				if !reflect.DeepEqual(oldValue, newValue) {
+					change.Fields = append(change.Fields, FieldChange{Field: fd.pbKey, Old: oldValue.(Files).Names(), New: newValue.(Files).Names()})
+				}
+				continue
+			}
+			if !reflect.DeepEqual(oldValue, newValue) {
+				change.Fields = append(change.Fields, FieldChange{Field: fd.pbKey, Old: oldValue, New: newValue})
+			}
+		}
+		if len(change.Fields) > 0 || change.IconChanged {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for _, item := range oldItems {
+		ref := itemRef(reflect.ValueOf(item), fields)
+		if !seen[ref.Id] {
+			diff.Removed = append(diff.Removed, ref)
+		}
+	}
+
+	byName := func(a, b ItemRef) int {
+		if a.Name != b.Name {
+			if a.Name < b.Name {
+				return -1
+			}
+			return 1
+		}
+		if a.Id < b.Id {
+			return -1
+		}
+		return 1
+	}
+	slices.SortFunc(diff.Added, byName)
+	slices.SortFunc(diff.Removed, byName)
+	slices.SortFunc(diff.Changed, func(a, b ItemChange) int { return byName(a.ItemRef, b.ItemRef) })
+	return diff, nil
+}
+
+// openDumpDb connects to a possibly compressed seed file for reading.
+func openDumpDb(path string) (*dbx.DB, func(), error) {
+	dbPath, cleanup, err := decompressToTemp(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := core.DefaultDBConnect(dbPath)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return db, func() {
+		db.Close()
+		cleanup()
+	}, nil
+}
+
+// DiffDumps compares two seed files collection by collection using the seed
+// struct mappings.
+func DiffDumps(oldPath string, newPath string) (*DumpDiff, error) {
+	oldDb, closeOld, err := openDumpDb(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeOld()
+	newDb, closeNew, err := openDumpDb(newPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeNew()
+
+	diff := &DumpDiff{Collections: []CollectionDiff{}}
+	for _, dictionary := range dictionaryCollections {
+		collectionDiff, err := dictionary.diff(oldDb, newDb)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dictionary.name, err)
+		}
+		if !collectionDiff.IsEmpty() {
+			diff.Collections = append(diff.Collections, *collectionDiff)
+		}
+	}
+	return diff, nil
+}
+
+func NewCobraDumpDiffCommand() *cobra.Command {
+	var asJSON bool
+	command := &cobra.Command{
+		Use:   "diff old_seed_file new_seed_file",
+		Short: "Show the dictionary changes between two dumps",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			diff, err := DiffDumps(args[0], args[1])
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(diff)
+			}
+			return diff.WriteText(cmd.OutOrStdout())
+		},
+	}
+	command.Flags().BoolVar(&asJSON, "json", false, "print the diff as json")
+	return command
+}
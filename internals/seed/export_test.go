@@ -0,0 +1,80 @@
+package seed
+
+import (
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// DumpCollection dumps a single collection with the mapping of T, for the
+// field kinds no dictionary uses yet.
+func DumpCollection[T any](app core.App, path string, table string) error {
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+	db, err := core.DefaultDBConnect(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := createTableFromStruct[T](db, table); err != nil {
+		return err
+	}
+	return dumpCollection[T](app, fsys, db, table, nil)
+}
+
+// SeedCollection seeds a single collection with the mapping of T.
+func SeedCollection[T any](app core.App, path string, table string) error {
+	db, err := core.DefaultDBConnect(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return seedCollection[T](app, db, table, nil)
+}
+
+// WriteDump writes a seed file without storing it as a dump.
+var WriteDump = writeDump
+
+// UseReflectionMappings turns the generated mappings off for the test.
+func UseReflectionMappings(t testing.TB) {
+	useGeneratedMappings = false
+	t.Cleanup(func() { useGeneratedMappings = true })
+}
+
+func tableSchemaMismatch[T any]() string {
+	generated := structTableSchema[T]()
+	useGeneratedMappings = false
+	defer func() { useGeneratedMappings = true }()
+	if reflected := structTableSchema[T](); !maps.Equal(generated, reflected) {
+		return fmt.Sprintf("%T: generated %v, reflected %v", *new(T), generated, reflected)
+	}
+	return ""
+}
+
+// TableSchemaMismatches compares the generated table schemas of the
+// dictionaries with the reflected ones.
+func TableSchemaMismatches() []string {
+	mismatches := []string{}
+	for _, mismatch := range []string{
+		tableSchemaMismatch[Special](),
+		tableSchemaMismatch[Element](),
+		tableSchemaMismatch[CharacterRole](),
+		tableSchemaMismatch[Patch](),
+		tableSchemaMismatch[ArtifactSet](),
+		tableSchemaMismatch[ArtifactType](),
+		tableSchemaMismatch[DomainOfBlessing](),
+		tableSchemaMismatch[WeaponType](),
+		tableSchemaMismatch[Weapon](),
+		tableSchemaMismatch[Character](),
+	} {
+		if mismatch != "" {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+	return mismatches
+}
@@ -0,0 +1,118 @@
+package seed
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	validation "github.com/pocketbase/ozzo-validation/v4"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+
+	"github.com/qxuken/gbp/internals/icons"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// iconFields lists the icon fields of the dictionary collection.
+func iconFields(collection string) []string {
+	idx := slices.IndexFunc(dictionaryCollections, func(d dictionaryCollection) bool { return d.name == collection })
+	if idx < 0 {
+		return nil
+	}
+	fields := []string{}
+	for _, fd := range dictionaryCollections[idx].fields() {
+		if fd.isFile {
+			fields = append(fields, fd.pbKey)
+		}
+	}
+	return fields
+}
+
+func readUploadedFile(file *filesystem.File) ([]byte, error) {
+	reader, err := file.Reader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// normalizeRecordIcons replaces the icons uploaded to the record with their
+// normalized webp named after the content hash, and makes sure the icons
+// collection holds their variants.
+func normalizeRecordIcons(app core.App, record *core.Record) error {
+	for _, field := range iconFields(record.Collection().Name) {
+		for _, file := range record.GetUnsavedFiles(field) {
+			content, err := readUploadedFile(file)
+			if err != nil {
+				return err
+			}
+			icon, err := icons.Normalize(content)
+			if errors.Is(err, icons.ErrUnsupportedImage) {
+				return validation.Errors{field: validation.NewError("validation_unsupported_image", err.Error())}
+			} else if err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+			normalized, err := filesystem.NewFileFromBytes(icon.Image, icons.FileName(icon.Hash))
+			if err != nil {
+				return err
+			}
+			// the content hash is unique enough, skip the upload suffix
+			normalized.Name = icons.FileName(icon.Hash)
+			record.Set(field, normalized)
+			if err := ensureIcon(app, icon); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ensureIcon saves the icon variants unless an icon with the same hash was
+// already saved, from this collection or another one.
+func ensureIcon(app core.App, icon *icons.Icon) error {
+	if _, err := models.FindIconByHash(app, icon.Hash); err == nil {
+		return nil
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+	thumb, err := icon.Thumb()
+	if err != nil {
+		return err
+	}
+	record, err := models.NewIcon(app)
+	if err != nil {
+		return err
+	}
+	image, err := filesystem.NewFileFromBytes(icon.Image, icons.FileName(icon.Hash))
+	if err != nil {
+		return err
+	}
+	image.Name = icons.FileName(icon.Hash)
+	thumbFile, err := filesystem.NewFileFromBytes(thumb, icons.ThumbName(icon.Hash))
+	if err != nil {
+		return err
+	}
+	thumbFile.Name = icons.ThumbName(icon.Hash)
+	record.Set("hash", icon.Hash)
+	record.Set("image", image)
+	record.Set("thumb", thumbFile)
+	record.Set("width", icon.Width)
+	record.Set("height", icon.Height)
+	return app.Save(record)
+}
+
+// BindIconPipeline normalizes the icons of the dictionary records on every
+// save, the admin ui uploads and the seeds alike, see internals/icons.
+func BindIconPipeline(app core.App) {
+	normalize := func(e *core.RecordEvent) error {
+		if err := normalizeRecordIcons(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	}
+	app.OnRecordCreate(DictionaryCollections()...).BindFunc(normalize)
+	app.OnRecordUpdate(DictionaryCollections()...).BindFunc(normalize)
+}
@@ -0,0 +1,258 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// SEED_LOCK_FILE is created in the data dir while a seed, a rollback or a
+// dump runs, it keeps the serve process and the cli commands from seeding at
+// the same time. The dumps mark it with their kind, they only read the
+// dictionaries so IsSeeding ignores them.
+const SEED_LOCK_FILE = "seed.lock"
+
+// The kinds of the seed lock holders, see SEED_LOCK_FILE.
+const (
+	lockKindSeed = "seed"
+	lockKindDump = "dump"
+)
+
+// DEFAULT_SEED_LOCK_TIMEOUT is how long a seed waits for the running one.
+const DEFAULT_SEED_LOCK_TIMEOUT = 30 * time.Second
+
+// The lock holder touches the lock file every heartbeat, a file older than
+// staleLockAge was left behind by a crashed process and is taken over.
+const (
+	lockHeartbeat = 5 * time.Second
+	staleLockAge  = 30 * time.Second
+	lockPoll      = 100 * time.Millisecond
+)
+
+// ErrSeedLocked is returned when the seed lock couldn't be acquired in time.
+var ErrSeedLocked = errors.New("another seed is in progress")
+
+// processLocks serializes the seeds of this process per data dir, the lock
+// file alone can't tell two goroutines apart.
+var (
+	processLocksMutex sync.Mutex
+	processLocks      = map[string]chan struct{}{}
+)
+
+func processLock(dataDir string) chan struct{} {
+	processLocksMutex.Lock()
+	defer processLocksMutex.Unlock()
+	lock, ok := processLocks[dataDir]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		processLocks[dataDir] = lock
+	}
+	return lock
+}
+
+// SeedLock is a held seed lock, see AcquireSeedLock.
+type SeedLock struct {
+	path      string
+	process   chan struct{}
+	stop      chan struct{}
+	heartbeat sync.WaitGroup
+	once      sync.Once
+}
+
+// AcquireSeedLock waits up to timeout for both the process wide and the
+// cross process (lock file) seed lock of the app data dir.
+func AcquireSeedLock(app core.App, timeout time.Duration) (*SeedLock, error) {
+	return acquireLock(app, timeout, lockKindSeed)
+}
+
+// AcquireDumpLock takes the seed lock for a dump, see AcquireSeedLock, without
+// holding back the plan writes.
+func AcquireDumpLock(app core.App, timeout time.Duration) (*SeedLock, error) {
+	return acquireLock(app, timeout, lockKindDump)
+}
+
+func acquireLock(app core.App, timeout time.Duration, kind string) (*SeedLock, error) {
+	if timeout <= 0 {
+		timeout = DEFAULT_SEED_LOCK_TIMEOUT
+	}
+	deadline := time.Now().Add(timeout)
+
+	process := processLock(app.DataDir())
+	select {
+	case process <- struct{}{}:
+	case <-time.After(timeout):
+		return nil, ErrSeedLocked
+	}
+
+	path := filepath.Join(app.DataDir(), SEED_LOCK_FILE)
+	for {
+		err := createLockFile(path, kind)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			<-process
+			return nil, err
+		}
+		if isStaleLockFile(path) && takeOverStaleLock(path) {
+			app.Logger().Warn("Took over a stale seed lock", "path", path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			<-process
+			return nil, ErrSeedLocked
+		}
+		time.Sleep(lockPoll)
+	}
+
+	lock := &SeedLock{path: path, process: process, stop: make(chan struct{})}
+	lock.heartbeat.Add(1)
+	go lock.keepAlive()
+	return lock, nil
+}
+
+func createLockFile(path string, kind string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	_, err = fmt.Fprintf(f, "kind=%s pid=%d host=%s since=%s\n", kind, os.Getpid(), hostname, time.Now().UTC().Format(time.RFC3339))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+func isStaleLockFile(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return time.Since(info.ModTime()) > staleLockAge
+}
+
+// takeOverStaleLock removes the lock file if it is still stale, the next
+// createLockFile takes its place. The check and the removal run under a
+// takeover file, two processes finding the same stale lock would otherwise
+// remove the new lock of the other. Returns whether it removed the lock.
+func takeOverStaleLock(path string) bool {
+	guard := path + ".takeover"
+	if err := createLockFile(guard, lockKindSeed); err != nil {
+		// the takeover is held for an instant, an old one was left behind by
+		// a crashed process
+		if errors.Is(err, os.ErrExist) && isStaleLockFile(guard) {
+			os.Remove(guard)
+		}
+		return false
+	}
+	defer os.Remove(guard)
+	if !isStaleLockFile(path) {
+		return false
+	}
+	return os.Remove(path) == nil
+}
+
+func (l *SeedLock) keepAlive() {
+	defer l.heartbeat.Done()
+	ticker := time.NewTicker(lockHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(l.path, now, now)
+		}
+	}
+}
+
+// Release frees the lock, it is safe to call more than once.
+func (l *SeedLock) Release() {
+	l.once.Do(func() {
+		close(l.stop)
+		l.heartbeat.Wait()
+		os.Remove(l.path)
+		<-l.process
+	})
+}
+
+// IsSeeding reports whether a seed or a rollback holds the lock of the app
+// data dir, in this or in another process. The dumps don't count.
+func IsSeeding(app core.App) bool {
+	path := filepath.Join(app.DataDir(), SEED_LOCK_FILE)
+	content, err := os.ReadFile(path)
+	if err != nil || isStaleLockFile(path) {
+		return false
+	}
+	// a lock file being written has no kind yet
+	return !strings.HasPrefix(string(content), "kind="+lockKindDump+" ")
+}
+
+// isDictionaryCollection reports whether the collection is filled by the seed.
+func isDictionaryCollection(collectionId string, app core.App) bool {
+	collection, err := app.FindCachedCollectionByNameOrId(collectionId)
+	if err != nil {
+		return false
+	}
+	for _, dictionary := range dictionaryCollections {
+		if dictionary.name == collection.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// referencesDictionaryChange reports whether the record sets or changes a
+// relation pointing to a dictionary collection.
+func referencesDictionaryChange(app core.App, record *core.Record) bool {
+	for _, field := range record.Collection().Fields {
+		relation, ok := field.(*core.RelationField)
+		if !ok || !isDictionaryCollection(relation.CollectionId, app) {
+			continue
+		}
+		current := record.GetStringSlice(relation.Name)
+		if record.IsNew() {
+			if len(current) > 0 {
+				return true
+			}
+			continue
+		}
+		original := record.Original().GetStringSlice(relation.Name)
+		if len(current) != len(original) {
+			return true
+		}
+		for i := range current {
+			if current[i] != original[i] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BindMaintenanceGuard rejects the plan writes referencing dictionary records
+// while a seed is in progress, the referenced records may be about to change.
+func BindMaintenanceGuard(app core.App) {
+	guard := func(e *core.RecordRequestEvent) error {
+		if IsSeeding(e.App) && referencesDictionaryChange(e.App, e.Record) {
+			return e.Error(http.StatusServiceUnavailable, "The dictionaries are being updated, please try again shortly.", nil)
+		}
+		return e.Next()
+	}
+	app.OnRecordCreateRequest(models.PLANS_COLLECTIONS...).BindFunc(guard)
+	app.OnRecordUpdateRequest(models.PLANS_COLLECTIONS...).BindFunc(guard)
+}
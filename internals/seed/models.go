@@ -1,6 +1,12 @@
 package seed
 
+//go:generate go run ../../cmd/seedgen
+
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
 	"github.com/pocketbase/pocketbase/tools/types"
 )
 
@@ -10,10 +16,59 @@ func (item Icon) GoString() string {
 	return "<icon>"
 }
 
+// File is a single file of a multi file field.
+type File struct {
+	Name    string `json:"name"`
+	Content []byte `json:"content"`
+}
+
+// Files maps a multi file field, tagged with "files", the seed table stores
+// it as json.
+type Files []File
+
+func (item Files) GoString() string {
+	return fmt.Sprintf("<%d files>", len(item))
+}
+
+func (item Files) Names() []string {
+	names := make([]string, len(item))
+	for i, f := range item {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func (item Files) Value() (driver.Value, error) {
+	if item == nil {
+		item = Files{}
+	}
+	return json.Marshal(item)
+}
+
+func (item *Files) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*item = Files{}
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("failed to unmarshal Files value: %v", value)
+	}
+	if len(raw) == 0 {
+		*item = Files{}
+		return nil
+	}
+	return json.Unmarshal(raw, item)
+}
+
 type Special struct {
 	Id      string `db:"id" pb:"id"`
 	Name    string `db:"name" pb:"name"`
-	Substat bool   `db:"substat" pb:"substat"`
+	Substat int    `db:"substat" pb:"substat"`
 	Order   int    `db:"order" pb:"order"`
 }
 
@@ -34,7 +89,7 @@ type CharacterRole struct {
 type ArtifactSet struct {
 	Id           string `db:"id" pb:"id"`
 	Name         string `db:"name" pb:"name"`
-	Rarity       string `db:"rarity" pb:"rarity"`
+	Rarity       int    `db:"rarity" pb:"rarity"`
 	Patch        string `db:"patch" pb:"patch,opt"`
 	Useless      bool   `db:"useless" pb:"useless"`
 	IconFilename string `db:"iconFilename" pb:"icon,fileext"`
@@ -73,6 +128,8 @@ type Weapon struct {
 	Useless      bool   `db:"useless" pb:"useless"`
 	IconContent  Icon   `db:"iconContent" pb:"icon,file"`
 	IconFilename string `db:"iconFilename" pb:"icon,fileext"`
+	// Aliases are the community names the search matches besides the name
+	Aliases types.JSONArray[string] `db:"aliases" pb:"aliases,json,opt"`
 }
 
 type Patch struct {
@@ -91,4 +148,14 @@ type Character struct {
 	Patch        string `db:"patch" pb:"patch,opt"`
 	IconContent  Icon   `db:"iconContent" pb:"icon,file"`
 	IconFilename string `db:"iconFilename" pb:"icon,fileext"`
+	// Aliases are the community names the search matches besides the name
+	Aliases types.JSONArray[string] `db:"aliases" pb:"aliases,json,opt"`
+}
+
+type Translation struct {
+	Id         string `db:"id" pb:"id"`
+	Collection string `db:"collection" pb:"collection,select"`
+	Record     string `db:"record" pb:"record"`
+	Locale     string `db:"locale" pb:"locale"`
+	Name       string `db:"name" pb:"name"`
 }
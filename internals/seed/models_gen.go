@@ -0,0 +1,439 @@
+// Code generated by seedgen from models.go; DO NOT EDIT.
+
+package seed
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+func (item Special) recordId() string {
+	return item.Id
+}
+
+func (item Special) toRecord(record *core.Record) error {
+	record.Set("name", item.Name)
+	record.Set("substat", item.Substat)
+	record.Set("order", item.Order)
+	return nil
+}
+
+func (Special) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["name"] = record.GetString("name")
+	params["substat"] = record.GetInt("substat")
+	params["order"] = record.GetInt("order")
+	return params, nil
+}
+
+func (Special) tableSchema() map[string]string {
+	return map[string]string{
+		"id":      "TEXT NOT NULL PRIMARY KEY",
+		"name":    "TEXT NOT NULL",
+		"substat": "INTEGER NOT NULL",
+		"order":   "INTEGER NOT NULL",
+	}
+}
+
+func (item Element) recordId() string {
+	return item.Id
+}
+
+func (item Element) toRecord(record *core.Record) error {
+	record.Set("name", item.Name)
+	record.Set("color", item.Color)
+	record.Set("inverseTextColor", item.InverseTextColor)
+	iconFile, err := filesystem.NewFileFromBytes(item.IconContent, item.IconFilename)
+	if err != nil {
+		return err
+	}
+	record.Set("icon", iconFile)
+	return nil
+}
+
+func (Element) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["name"] = record.GetString("name")
+	params["color"] = record.GetString("color")
+	params["inverseTextColor"] = record.GetBool("inverseTextColor")
+	iconName, iconContent, err := getFileContent(fsys, record, "icon")
+	if err != nil {
+		return nil, err
+	}
+	params["iconContent"] = iconContent
+	params["iconFilename"] = iconName
+	return params, nil
+}
+
+func (Element) tableSchema() map[string]string {
+	return map[string]string{
+		"id":               "TEXT NOT NULL PRIMARY KEY",
+		"name":             "TEXT NOT NULL",
+		"color":            "TEXT NOT NULL",
+		"inverseTextColor": "BOOL NOT NULL",
+		"iconContent":      "BLOB NOT NULL",
+		"iconFilename":     "TEXT NOT NULL",
+	}
+}
+
+func (item CharacterRole) recordId() string {
+	return item.Id
+}
+
+func (item CharacterRole) toRecord(record *core.Record) error {
+	record.Set("name", item.Name)
+	return nil
+}
+
+func (CharacterRole) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["name"] = record.GetString("name")
+	return params, nil
+}
+
+func (CharacterRole) tableSchema() map[string]string {
+	return map[string]string{
+		"id":   "TEXT NOT NULL PRIMARY KEY",
+		"name": "TEXT NOT NULL",
+	}
+}
+
+func (item ArtifactSet) recordId() string {
+	return item.Id
+}
+
+func (item ArtifactSet) toRecord(record *core.Record) error {
+	record.Set("name", item.Name)
+	record.Set("rarity", item.Rarity)
+	record.Set("patch", item.Patch)
+	record.Set("useless", item.Useless)
+	iconFile, err := filesystem.NewFileFromBytes(item.IconContent, item.IconFilename)
+	if err != nil {
+		return err
+	}
+	record.Set("icon", iconFile)
+	return nil
+}
+
+func (ArtifactSet) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["name"] = record.GetString("name")
+	params["rarity"] = record.GetInt("rarity")
+	params["patch"] = record.GetString("patch")
+	params["useless"] = record.GetBool("useless")
+	iconName, iconContent, err := getFileContent(fsys, record, "icon")
+	if err != nil {
+		return nil, err
+	}
+	params["iconContent"] = iconContent
+	params["iconFilename"] = iconName
+	return params, nil
+}
+
+func (ArtifactSet) tableSchema() map[string]string {
+	return map[string]string{
+		"id":           "TEXT NOT NULL PRIMARY KEY",
+		"name":         "TEXT NOT NULL",
+		"rarity":       "INTEGER NOT NULL",
+		"patch":        "TEXT",
+		"useless":      "BOOL NOT NULL",
+		"iconFilename": "TEXT NOT NULL",
+		"iconContent":  "BLOB NOT NULL",
+	}
+}
+
+func (item ArtifactType) recordId() string {
+	return item.Id
+}
+
+func (item ArtifactType) toRecord(record *core.Record) error {
+	record.Set("name", item.Name)
+	record.Set("specials", item.Specials)
+	record.Set("order", item.Order)
+	iconFile, err := filesystem.NewFileFromBytes(item.IconContent, item.IconFilename)
+	if err != nil {
+		return err
+	}
+	record.Set("icon", iconFile)
+	return nil
+}
+
+func (ArtifactType) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["name"] = record.GetString("name")
+	specialsJSON, err := json.Marshal(record.GetStringSlice("specials"))
+	if err != nil {
+		return nil, err
+	}
+	params["specials"] = specialsJSON
+	params["order"] = record.GetInt("order")
+	iconName, iconContent, err := getFileContent(fsys, record, "icon")
+	if err != nil {
+		return nil, err
+	}
+	params["iconContent"] = iconContent
+	params["iconFilename"] = iconName
+	return params, nil
+}
+
+func (ArtifactType) tableSchema() map[string]string {
+	return map[string]string{
+		"id":           "TEXT NOT NULL PRIMARY KEY",
+		"name":         "TEXT NOT NULL",
+		"specials":     "TEXT NOT NULL",
+		"order":        "INTEGER NOT NULL",
+		"iconContent":  "BLOB NOT NULL",
+		"iconFilename": "TEXT NOT NULL",
+	}
+}
+
+func (item DomainOfBlessing) recordId() string {
+	return item.Id
+}
+
+func (item DomainOfBlessing) toRecord(record *core.Record) error {
+	record.Set("name", item.Name)
+	record.Set("artifactSets", item.ArtifactSets)
+	return nil
+}
+
+func (DomainOfBlessing) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["name"] = record.GetString("name")
+	artifactSetsJSON, err := json.Marshal(record.GetStringSlice("artifactSets"))
+	if err != nil {
+		return nil, err
+	}
+	params["artifactSets"] = artifactSetsJSON
+	return params, nil
+}
+
+func (DomainOfBlessing) tableSchema() map[string]string {
+	return map[string]string{
+		"id":           "TEXT NOT NULL PRIMARY KEY",
+		"name":         "TEXT NOT NULL",
+		"artifactSets": "TEXT NOT NULL",
+	}
+}
+
+func (item WeaponType) recordId() string {
+	return item.Id
+}
+
+func (item WeaponType) toRecord(record *core.Record) error {
+	record.Set("name", item.Name)
+	iconFile, err := filesystem.NewFileFromBytes(item.IconContent, item.IconFilename)
+	if err != nil {
+		return err
+	}
+	record.Set("icon", iconFile)
+	return nil
+}
+
+func (WeaponType) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["name"] = record.GetString("name")
+	iconName, iconContent, err := getFileContent(fsys, record, "icon")
+	if err != nil {
+		return nil, err
+	}
+	params["iconContent"] = iconContent
+	params["iconFilename"] = iconName
+	return params, nil
+}
+
+func (WeaponType) tableSchema() map[string]string {
+	return map[string]string{
+		"id":           "TEXT NOT NULL PRIMARY KEY",
+		"name":         "TEXT NOT NULL",
+		"iconContent":  "BLOB NOT NULL",
+		"iconFilename": "TEXT NOT NULL",
+	}
+}
+
+func (item Weapon) recordId() string {
+	return item.Id
+}
+
+func (item Weapon) toRecord(record *core.Record) error {
+	record.Set("name", item.Name)
+	record.Set("rarity", item.Rarity)
+	record.Set("weaponType", item.WeaponType)
+	record.Set("special", item.Special)
+	record.Set("patch", item.Patch)
+	record.Set("useless", item.Useless)
+	iconFile, err := filesystem.NewFileFromBytes(item.IconContent, item.IconFilename)
+	if err != nil {
+		return err
+	}
+	record.Set("icon", iconFile)
+	record.Set("aliases", item.Aliases)
+	return nil
+}
+
+func (Weapon) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["name"] = record.GetString("name")
+	params["rarity"] = record.GetInt("rarity")
+	params["weaponType"] = record.GetString("weaponType")
+	params["special"] = record.GetString("special")
+	params["patch"] = record.GetString("patch")
+	params["useless"] = record.GetBool("useless")
+	iconName, iconContent, err := getFileContent(fsys, record, "icon")
+	if err != nil {
+		return nil, err
+	}
+	params["iconContent"] = iconContent
+	params["iconFilename"] = iconName
+	aliasesJSON, err := json.Marshal(record.GetStringSlice("aliases"))
+	if err != nil {
+		return nil, err
+	}
+	params["aliases"] = aliasesJSON
+	return params, nil
+}
+
+func (Weapon) tableSchema() map[string]string {
+	return map[string]string{
+		"id":           "TEXT NOT NULL PRIMARY KEY",
+		"name":         "TEXT NOT NULL",
+		"rarity":       "INTEGER NOT NULL",
+		"weaponType":   "TEXT NOT NULL",
+		"special":      "TEXT NOT NULL",
+		"patch":        "TEXT",
+		"useless":      "BOOL NOT NULL",
+		"iconContent":  "BLOB NOT NULL",
+		"iconFilename": "TEXT NOT NULL",
+		"aliases":      "TEXT",
+	}
+}
+
+func (item Patch) recordId() string {
+	return item.Id
+}
+
+func (item Patch) toRecord(record *core.Record) error {
+	record.Set("major", item.Major)
+	record.Set("patch", item.Patch)
+	return nil
+}
+
+func (Patch) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["major"] = record.GetInt("major")
+	params["patch"] = record.GetInt("patch")
+	return params, nil
+}
+
+func (Patch) tableSchema() map[string]string {
+	return map[string]string{
+		"id":    "TEXT NOT NULL PRIMARY KEY",
+		"major": "INTEGER NOT NULL",
+		"patch": "INTEGER",
+	}
+}
+
+func (item Character) recordId() string {
+	return item.Id
+}
+
+func (item Character) toRecord(record *core.Record) error {
+	record.Set("name", item.Name)
+	record.Set("rarity", item.Rarity)
+	record.Set("element", item.Element)
+	record.Set("weaponType", item.WeaponType)
+	record.Set("special", item.Special)
+	record.Set("patch", item.Patch)
+	iconFile, err := filesystem.NewFileFromBytes(item.IconContent, item.IconFilename)
+	if err != nil {
+		return err
+	}
+	record.Set("icon", iconFile)
+	record.Set("aliases", item.Aliases)
+	return nil
+}
+
+func (Character) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["name"] = record.GetString("name")
+	params["rarity"] = record.GetInt("rarity")
+	params["element"] = record.GetString("element")
+	params["weaponType"] = record.GetString("weaponType")
+	params["special"] = record.GetString("special")
+	params["patch"] = record.GetString("patch")
+	iconName, iconContent, err := getFileContent(fsys, record, "icon")
+	if err != nil {
+		return nil, err
+	}
+	params["iconContent"] = iconContent
+	params["iconFilename"] = iconName
+	aliasesJSON, err := json.Marshal(record.GetStringSlice("aliases"))
+	if err != nil {
+		return nil, err
+	}
+	params["aliases"] = aliasesJSON
+	return params, nil
+}
+
+func (Character) tableSchema() map[string]string {
+	return map[string]string{
+		"id":           "TEXT NOT NULL PRIMARY KEY",
+		"name":         "TEXT NOT NULL",
+		"rarity":       "INTEGER NOT NULL",
+		"element":      "TEXT NOT NULL",
+		"weaponType":   "TEXT NOT NULL",
+		"special":      "TEXT NOT NULL",
+		"patch":        "TEXT",
+		"iconContent":  "BLOB NOT NULL",
+		"iconFilename": "TEXT NOT NULL",
+		"aliases":      "TEXT",
+	}
+}
+
+func (item Translation) recordId() string {
+	return item.Id
+}
+
+func (item Translation) toRecord(record *core.Record) error {
+	if err := checkSelectValues(record.Collection(), "collection", item.Collection); err != nil {
+		return err
+	}
+	record.Set("collection", item.Collection)
+	record.Set("record", item.Record)
+	record.Set("locale", item.Locale)
+	record.Set("name", item.Name)
+	return nil
+}
+
+func (Translation) fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error) {
+	params := dbx.Params{}
+	params["id"] = record.GetString("id")
+	params["collection"] = record.GetString("collection")
+	params["record"] = record.GetString("record")
+	params["locale"] = record.GetString("locale")
+	params["name"] = record.GetString("name")
+	return params, nil
+}
+
+func (Translation) tableSchema() map[string]string {
+	return map[string]string{
+		"id":         "TEXT NOT NULL PRIMARY KEY",
+		"collection": "TEXT NOT NULL",
+		"record":     "TEXT NOT NULL",
+		"locale":     "TEXT NOT NULL",
+		"name":       "TEXT NOT NULL",
+	}
+}
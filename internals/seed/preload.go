@@ -1,7 +1,12 @@
 package seed
 
 import (
+	"errors"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pocketbase/pocketbase/core"
 
@@ -15,29 +20,137 @@ const (
 	PRELOAD_SEED_NOTE = "seed.note"
 )
 
+// PRELOAD_DIR_ENV overrides the preload seed location, same as --preload-dir.
+const PRELOAD_DIR_ENV = "GBP_PRELOAD_DIR"
+
+// preloadSource is a located preload seed. The seed file has to be a regular
+// file for sqlite, so an embedded one is copied out on demand.
+type preloadSource struct {
+	name string
+	fsys fs.FS
+	// dir is the on disk directory of fsys, empty for the embedded seed
+	dir string
+}
+
+func (p *preloadSource) hash() (string, error) {
+	if hash, err := fs.ReadFile(p.fsys, PRELOAD_SEED_HASH); err == nil {
+		return strings.TrimSpace(string(hash)), nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	f, err := p.fsys.Open(PRELOAD_SEED_FILE)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashSeedReader(f)
+}
+
+func (p *preloadSource) note() string {
+	note, _ := fs.ReadFile(p.fsys, PRELOAD_SEED_NOTE)
+	return string(note)
+}
+
+// seedPath returns an on disk path of the seed file and its cleanup.
+func (p *preloadSource) seedPath(tmpDir string) (string, func(), error) {
+	if p.dir != "" {
+		return filepath.Join(p.dir, PRELOAD_SEED_FILE), func() {}, nil
+	}
+	src, err := p.fsys.Open(PRELOAD_SEED_FILE)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+	tmpFile, err := os.CreateTemp(tmpDir, "*-"+PRELOAD_SEED_FILE)
+	if err != nil {
+		return "", nil, err
+	}
+	tmpPath := tmpFile.Name()
+	cleanup := func() { os.Remove(tmpPath) }
+	if _, err := io.Copy(tmpFile, src); err != nil {
+		tmpFile.Close()
+		cleanup()
+		return "", nil, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tmpPath, cleanup, nil
+}
+
+func dirPreload(dir string) *preloadSource {
+	if _, err := os.Stat(filepath.Join(dir, PRELOAD_SEED_FILE)); err != nil {
+		return nil
+	}
+	return &preloadSource{name: dir, fsys: os.DirFS(dir), dir: dir}
+}
+
+// findPreload resolves the preload seed: an explicitly configured directory
+// first, then the seed embedded at build time and finally the working and
+// the executable directories. Returns nil when there is none.
+func findPreload(dir string) (*preloadSource, error) {
+	if dir != "" {
+		if source := dirPreload(dir); source != nil {
+			return source, nil
+		}
+		return nil, errors.New("no " + PRELOAD_SEED_FILE + " in the preload dir " + dir)
+	}
+	if embedded := embeddedPreload(); embedded != nil {
+		return &preloadSource{name: "embedded", fsys: embedded}, nil
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if source := dirPreload(wd); source != nil {
+			return source, nil
+		}
+	}
+	if exe, err := os.Executable(); err == nil {
+		if source := dirPreload(filepath.Dir(exe)); source != nil {
+			return source, nil
+		}
+	}
+	return nil, nil
+}
+
 // UpdateFromPreload applies the bundled seed file unless its hash already
 // matches the current dictionary version or the latest stored dump.
-func UpdateFromPreload(app core.App, latestDumpCache *models.LatestDbDumpCache) error {
-	app.Logger().Debug("Checking " + PRELOAD_SEED_HASH)
-	hash, err := os.ReadFile(PRELOAD_SEED_HASH)
+//
+// preloadDir overrides the seed location, see findPreload for the defaults.
+// A build without any preload seed is not an error.
+func UpdateFromPreload(app core.App, latestDumpCache *models.LatestDbDumpCache, preloadDir string) error {
+	source, err := findPreload(preloadDir)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		app.Logger().Debug("No preload seed found")
+		return nil
+	}
+	app.Logger().Debug("Checking preload seed", "source", source.name)
+	hash, err := source.hash()
 	if err != nil {
 		return err
 	}
-	note, _ := os.ReadFile(PRELOAD_SEED_NOTE)
 
 	dictionaryVersion, err := models.FindAppSettingsByKey(app, "dictionaryVersion")
-	if err == nil && dictionaryVersion != nil && dictionaryVersion.Value() == string(hash) {
+	if err == nil && dictionaryVersion != nil && dictionaryVersion.Value() == hash {
 		app.Logger().Debug("No seed update required")
 		return nil
 	}
 	latestDump, err := latestDumpCache.Get(app)
-	if err == nil && latestDump.Hash() == string(hash) {
+	if err == nil && latestDump.Hash() == hash {
 		app.Logger().Debug("No seed update required")
 		return nil
 	}
 
-	if err := SaveDump(app, PRELOAD_SEED_FILE, string(note)); err != nil {
+	path, cleanup, err := source.seedPath(app.DataDir())
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := SaveDump(app, path, source.note()); err != nil {
 		return err
 	}
-	return Seed(app, PRELOAD_SEED_FILE)
+	return Seed(app, path)
 }
@@ -0,0 +1,25 @@
+//go:build embed_seed
+
+package seed
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// The release builds bundle the preload seed into the binary, build.nu copies
+// the seed files into the preload dir before building with -tags embed_seed.
+//
+//go:embed preload
+var preloadFS embed.FS
+
+func embeddedPreload() fs.FS {
+	fsys, err := fs.Sub(preloadFS, "preload")
+	if err != nil {
+		panic(err)
+	}
+	if _, err := fs.Stat(fsys, PRELOAD_SEED_FILE); err != nil {
+		return nil
+	}
+	return fsys
+}
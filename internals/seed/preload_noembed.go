@@ -0,0 +1,9 @@
+//go:build !embed_seed
+
+package seed
+
+import "io/fs"
+
+func embeddedPreload() fs.FS {
+	return nil
+}
@@ -0,0 +1,205 @@
+package seed
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/spf13/cobra"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// Upstream pull configuration, the flags of the same name take precedence.
+const (
+	SEED_UPSTREAM_ENV      = "GBP_SEED_UPSTREAM"
+	SEED_PULL_SCHEDULE_ENV = "GBP_SEED_PULL_SCHEDULE"
+)
+
+// SEED_PULL_STATUS_KEY is the app settings key holding the outcome of the
+// last upstream pull, so that the admin can see it in the dashboard.
+const SEED_PULL_STATUS_KEY = "seedPullStatus"
+
+const seedPullJobId = "seedPull"
+
+var pullClient = &http.Client{Timeout: 10 * time.Minute}
+
+// PullStatus is the json stored under SEED_PULL_STATUS_KEY.
+type PullStatus struct {
+	Upstream string         `json:"upstream"`
+	Time     types.DateTime `json:"time"`
+	Hash     string         `json:"hash,omitempty"`
+	Updated  bool           `json:"updated"`
+	Error    string         `json:"error,omitempty"`
+}
+
+type upstreamDump struct {
+	Hash  string `json:"hash"`
+	Notes string `json:"notes"`
+}
+
+func upstreamUrl(baseUrl string, path string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(baseUrl))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported upstream url %q", baseUrl)
+	}
+	return u.JoinPath(path).String(), nil
+}
+
+func fetchUpstreamDump(baseUrl string) (*upstreamDump, error) {
+	latestUrl, err := upstreamUrl(baseUrl, "/api/dump/latest")
+	if err != nil {
+		return nil, err
+	}
+	res, err := pullClient.Get(latestUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with %s", latestUrl, res.Status)
+	}
+	dump := &upstreamDump{}
+	if err := json.NewDecoder(res.Body).Decode(dump); err != nil {
+		return nil, err
+	}
+	if dump.Hash == "" {
+		return nil, fmt.Errorf("%s returned an empty hash", latestUrl)
+	}
+	return dump, nil
+}
+
+// downloadUpstreamDump stores the upstream seed file in dest. The compressed
+// variants are kept as they are, Seed and GetSeedHash read them transparently.
+func downloadUpstreamDump(baseUrl string, dest string) error {
+	seedUrl, err := upstreamUrl(baseUrl, "/api/dump/latest_seed.db")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, seedUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept-Encoding", "zstd, gzip")
+	res, err := pullClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with %s", seedUrl, res.Status)
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, res.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Pull applies the latest dump of the upstream instance at baseUrl unless the
+// local dictionary is already at its version. Reports whether it seeded.
+func Pull(app core.App, baseUrl string) (bool, error) {
+	app.Logger().Info("Pulling seed", "upstream", baseUrl)
+	upstream, err := fetchUpstreamDump(baseUrl)
+	if err != nil {
+		return false, err
+	}
+
+	dictionaryVersion, err := models.FindAppSettingsByKey(app, "dictionaryVersion")
+	if err == nil && dictionaryVersion.Value() == upstream.Hash {
+		app.Logger().Debug("No seed update required")
+		return false, nil
+	}
+
+	// the dump could already be stored, e.g. after a rollback
+	if dump, err := models.FindDbDumpByHash(app, upstream.Hash); err == nil {
+		return true, Seed(app, dump.DumpPath(app))
+	} else if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	tmpFile, err := os.CreateTemp(app.DataDir(), "*-pull.db")
+	if err != nil {
+		return false, err
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	if err := downloadUpstreamDump(baseUrl, tmpPath); err != nil {
+		return false, err
+	}
+	hash, err := GetSeedHash(tmpPath)
+	if err != nil {
+		return false, err
+	}
+	if hash != upstream.Hash {
+		return false, fmt.Errorf("downloaded seed hash %s doesn't match the upstream %s", hash, upstream.Hash)
+	}
+
+	if err := SaveDump(app, tmpPath, upstream.Notes); err != nil {
+		return false, err
+	}
+	return true, Seed(app, tmpPath)
+}
+
+// PullAndRecord runs Pull and stores its outcome under SEED_PULL_STATUS_KEY.
+func PullAndRecord(app core.App, baseUrl string) error {
+	updated, pullErr := Pull(app, baseUrl)
+	status := PullStatus{
+		Upstream: baseUrl,
+		Time:     types.NowDateTime(),
+		Updated:  updated,
+	}
+	if pullErr != nil {
+		status.Error = pullErr.Error()
+	} else if dictionaryVersion, err := models.FindAppSettingsByKey(app, "dictionaryVersion"); err == nil {
+		status.Hash = dictionaryVersion.Value()
+	}
+	raw, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	if _, err := models.UpsertAppSettings(app, SEED_PULL_STATUS_KEY, string(raw)); err != nil {
+		return err
+	}
+	return pullErr
+}
+
+// BindPullJob schedules the upstream pull with a cron expression.
+func BindPullJob(app core.App, baseUrl string, schedule string) error {
+	if baseUrl == "" || schedule == "" {
+		return nil
+	}
+	return app.Cron().Add(seedPullJobId, schedule, func() {
+		if err := PullAndRecord(app, baseUrl); err != nil {
+			app.Logger().Error("Seed pull failed", "upstream", baseUrl, "error", err)
+		}
+	})
+}
+
+func NewCobraSeedPullCommand(app core.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull base_url",
+		Short: "Pull the latest seed from an upstream instance",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return PullAndRecord(app, args[0])
+		},
+	}
+}
@@ -0,0 +1,255 @@
+package seed
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/spf13/cobra"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// DICTIONARY_VERSION_HISTORY_KEY is the app setting listing the dictionary
+// versions in the order they were applied, the current one last.
+const DICTIONARY_VERSION_HISTORY_KEY = "dictionaryVersionHistory"
+
+// maxDictionaryVersionHistory bounds the kept history.
+const maxDictionaryVersionHistory = 50
+
+// ErrNoRollback is returned when there is no previous dictionary version to
+// roll back to, or its dump isn't stored anymore.
+var ErrNoRollback = errors.New("nothing to roll back to")
+
+// ErrRollbackReferenced is returned when the records a rollback would delete
+// are referenced by user data, like the plans.
+var ErrRollbackReferenced = errors.New("the records to delete are referenced")
+
+// DictionaryVersionEntry is an applied dictionary version.
+type DictionaryVersionEntry struct {
+	Hash      string         `json:"hash"`
+	AppliedAt types.DateTime `json:"appliedAt"`
+}
+
+// DictionaryVersionHistory returns the applied dictionary versions, oldest
+// first.
+func DictionaryVersionHistory(app core.App) ([]DictionaryVersionEntry, error) {
+	history := []DictionaryVersionEntry{}
+	setting, err := models.FindAppSettingsByKey(app, DICTIONARY_VERSION_HISTORY_KEY)
+	if err == sql.ErrNoRows {
+		return history, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(setting.Value()), &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func saveDictionaryVersionHistory(app core.App, history []DictionaryVersionEntry) error {
+	if len(history) > maxDictionaryVersionHistory {
+		history = history[len(history)-maxDictionaryVersionHistory:]
+	}
+	raw, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	_, err = models.UpsertAppSettings(app, DICTIONARY_VERSION_HISTORY_KEY, string(raw))
+	return err
+}
+
+// recordDictionaryVersion appends the hash to the history, the version
+// applied before the history existed is recorded first.
+func recordDictionaryVersion(app core.App, hash string) error {
+	history, err := DictionaryVersionHistory(app)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		if current, err := models.FindAppSettingsByKey(app, "dictionaryVersion"); err == nil && current.Value() != "" && current.Value() != hash {
+			history = append(history, DictionaryVersionEntry{Hash: current.Value()})
+		}
+	}
+	if len(history) > 0 && history[len(history)-1].Hash == hash {
+		return nil
+	}
+	history = append(history, DictionaryVersionEntry{Hash: hash, AppliedAt: types.NowDateTime()})
+	return saveDictionaryVersionHistory(app, history)
+}
+
+// RollbackResult describes an applied rollback.
+type RollbackResult struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Deleted lists per collection the records the rolled back dump added
+	Deleted map[string][]ItemRef `json:"deleted"`
+}
+
+// RollbackOptions tune a Rollback run.
+type RollbackOptions struct {
+	Progress Progress
+	// LockTimeout bounds the wait for a running seed,
+	// DEFAULT_SEED_LOCK_TIMEOUT when zero
+	LockTimeout time.Duration
+}
+
+// findAddedReferences lists the records outside of the dictionaries that
+// reference the records the rollback would delete, deleting them would
+// cascade to the plans or drop them from their relations.
+func findAddedReferences(app core.App, diff *DumpDiff) ([]string, error) {
+	collections, err := app.FindAllCollections(core.CollectionTypeBase, core.CollectionTypeAuth)
+	if err != nil {
+		return nil, err
+	}
+	references := []string{}
+	for _, dictionary := range dictionaryCollections {
+		collectionDiff := diff.Collection(dictionary.name)
+		if collectionDiff == nil || len(collectionDiff.Added) == 0 {
+			continue
+		}
+		target, err := app.FindCachedCollectionByNameOrId(dictionary.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, collection := range collections {
+			if slices.Contains(DictionaryCollections(), collection.Name) {
+				continue
+			}
+			for _, field := range collection.Fields {
+				relation, ok := field.(*core.RelationField)
+				if !ok || relation.CollectionId != target.Id {
+					continue
+				}
+				for _, item := range collectionDiff.Added {
+					records, err := app.FindRecordsByFilter(collection.Name, relation.Name+" ?= {:id}", "", 0, 0, dbx.Params{"id": item.Id})
+					if err != nil {
+						return nil, err
+					}
+					for _, record := range records {
+						references = append(references, fmt.Sprintf("%s %s (%s) by %s.%s %s", dictionary.name, item.Name, item.Id, collection.Name, relation.Name, record.Id))
+					}
+				}
+			}
+		}
+	}
+	return references, nil
+}
+
+// Rollback re-seeds the dump applied before the current dictionary version
+// and deletes the records the current dump added, both dumps have to be
+// stored. The current version is dropped from the history, so a second
+// rollback goes one version further back. It is refused with
+// ErrRollbackReferenced while the added records are referenced outside of the
+// dictionaries, the user data is never deleted.
+func Rollback(app core.App, opts RollbackOptions) (*RollbackResult, error) {
+	lock, err := AcquireSeedLock(app, opts.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	history, err := DictionaryVersionHistory(app)
+	if err != nil {
+		return nil, err
+	}
+	current, err := models.FindAppSettingsByKey(app, "dictionaryVersion")
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == sql.ErrNoRows || len(history) < 2 || history[len(history)-1].Hash != current.Value() {
+		return nil, fmt.Errorf("%w: no previous dictionary version recorded", ErrNoRollback)
+	}
+	previousEntry := history[len(history)-2]
+
+	currentDump, err := models.FindDbDumpByHash(app, current.Value())
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: the dump of the current version %s isn't stored", ErrNoRollback, current.Value())
+	} else if err != nil {
+		return nil, err
+	}
+	previousDump, err := models.FindDbDumpByHash(app, previousEntry.Hash)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: the dump of the previous version %s isn't stored", ErrNoRollback, previousEntry.Hash)
+	} else if err != nil {
+		return nil, err
+	}
+
+	diff, err := DiffDumps(previousDump.DumpPath(app), currentDump.DumpPath(app))
+	if err != nil {
+		return nil, err
+	}
+	// the lock keeps the plan writes referencing the dictionaries out until
+	// the rollback is done, see BindMaintenanceGuard
+	references, err := findAddedReferences(app, diff)
+	if err != nil {
+		return nil, err
+	}
+	if len(references) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRollbackReferenced, strings.Join(references, ", "))
+	}
+
+	result := &RollbackResult{From: current.Value(), To: previousEntry.Hash, Deleted: map[string][]ItemRef{}}
+	app.Logger().Info("Rolling back the dictionaries", "from", result.From, "to", result.To)
+	err = seedFile(app, previousDump.DumpPath(app), dictionaryCollections, opts.Progress, func(txApp core.App) error {
+		// the dependants go first
+		for _, dictionary := range slices.Backward(dictionaryCollections) {
+			collectionDiff := diff.Collection(dictionary.name)
+			if collectionDiff == nil {
+				continue
+			}
+			for _, item := range collectionDiff.Added {
+				record, err := txApp.FindRecordById(dictionary.name, item.Id)
+				if err == sql.ErrNoRows {
+					continue
+				} else if err != nil {
+					return err
+				}
+				if err := txApp.Delete(record); err != nil {
+					return fmt.Errorf("%s %s: %w", dictionary.name, item.Id, err)
+				}
+				result.Deleted[dictionary.name] = append(result.Deleted[dictionary.name], item)
+			}
+		}
+		if _, err := models.UpsertAppSettings(txApp, "dictionaryVersion", previousEntry.Hash); err != nil {
+			return err
+		}
+		return saveDictionaryVersionHistory(txApp, history[:len(history)-1])
+	})
+	if err != nil {
+		return nil, err
+	}
+	app.Logger().Info("Rollback Completed")
+	return result, nil
+}
+
+func NewCobraDumpRollbackCommand(app core.App) *cobra.Command {
+	var lockTimeout time.Duration
+	command := &cobra.Command{
+		Use:   "rollback",
+		Short: "Re-seed the dump applied before the current dictionary version",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := Rollback(app, RollbackOptions{LockTimeout: lockTimeout})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Rolled back from %s to %s\n", result.From, result.To)
+			for _, dictionary := range dictionaryCollections {
+				for _, item := range result.Deleted[dictionary.name] {
+					fmt.Fprintf(cmd.OutOrStdout(), "  deleted %s %s (%s)\n", dictionary.name, item.Name, item.Id)
+				}
+			}
+			return nil
+		},
+	}
+	command.Flags().DurationVar(&lockTimeout, "lock-timeout", DEFAULT_SEED_LOCK_TIMEOUT, "how long to wait for a seed already in progress")
+	return command
+}
@@ -0,0 +1,167 @@
+package seed
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// SchemaIssue is a mismatch between a seed struct field and the migrated
+// collection field it is mapped to.
+type SchemaIssue struct {
+	Collection string `json:"collection"`
+	Field      string `json:"field"`
+	Problem    string `json:"problem"`
+}
+
+func (i SchemaIssue) String() string {
+	if i.Field == "" {
+		return fmt.Sprintf("%s: %s", i.Collection, i.Problem)
+	}
+	return fmt.Sprintf("%s.%s: %s", i.Collection, i.Field, i.Problem)
+}
+
+// fieldRequired reads the Required option the field types share without
+// exposing it through core.Field.
+func fieldRequired(field core.Field) bool {
+	rv := reflect.ValueOf(field)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	required := rv.FieldByName("Required")
+	return required.IsValid() && required.Kind() == reflect.Bool && required.Bool()
+}
+
+func describeGoType(fd pbFieldInfo) string {
+	switch {
+	case fd.isFile:
+		return "single file"
+	case fd.isFiles:
+		return "multi file"
+	default:
+		return fd.goType.String()
+	}
+}
+
+// checkFieldKind reports the problems of mapping the struct field to the
+// collection field, position is the index of the collection in the seeding
+// order.
+func checkFieldKind(app core.App, fd pbFieldInfo, field core.Field, position int) []string {
+	kind := fd.goType.Kind()
+	isString := kind == reflect.String && !fd.isFile && !fd.isFiles
+	isInt := kind == reflect.Int || kind == reflect.Int64
+	isFloat := kind == reflect.Float32 || kind == reflect.Float64
+	mismatch := func() []string {
+		return []string{fmt.Sprintf("%s field mapped to %s", field.Type(), describeGoType(fd))}
+	}
+
+	switch f := field.(type) {
+	case *core.TextField, *core.EmailField, *core.URLField, *core.EditorField:
+		if !isString {
+			return mismatch()
+		}
+	case *core.NumberField:
+		if !isInt && !isFloat || (f.OnlyInt && isFloat) {
+			return mismatch()
+		}
+	case *core.BoolField:
+		if kind != reflect.Bool {
+			return mismatch()
+		}
+	case *core.DateField, *core.AutodateField:
+		if fd.goType != dateTimeType {
+			return mismatch()
+		}
+	case *core.JSONField:
+		if !fd.isJSON {
+			return mismatch()
+		}
+	case *core.FileField:
+		if f.IsMultiple() && !fd.isFiles || !f.IsMultiple() && !fd.isFile {
+			return mismatch()
+		}
+	case *core.SelectField:
+		if f.IsMultiple() && !isStringSlice(fd.goType) || !f.IsMultiple() && !isString {
+			return mismatch()
+		}
+	case *core.RelationField:
+		if f.IsMultiple() && !isStringSlice(fd.goType) || !f.IsMultiple() && !isString {
+			return mismatch()
+		}
+		target, err := app.FindCachedCollectionByNameOrId(f.CollectionId)
+		if err != nil {
+			return []string{fmt.Sprintf("relation target %s not found", f.CollectionId)}
+		}
+		targetPosition := slices.IndexFunc(dictionaryCollections, func(d dictionaryCollection) bool { return d.name == target.Name })
+		if targetPosition < 0 {
+			return []string{fmt.Sprintf("relation target %s isn't a dictionary collection", target.Name)}
+		}
+		if targetPosition >= position {
+			return []string{fmt.Sprintf("relation target %s is seeded after the collection", target.Name)}
+		}
+	default:
+		return []string{fmt.Sprintf("unsupported %s field", field.Type())}
+	}
+	return nil
+}
+
+// CheckSchema compares every dictionary seed struct against the migrated
+// collection it seeds: the mapped fields have to exist with a compatible
+// type and requirement, and the required collection fields have to be
+// mapped.
+func CheckSchema(app core.App) []SchemaIssue {
+	issues := []SchemaIssue{}
+	for position, dictionary := range dictionaryCollections {
+		collection, err := app.FindCachedCollectionByNameOrId(dictionary.name)
+		if err != nil {
+			issues = append(issues, SchemaIssue{Collection: dictionary.name, Problem: "collection not found"})
+			continue
+		}
+		mapped := map[string]bool{}
+		for _, fd := range dictionary.fields() {
+			if fd.isFileExt {
+				continue
+			}
+			mapped[fd.pbKey] = true
+			field := collection.Fields.GetByName(fd.pbKey)
+			if field == nil {
+				issues = append(issues, SchemaIssue{Collection: dictionary.name, Field: fd.pbKey, Problem: "mapped field missing from the collection"})
+				continue
+			}
+			if fd.isPK {
+				continue
+			}
+			for _, problem := range checkFieldKind(app, fd, field, position) {
+				issues = append(issues, SchemaIssue{Collection: dictionary.name, Field: fd.pbKey, Problem: problem})
+			}
+			if fd.isOpt && fieldRequired(field) {
+				issues = append(issues, SchemaIssue{Collection: dictionary.name, Field: fd.pbKey, Problem: "required by the collection but optional in the seed"})
+			}
+		}
+		for _, field := range collection.Fields {
+			if mapped[field.GetName()] || field.GetSystem() {
+				continue
+			}
+			if _, ok := field.(*core.AutodateField); ok {
+				continue
+			}
+			if fieldRequired(field) {
+				issues = append(issues, SchemaIssue{Collection: dictionary.name, Field: field.GetName(), Problem: "required by the collection but not seeded"})
+			}
+		}
+	}
+	return issues
+}
+
+// BindSchemaCheck logs the schema issues as warnings once the app serves, a
+// mismatch otherwise only shows when a seed fails or silently drops a field.
+func BindSchemaCheck(app core.App) {
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		for _, issue := range CheckSchema(e.App) {
+			e.App.Logger().Warn("Seed schema mismatch", "collection", issue.Collection, "field", issue.Field, "problem", issue.Problem)
+		}
+		return e.Next()
+	})
+}
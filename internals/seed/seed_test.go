@@ -2,13 +2,26 @@ package seed_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/types"
 
+	"github.com/qxuken/gbp/internals/icons"
 	"github.com/qxuken/gbp/internals/models"
 	"github.com/qxuken/gbp/internals/seed"
 	"github.com/qxuken/gbp/internals/testutil"
@@ -45,6 +58,17 @@ func fileContent(t testing.TB, app core.App, record *core.Record, field string)
 func TestDumpSeedRoundTrip(t *testing.T) {
 	source := testutil.NewTestApp(t)
 	testutil.SeedDictionaries(t, source)
+	testutil.CreateRecord(t, source, models.TRANSLATIONS_COLLECTION_NAME, "trdilucja000000", map[string]any{
+		"collection": models.CHARACTERS_COLLECTION_NAME, "record": "characterdiluc0", "locale": "ja", "name": "ディルック",
+	})
+	sourceCharacter, err := source.FindRecordById(models.CHARACTERS_COLLECTION_NAME, "characterdiluc0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceCharacter.Set("aliases", []string{"Darknight Hero", "Tomato"})
+	if err := source.Save(sourceCharacter); err != nil {
+		t.Fatal(err)
+	}
 
 	dumpPath := filepath.Join(t.TempDir(), "seed.db")
 	if err := seed.Dump(source, dumpPath, "round trip notes"); err != nil {
@@ -70,6 +94,7 @@ func TestDumpSeedRoundTrip(t *testing.T) {
 		models.DOMAINS_OF_BLESSING_COLLECTION_NAME: 1,
 		models.WEAPONS_COLLECTION_NAME:             1,
 		models.CHARACTERS_COLLECTION_NAME:          1,
+		models.TRANSLATIONS_COLLECTION_NAME:        1,
 	}
 	for collectionName, expected := range expectedCounts {
 		records, err := target.FindAllRecords(collectionName)
@@ -91,6 +116,9 @@ func TestDumpSeedRoundTrip(t *testing.T) {
 	if v := character.GetInt("rarity"); v != 5 {
 		t.Errorf("character rarity: expected 5, got %d", v)
 	}
+	if v := character.GetStringSlice("aliases"); !slices.Equal(v, []string{"Darknight Hero", "Tomato"}) {
+		t.Errorf("character aliases: unexpected %v", v)
+	}
 	for field, expected := range map[string]string{
 		"element":    "elementpyro0000",
 		"weaponType": "weapontypesword",
@@ -102,6 +130,14 @@ func TestDumpSeedRoundTrip(t *testing.T) {
 		}
 	}
 
+	translation, err := target.FindRecordById(models.TRANSLATIONS_COLLECTION_NAME, "trdilucja000000")
+	if err != nil {
+		t.Fatalf("translation: %v", err)
+	}
+	if v := translation.GetString("name"); translation.GetString("locale") != "ja" || v != "ディルック" {
+		t.Errorf("translation: unexpected %s %q", translation.GetString("locale"), v)
+	}
+
 	element, err := target.FindRecordById(models.ELEMENTS_COLLECTION_NAME, "elementpyro0000")
 	if err != nil {
 		t.Fatalf("element: %v", err)
@@ -213,3 +249,1021 @@ func TestGetSeedHash(t *testing.T) {
 		t.Error("expected an error for a missing file")
 	}
 }
+
+// TestCompressedDump checks that a compressed dump keeps the hash of its raw
+// content and can be seeded back without decompressing it first.
+func TestCompressedDump(t *testing.T) {
+	for _, compression := range []seed.Compression{seed.COMPRESSION_GZIP, seed.COMPRESSION_ZSTD} {
+		t.Run(string(compression), func(t *testing.T) {
+			source := testutil.NewTestApp(t)
+			testutil.SeedDictionaries(t, source)
+
+			dir := t.TempDir()
+			rawPath := filepath.Join(dir, "raw.db")
+			if err := seed.Dump(source, rawPath, ""); err != nil {
+				t.Fatalf("dump: %v", err)
+			}
+			rawHash, err := seed.GetSeedHash(rawPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compressedPath := filepath.Join(dir, "compressed.db")
+			raw, err := os.ReadFile(rawPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(compressedPath, raw, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := seed.CompressFile(compressedPath, compression); err != nil {
+				t.Fatalf("compress: %v", err)
+			}
+			detected, err := seed.DetectCompression(compressedPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if detected != compression {
+				t.Errorf("detected compression: expected %q, got %q", compression, detected)
+			}
+			compressedHash, err := seed.GetSeedHash(compressedPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if compressedHash != rawHash {
+				t.Errorf("hash: expected the raw content hash %q, got %q", rawHash, compressedHash)
+			}
+
+			target := testutil.NewTestApp(t)
+			if err := seed.Seed(target, compressedPath); err != nil {
+				t.Fatalf("seed: %v", err)
+			}
+			characters, err := target.FindAllRecords(models.CHARACTERS_COLLECTION_NAME)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(characters) != 1 {
+				t.Errorf("expected 1 seeded character, got %d", len(characters))
+			}
+			if err := seed.SaveDump(target, compressedPath, ""); err != nil {
+				t.Fatalf("save dump: %v", err)
+			}
+			dump, err := models.FindLatestDbDump(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dump.Encoding() != string(compression) || dump.Hash() != rawHash {
+				t.Errorf("stored dump: unexpected encoding %q and hash %q", dump.Encoding(), dump.Hash())
+			}
+		})
+	}
+}
+
+// TestUpdateFromPreloadDir applies a preload seed from a configured directory
+// without a seed.hash, and skips it once the dictionary is at that version.
+func TestUpdateFromPreloadDir(t *testing.T) {
+	source := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, source)
+	preloadDir := t.TempDir()
+	seedPath := filepath.Join(preloadDir, seed.PRELOAD_SEED_FILE)
+	if err := seed.Dump(source, seedPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(preloadDir, seed.PRELOAD_SEED_NOTE), []byte("preload notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	target := testutil.NewTestApp(t)
+	cache := models.NewLatestDbDumpCache()
+	cache.Bind(target)
+	if err := seed.UpdateFromPreload(target, cache, preloadDir); err != nil {
+		t.Fatalf("preload: %v", err)
+	}
+	hash, err := seed.GetSeedHash(seedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump, err := models.FindLatestDbDump(target)
+	if err != nil {
+		t.Fatalf("stored dump: %v", err)
+	}
+	if dump.Hash() != hash || dump.Notes() != "preload notes" {
+		t.Errorf("stored dump: unexpected hash %q and notes %q", dump.Hash(), dump.Notes())
+	}
+
+	// a second start finds the dictionary up to date and stores nothing new
+	if err := seed.UpdateFromPreload(target, cache, preloadDir); err != nil {
+		t.Fatalf("second preload: %v", err)
+	}
+	dumps, err := target.FindAllRecords(models.DB_DUMPS_COLLECTION_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dumps) != 1 {
+		t.Errorf("expected a single stored dump, got %d", len(dumps))
+	}
+
+	if err := seed.UpdateFromPreload(target, cache, t.TempDir()); err == nil {
+		t.Error("expected an error for a preload dir without a seed")
+	}
+}
+
+// TestPull pulls a seed from a fake upstream serving a zstd dump, the failures
+// end up in the pull status setting.
+func TestPull(t *testing.T) {
+	source := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, source)
+	dumpPath := filepath.Join(t.TempDir(), "seed.db")
+	if err := seed.DumpWithOptions(source, dumpPath, seed.DumpOptions{Compression: seed.COMPRESSION_ZSTD}); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := seed.GetSeedHash(dumpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	advertisedHash := hash
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/dump/latest":
+			json.NewEncoder(w).Encode(map[string]string{"hash": advertisedHash, "notes": "upstream notes"})
+		case "/api/dump/latest_seed.db":
+			w.Header().Set("Content-Encoding", "zstd")
+			http.ServeFile(w, r, dumpPath)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	target := testutil.NewTestApp(t)
+	if err := seed.PullAndRecord(target, upstream.URL); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	dump, err := models.FindLatestDbDump(target)
+	if err != nil {
+		t.Fatalf("stored dump: %v", err)
+	}
+	if dump.Hash() != hash || dump.Notes() != "upstream notes" || dump.Encoding() != "zstd" {
+		t.Errorf("stored dump: unexpected hash %q, notes %q, encoding %q", dump.Hash(), dump.Notes(), dump.Encoding())
+	}
+	characters, err := target.FindAllRecords(models.CHARACTERS_COLLECTION_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(characters) != 1 {
+		t.Errorf("expected the pulled seed to be applied, got %d characters", len(characters))
+	}
+
+	// already at the upstream version
+	updated, err := seed.Pull(target, upstream.URL)
+	if err != nil || updated {
+		t.Errorf("expected no update, got %v %v", updated, err)
+	}
+
+	// a corrupted download is rejected and recorded
+	advertisedHash = "0000"
+	if err := seed.PullAndRecord(target, upstream.URL); err == nil {
+		t.Fatal("expected a hash mismatch error")
+	}
+	setting, err := models.FindAppSettingsByKey(target, seed.SEED_PULL_STATUS_KEY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := seed.PullStatus{}
+	if err := json.Unmarshal([]byte(setting.Value()), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Error == "" || status.Updated {
+		t.Errorf("expected a recorded failure, got %+v", status)
+	}
+}
+
+func TestSeedLock(t *testing.T) {
+	app := testutil.NewTestApp(t)
+
+	if seed.IsSeeding(app) {
+		t.Fatal("expected no seed in progress")
+	}
+	lock, err := seed.AcquireSeedLock(app, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !seed.IsSeeding(app) {
+		t.Error("expected the held lock to be reported")
+	}
+	if _, err := seed.AcquireSeedLock(app, 50*time.Millisecond); !errors.Is(err, seed.ErrSeedLocked) {
+		t.Errorf("expected ErrSeedLocked, got %v", err)
+	}
+	if err := seed.SeedWithOptions(app, "missing.db", seed.SeedOptions{LockTimeout: 50 * time.Millisecond}); !errors.Is(err, seed.ErrSeedLocked) {
+		t.Errorf("expected the seed to give up waiting for the lock, got %v", err)
+	}
+	lock.Release()
+	lock.Release()
+	if seed.IsSeeding(app) {
+		t.Error("expected the released lock to be gone")
+	}
+
+	// a dump keeps the seeds out without being one
+	lock, err = seed.AcquireDumpLock(app, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed.IsSeeding(app) {
+		t.Error("expected a dump not to be reported as a seed")
+	}
+	if _, err := seed.AcquireSeedLock(app, 50*time.Millisecond); !errors.Is(err, seed.ErrSeedLocked) {
+		t.Errorf("expected the dump to hold the seeds back, got %v", err)
+	}
+	lock.Release()
+
+	// a lock file left by a crashed process is taken over once stale
+	lockPath := filepath.Join(app.DataDir(), seed.SEED_LOCK_FILE)
+	if err := os.WriteFile(lockPath, []byte("pid=0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !seed.IsSeeding(app) {
+		t.Error("expected a fresh lock file of another process to be reported")
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	// along with a takeover left by a process crashed in the middle of it
+	if err := os.WriteFile(lockPath+".takeover", []byte("pid=0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(lockPath+".takeover", old, old); err != nil {
+		t.Fatal(err)
+	}
+	lock, err = seed.AcquireSeedLock(app, time.Second)
+	if err != nil {
+		t.Fatalf("expected the stale lock to be taken over, got %v", err)
+	}
+	lock.Release()
+	if _, err := os.Stat(lockPath + ".takeover"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the takeover to be gone, got %v", err)
+	}
+}
+
+func TestDiffDumps(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.db")
+	if err := seed.Dump(app, oldPath, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	testutil.CreateRecord(t, app, models.CHARACTERS_COLLECTION_NAME, "characterbennet", map[string]any{
+		"name": "Bennett", "rarity": 4, "element": "elementpyro0000",
+		"weaponType": "weapontypesword", "special": "spcritrate00000",
+		"icon": testutil.Icon(t, "bennett.png"),
+	})
+	weapon, err := app.FindRecordById(models.WEAPONS_COLLECTION_NAME, "weaponaquila000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weapon.Set("rarity", 4)
+	if err := app.Save(weapon); err != nil {
+		t.Fatal(err)
+	}
+	element, err := app.FindRecordById(models.ELEMENTS_COLLECTION_NAME, "elementpyro0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherIcon, err := filesystem.NewFileFromBytes(append(bytes.Clone(testutil.PngContent), 0), "pyro.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	element.Set("icon", otherIcon)
+	if err := app.Save(element); err != nil {
+		t.Fatal(err)
+	}
+	domain, err := app.FindRecordById(models.DOMAINS_OF_BLESSING_COLLECTION_NAME, "domainofvalor00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Delete(domain); err != nil {
+		t.Fatal(err)
+	}
+
+	newPath := filepath.Join(dir, "new.db")
+	if err := seed.DumpWithOptions(app, newPath, seed.DumpOptions{Compression: seed.COMPRESSION_GZIP}); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := seed.DiffDumps(oldPath, newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if characters := diff.Collection(models.CHARACTERS_COLLECTION_NAME); characters == nil || len(characters.Added) != 1 || characters.Added[0].Name != "Bennett" {
+		t.Errorf("characters: unexpected %+v", characters)
+	}
+	weapons := diff.Collection(models.WEAPONS_COLLECTION_NAME)
+	if weapons == nil || len(weapons.Changed) != 1 || len(weapons.Changed[0].Fields) != 1 || weapons.Changed[0].Fields[0].Field != "rarity" {
+		t.Errorf("weapons: unexpected %+v", weapons)
+	}
+	elements := diff.Collection(models.ELEMENTS_COLLECTION_NAME)
+	if elements == nil || len(elements.Changed) != 1 || !elements.Changed[0].IconChanged || len(elements.Changed[0].Fields) != 0 {
+		t.Errorf("elements: unexpected %+v", elements)
+	}
+	if domains := diff.Collection(models.DOMAINS_OF_BLESSING_COLLECTION_NAME); domains == nil || len(domains.Removed) != 1 {
+		t.Errorf("domains: unexpected %+v", domains)
+	}
+	if specials := diff.Collection(models.SPECIALS_COLLECTION_NAME); specials != nil {
+		t.Errorf("specials: expected no changes, got %+v", specials)
+	}
+
+	text := new(bytes.Buffer)
+	if err := diff.WriteText(text); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"+ Bennett (characterbennet)", "rarity: 5 -> 4", "icon changed", "- Domain of Valor"} {
+		if !bytes.Contains(text.Bytes(), []byte(expected)) {
+			t.Errorf("text output: missing %q in\n%s", expected, text)
+		}
+	}
+
+	same, err := seed.DiffDumps(oldPath, oldPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !same.IsEmpty() {
+		t.Errorf("expected no changes between the same dumps, got %+v", same)
+	}
+}
+
+func TestDumpGenerateNotes(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	dir := t.TempDir()
+
+	if err := seed.DumpWithOptions(app, filepath.Join(dir, "first.db"), seed.DumpOptions{GenerateNotes: true}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := models.FindLatestDbDump(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Notes() != "- Initial dictionary" {
+		t.Errorf("first notes: unexpected %q", first.Notes())
+	}
+
+	testutil.CreateRecord(t, app, models.CHARACTERS_COLLECTION_NAME, "characterbennet", map[string]any{
+		"name": "Bennett", "rarity": 4, "element": "elementpyro0000",
+		"weaponType": "weapontypesword", "special": "spcritrate00000",
+		"icon": testutil.Icon(t, "bennett.png"),
+	})
+	weapon, err := app.FindRecordById(models.WEAPONS_COLLECTION_NAME, "weaponaquila000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weapon.Set("useless", true)
+	if err := app.Save(weapon); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	err = seed.DumpWithOptions(app, filepath.Join(dir, "second.db"), seed.DumpOptions{
+		Notes:         "Version 5.2",
+		GenerateNotes: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := models.FindLatestDbDump(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Version 5.2\n\n- Updated weapons: Aquila Favonia\n- Added characters: Bennett"
+	if second.Notes() != expected {
+		t.Errorf("second notes: expected %q, got %q", expected, second.Notes())
+	}
+}
+
+func TestDictionarySourceRoundTrip(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	dir := t.TempDir()
+	dumpPath := filepath.Join(dir, "seed.db")
+	if err := seed.Dump(app, dumpPath, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	sourceDir := filepath.Join(dir, "source")
+	stale := []string{
+		filepath.Join(sourceDir, "icons", "characters", "characterkaeya0.png"),
+		filepath.Join(sourceDir, "files", "characters", "characterkaeya0", "card.png"),
+	}
+	for _, name := range stale {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, testutil.PngContent, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := seed.ExportDictionarySource(dumpPath, sourceDir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range stale {
+		if _, err := os.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected the export to remove %s, got %v", name, err)
+		}
+	}
+	raw, err := os.ReadFile(filepath.Join(sourceDir, "characters.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	characters := []map[string]any{}
+	if err := json.Unmarshal(raw, &characters); err != nil {
+		t.Fatal(err)
+	}
+	if len(characters) != 1 || characters[0]["id"] != "characterdiluc0" {
+		t.Fatalf("unexpected characters %v", characters)
+	}
+	iconPath, _ := characters[0]["icon"].(string)
+	if iconPath != "icons/characters/characterdiluc0.png" {
+		t.Errorf("expected the icon to be named after the character, got %q", iconPath)
+	}
+	if icon, err := os.ReadFile(filepath.Join(sourceDir, filepath.FromSlash(iconPath))); err != nil || !bytes.Equal(icon, testutil.PngContent) {
+		t.Fatalf("icon %q: unexpected content, err %v", iconPath, err)
+	}
+
+	builtPath := filepath.Join(dir, "built.db")
+	if err := seed.BuildDictionarySource(app, sourceDir, builtPath); err != nil {
+		t.Fatal(err)
+	}
+	diff, err := seed.DiffDumps(dumpPath, builtPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.IsEmpty() {
+		t.Errorf("expected the built dump to match, got %+v", diff)
+	}
+	builtDb, err := core.DefaultDBConnect(builtPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var iconFilename string
+	err = builtDb.NewQuery("SELECT iconFilename FROM characters").Row(&iconFilename)
+	builtDb.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iconFilename != "characterdiluc0.png" {
+		t.Errorf("expected the icon name to come back from the source, got %q", iconFilename)
+	}
+
+	characters[0]["name"] = "Diluc Ragnvindr"
+	raw, _ = json.Marshal(characters)
+	if err := os.WriteFile(filepath.Join(sourceDir, "characters.json"), raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.BuildDictionarySource(app, sourceDir, builtPath); err != nil {
+		t.Fatal(err)
+	}
+	diff, err = seed.DiffDumps(dumpPath, builtPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := diff.Collection(models.CHARACTERS_COLLECTION_NAME)
+	if len(diff.Collections) != 1 || changes == nil || len(changes.Changed) != 1 || changes.Changed[0].Fields[0].Field != "name" {
+		t.Errorf("expected only the character name to change, got %+v", diff)
+	}
+
+	builtHash, err := seed.GetSeedHash(builtPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	characters[0]["nmae"] = "typo"
+	raw, _ = json.Marshal(characters)
+	if err := os.WriteFile(filepath.Join(sourceDir, "characters.json"), raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.BuildDictionarySource(app, sourceDir, builtPath); err == nil {
+		t.Error("expected an unknown field to fail the build")
+	}
+	// the failed build leaves the previous seed file alone
+	if hash, err := seed.GetSeedHash(builtPath); err != nil || hash != builtHash {
+		t.Errorf("expected the previous seed file kept, got hash %q, err %v", hash, err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, "*-build.db*")); len(leftovers) > 0 {
+		t.Errorf("expected no temporary build files, got %v", leftovers)
+	}
+}
+
+func TestDictionarySourcePaths(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	dir := t.TempDir()
+	dumpPath := filepath.Join(dir, "seed.db")
+	if err := seed.Dump(app, dumpPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	sourceDir := filepath.Join(dir, "source")
+	if err := seed.ExportDictionarySource(dumpPath, sourceDir); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(sourceDir, "characters.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	characters := []map[string]any{}
+	if err := json.Unmarshal(raw, &characters); err != nil {
+		t.Fatal(err)
+	}
+	characters[0]["icon"] = "../seed.db"
+	raw, _ = json.Marshal(characters)
+	if err := os.WriteFile(filepath.Join(sourceDir, "characters.json"), raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.BuildDictionarySource(app, sourceDir, filepath.Join(dir, "built.db")); err == nil || !strings.Contains(err.Error(), "invalid file path") {
+		t.Errorf("build: expected a path outside of the source to fail, got %v", err)
+	}
+
+	db, err := core.DefaultDBConnect(dumpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.NewQuery("UPDATE characters SET id = '../evil'").Execute()
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.ExportDictionarySource(dumpPath, filepath.Join(dir, "evil")); err == nil || !strings.Contains(err.Error(), "invalid file name") {
+		t.Errorf("export: expected a file name leaving its directory to fail, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil", "icons", "evil.png")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("export: expected no file outside the icons dir, got %v", err)
+	}
+}
+
+func TestSeedSelection(t *testing.T) {
+	source := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, source)
+	testutil.CreateRecord(t, source, models.ELEMENTS_COLLECTION_NAME, "elementhydro000", map[string]any{
+		"name": "Hydro", "color": "#2196f3", "inverseTextColor": true, "icon": testutil.Icon(t, "hydro.png"),
+	})
+	testutil.CreateRecord(t, source, models.CHARACTERS_COLLECTION_NAME, "charactermona00", map[string]any{
+		"name": "Mona", "rarity": 5, "element": "elementhydro000",
+		"weaponType": "weapontypesword", "special": "spcritrate00000",
+		"icon": testutil.Icon(t, "mona.png"),
+	})
+	dumpPath := filepath.Join(t.TempDir(), "seed.db")
+	if err := seed.Dump(source, dumpPath, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	target := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, target)
+
+	err := seed.SeedWithOptions(target, dumpPath, seed.SeedOptions{Only: []string{"unknown"}})
+	if !errors.Is(err, seed.ErrInvalidSelection) {
+		t.Errorf("unknown collection: expected ErrInvalidSelection, got %v", err)
+	}
+
+	err = seed.SeedWithOptions(target, dumpPath, seed.SeedOptions{Only: []string{models.CHARACTERS_COLLECTION_NAME}})
+	if !errors.Is(err, seed.ErrInvalidSelection) || !strings.Contains(err.Error(), "elementhydro000") {
+		t.Errorf("dangling element: expected ErrInvalidSelection naming the element, got %v", err)
+	}
+	if err := seed.CheckSeedSelection(target, dumpPath, seed.SeedOptions{Only: []string{models.CHARACTERS_COLLECTION_NAME}}); !errors.Is(err, seed.ErrInvalidSelection) {
+		t.Errorf("check: expected ErrInvalidSelection, got %v", err)
+	}
+	if _, err := target.FindRecordById(models.CHARACTERS_COLLECTION_NAME, "charactermona00"); err == nil {
+		t.Error("the rejected selection shouldn't seed anything")
+	}
+
+	err = seed.SeedWithOptions(target, dumpPath, seed.SeedOptions{
+		Only: []string{models.ELEMENTS_COLLECTION_NAME, models.CHARACTERS_COLLECTION_NAME},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := target.FindRecordById(models.CHARACTERS_COLLECTION_NAME, "charactermona00"); err != nil {
+		t.Errorf("mona: %v", err)
+	}
+	if _, err := models.FindAppSettingsByKey(target, "dictionaryVersion"); err == nil {
+		t.Error("a partial seed shouldn't set the dictionary version")
+	}
+
+	err = seed.SeedWithOptions(target, dumpPath, seed.SeedOptions{Exclude: []string{models.WEAPONS_COLLECTION_NAME}})
+	if err != nil {
+		t.Errorf("exclude: %v", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, app)
+	dir := t.TempDir()
+
+	if _, err := seed.Rollback(app, seed.RollbackOptions{}); !errors.Is(err, seed.ErrNoRollback) {
+		t.Errorf("empty history: expected ErrNoRollback, got %v", err)
+	}
+
+	firstPath := filepath.Join(dir, "first.db")
+	if err := seed.Dump(app, firstPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.UpdateDictionaryVersion(app, firstPath); err != nil {
+		t.Fatal(err)
+	}
+	firstHash, err := seed.GetSeedHash(firstPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutil.CreateRecord(t, app, models.CHARACTERS_COLLECTION_NAME, "characterbennet", map[string]any{
+		"name": "Bennett", "rarity": 4, "element": "elementpyro0000",
+		"weaponType": "weapontypesword", "special": "spcritrate00000",
+		"icon": testutil.Icon(t, "bennett.png"),
+	})
+	testutil.CreateRecord(t, app, models.WEAPONS_COLLECTION_NAME, "weaponharbinger", map[string]any{
+		"name": "Harbinger of Dawn", "rarity": 3, "weaponType": "weapontypesword",
+		"special": "spcritrate00000", "patch": "patch5dot100000", "useless": false,
+		"icon": testutil.Icon(t, "harbinger.png"),
+	})
+	diluc, err := app.FindRecordById(models.CHARACTERS_COLLECTION_NAME, "characterdiluc0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diluc.Set("name", "Broken")
+	if err := app.Save(diluc); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	secondPath := filepath.Join(dir, "second.db")
+	if err := seed.Dump(app, secondPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.UpdateDictionaryVersion(app, secondPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// the plans made with the added records are kept, the rollback is refused
+	user, _ := testutil.CreateUser(t, app, "rollback@example.com")
+	profile, err := models.FindDefaultGameProfile(app, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "bennettplan0000", map[string]any{
+		"user": user.Id, "profile": profile.Id, "character": "characterbennet", "order": 1,
+	})
+	testutil.CreateRecord(t, app, models.WEAPON_PLANS_COLLECTION_NAME, "harbingerplan00", map[string]any{
+		"characterPlan": "bennettplan0000", "weapon": "weaponharbinger", "order": 1,
+	})
+	_, err = seed.Rollback(app, seed.RollbackOptions{})
+	if !errors.Is(err, seed.ErrRollbackReferenced) {
+		t.Fatalf("referenced: expected ErrRollbackReferenced, got %v", err)
+	}
+	for _, reference := range []string{"characterPlans.character bennettplan0000", "weaponPlans.weapon harbingerplan00"} {
+		if !strings.Contains(err.Error(), reference) {
+			t.Errorf("referenced: expected %q to be listed, got %v", reference, err)
+		}
+	}
+	for collection, id := range map[string]string{
+		models.CHARACTER_PLANS_COLLECTION_NAME: "bennettplan0000",
+		models.WEAPON_PLANS_COLLECTION_NAME:    "harbingerplan00",
+		models.CHARACTERS_COLLECTION_NAME:      "characterbennet",
+		models.WEAPONS_COLLECTION_NAME:         "weaponharbinger",
+	} {
+		if _, err := app.FindRecordById(collection, id); err != nil {
+			t.Errorf("referenced: expected %s %s to be kept, got %v", collection, id, err)
+		}
+	}
+	bennettPlan, err := app.FindRecordById(models.CHARACTER_PLANS_COLLECTION_NAME, "bennettplan0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Delete(bennettPlan); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := seed.Rollback(app, seed.RollbackOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.To != firstHash {
+		t.Errorf("expected to roll back to %q, got %q", firstHash, result.To)
+	}
+	deleted := result.Deleted[models.CHARACTERS_COLLECTION_NAME]
+	if len(deleted) != 1 || deleted[0].Id != "characterbennet" {
+		t.Errorf("expected bennett to be deleted, got %+v", result.Deleted)
+	}
+	if deleted := result.Deleted[models.WEAPONS_COLLECTION_NAME]; len(deleted) != 1 || deleted[0].Id != "weaponharbinger" {
+		t.Errorf("expected the harbinger to be deleted, got %+v", result.Deleted)
+	}
+	if _, err := app.FindRecordById(models.CHARACTERS_COLLECTION_NAME, "characterbennet"); err == nil {
+		t.Error("bennett: expected the record to be gone")
+	}
+	diluc, err = app.FindRecordById(models.CHARACTERS_COLLECTION_NAME, "characterdiluc0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diluc.GetString("name") != "Diluc" {
+		t.Errorf("diluc: expected the previous name, got %q", diluc.GetString("name"))
+	}
+	version, err := models.FindAppSettingsByKey(app, "dictionaryVersion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.Value() != firstHash {
+		t.Errorf("dictionaryVersion: expected %q, got %q", firstHash, version.Value())
+	}
+	history, err := seed.DictionaryVersionHistory(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Hash != firstHash {
+		t.Errorf("history: unexpected %+v", history)
+	}
+
+	if _, err := seed.Rollback(app, seed.RollbackOptions{}); !errors.Is(err, seed.ErrNoRollback) {
+		t.Errorf("second rollback: expected ErrNoRollback, got %v", err)
+	}
+}
+
+type statTable struct {
+	Id         string                  `db:"id" pb:"id"`
+	Value      float64                 `db:"value" pb:"value"`
+	Released   types.DateTime          `db:"released" pb:"released"`
+	Gallery    seed.Files              `db:"gallery" pb:"gallery,files"`
+	Tier       string                  `db:"tier" pb:"tier,select"`
+	Characters types.JSONArray[string] `db:"characters" pb:"characters"`
+}
+
+func createStatTables(t testing.TB, app core.App) {
+	t.Helper()
+	characters, err := app.FindCollectionByNameOrId(models.CHARACTERS_COLLECTION_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := core.NewBaseCollection("statTables")
+	collection.Fields.Add(
+		&core.NumberField{Name: "value"},
+		&core.DateField{Name: "released"},
+		&core.FileField{Name: "gallery", MaxSelect: 5, MaxSize: 1 << 20},
+		&core.SelectField{Name: "tier", Values: []string{"low", "high"}, MaxSelect: 1},
+		&core.RelationField{Name: "characters", CollectionId: characters.Id, MaxSelect: 5},
+	)
+	if err := app.Save(collection); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var galleryName = regexp.MustCompile(`^(card|splash)_[a-z0-9]{10}\.png$`)
+
+func storedFileContent(t testing.TB, app core.App, record *core.Record, name string) []byte {
+	t.Helper()
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	r, err := fsys.GetReader(path.Join(record.BaseFilesPath(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestFieldKinds(t *testing.T) {
+	source := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, source)
+	createStatTables(t, source)
+	released := types.NowDateTime().Add(-time.Hour)
+	testutil.CreateRecord(t, source, "statTables", "stattable000001", map[string]any{
+		"value":      1.25,
+		"released":   released,
+		"gallery":    []any{testutil.Icon(t, "card.png"), testutil.Icon(t, "splash.png")},
+		"tier":       "high",
+		"characters": []string{"characterdiluc0"},
+	})
+	dumpPath := filepath.Join(t.TempDir(), "stats.db")
+	if err := seed.DumpCollection[statTable](source, dumpPath, "statTables"); err != nil {
+		t.Fatal(err)
+	}
+
+	target := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, target)
+	createStatTables(t, target)
+	if err := seed.SeedCollection[statTable](target, dumpPath, "statTables"); err != nil {
+		t.Fatal(err)
+	}
+	record, err := target.FindRecordById("statTables", "stattable000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.GetFloat("value") != 1.25 {
+		t.Errorf("value: unexpected %v", record.GetFloat("value"))
+	}
+	if record.GetDateTime("released").String() != released.String() {
+		t.Errorf("released: expected %q, got %q", released.String(), record.GetDateTime("released").String())
+	}
+	if record.GetString("tier") != "high" {
+		t.Errorf("tier: unexpected %q", record.GetString("tier"))
+	}
+	if characters := record.GetStringSlice("characters"); len(characters) != 1 || characters[0] != "characterdiluc0" {
+		t.Errorf("characters: unexpected %v", characters)
+	}
+	checkGallery := func(record *core.Record) {
+		t.Helper()
+		gallery := record.GetStringSlice("gallery")
+		if len(gallery) != 2 || !galleryName.MatchString(gallery[0]) || !galleryName.MatchString(gallery[1]) {
+			t.Fatalf("gallery: unexpected %v", gallery)
+		}
+		for _, name := range gallery {
+			if content := storedFileContent(t, target, record, name); !bytes.Equal(content, testutil.PngContent) {
+				t.Errorf("gallery %s: unexpected content", name)
+			}
+		}
+	}
+	checkGallery(record)
+
+	// the names don't pile up upload suffixes over dump and seed cycles
+	secondPath := filepath.Join(t.TempDir(), "stats.db")
+	if err := seed.DumpCollection[statTable](target, secondPath, "statTables"); err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.SeedCollection[statTable](target, secondPath, "statTables"); err != nil {
+		t.Fatal(err)
+	}
+	record, err = target.FindRecordById("statTables", "stattable000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGallery(record)
+
+	collection, err := target.FindCollectionByNameOrId("statTables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection.Fields.GetByName("tier").(*core.SelectField).Values = []string{"low"}
+	if err := target.Save(collection); err != nil {
+		t.Fatal(err)
+	}
+	err = seed.SeedCollection[statTable](target, dumpPath, "statTables")
+	if err == nil || !strings.Contains(err.Error(), `"high" is not one of low`) {
+		t.Errorf("strict select: expected a validation error, got %v", err)
+	}
+}
+
+func TestSchemaConformance(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	testutil.RequireSeedSchema(t, app)
+
+	characters, err := app.FindCollectionByNameOrId(models.CHARACTERS_COLLECTION_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	characters.Fields.GetByName("rarity").SetName("stars")
+	characters.Fields.GetByName("patch").(*core.RelationField).Required = true
+	if err := app.Save(characters); err != nil {
+		t.Fatal(err)
+	}
+	issues := []string{}
+	for _, issue := range seed.CheckSchema(app) {
+		issues = append(issues, issue.String())
+	}
+	expected := []string{
+		"characters.rarity: mapped field missing from the collection",
+		"characters.patch: required by the collection but optional in the seed",
+		"characters.stars: required by the collection but not seeded",
+	}
+	if !slices.Equal(issues, expected) {
+		t.Errorf("expected %q, got %q", expected, issues)
+	}
+}
+
+// TestGeneratedMappings checks that the seedgen mappings behave like the
+// reflection ones they replace.
+func TestGeneratedMappings(t *testing.T) {
+	if mismatches := seed.TableSchemaMismatches(); len(mismatches) > 0 {
+		t.Errorf("table schemas: %q", mismatches)
+	}
+
+	source := testutil.NewTestApp(t)
+	testutil.SeedDictionaries(t, source)
+	dir := t.TempDir()
+	generatedPath := filepath.Join(dir, "generated.db")
+	if err := seed.Dump(source, generatedPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("reflection", func(t *testing.T) {
+		seed.UseReflectionMappings(t)
+		reflectedPath := filepath.Join(dir, "reflected.db")
+		if err := seed.WriteDump(source, reflectedPath, nil); err != nil {
+			t.Fatal(err)
+		}
+		diff, err := seed.DiffDumps(generatedPath, reflectedPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !diff.IsEmpty() {
+			t.Errorf("dump: expected no difference, got %+v", diff)
+		}
+
+		target := testutil.NewTestApp(t)
+		if err := seed.Seed(target, generatedPath); err != nil {
+			t.Fatal(err)
+		}
+		reseededPath := filepath.Join(dir, "reseeded.db")
+		if err := seed.Dump(target, reseededPath, ""); err != nil {
+			t.Fatal(err)
+		}
+		diff, err = seed.DiffDumps(generatedPath, reseededPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !diff.IsEmpty() {
+			t.Errorf("seed: expected no difference, got %+v", diff)
+		}
+	})
+}
+
+// TestIconPipeline covers the icon normalization on the record saves and on
+// the seed, the fixtures share a single png so they share a single icon.
+func TestIconPipeline(t *testing.T) {
+	expected, err := icons.Normalize(testutil.PngContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := testutil.NewTestApp(t)
+	seed.BindIconPipeline(source)
+	testutil.SeedDictionaries(t, source)
+
+	assertIcons := func(app core.App) {
+		t.Helper()
+		element, err := app.FindRecordById(models.ELEMENTS_COLLECTION_NAME, "elementpyro0000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := element.GetString("icon"); name != icons.FileName(expected.Hash) {
+			t.Errorf("element icon: expected %q, got %q", icons.FileName(expected.Hash), name)
+		}
+		if content := fileContent(t, app, element, "icon"); !bytes.Equal(content, expected.Image) {
+			t.Errorf("element icon: content isn't the normalized webp (%d bytes)", len(content))
+		}
+		records, err := app.FindAllRecords(models.ICONS_COLLECTION_NAME)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 1 || records[0].GetString("hash") != expected.Hash {
+			t.Fatalf("icons: expected the single %s icon, got %d records", expected.Hash, len(records))
+		}
+		if thumb := records[0].GetString("thumb"); thumb != icons.ThumbName(expected.Hash) {
+			t.Errorf("icons thumb: expected %q, got %q", icons.ThumbName(expected.Hash), thumb)
+		}
+	}
+	assertIcons(source)
+
+	element, err := source.FindRecordById(models.ELEMENTS_COLLECTION_NAME, "elementpyro0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid, err := filesystem.NewFileFromBytes([]byte("not an image"), "pyro.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	element.Set("icon", invalid)
+	if err := source.Save(element); err == nil || !strings.Contains(err.Error(), "unsupported image") {
+		t.Errorf("invalid icon: expected an unsupported image error, got %v", err)
+	}
+
+	dumpPath := filepath.Join(t.TempDir(), "seed.db")
+	if err := seed.Dump(source, dumpPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	target := testutil.NewTestApp(t)
+	seed.BindIconPipeline(target)
+	if err := seed.Seed(target, dumpPath); err != nil {
+		t.Fatal(err)
+	}
+	assertIcons(target)
+
+	// the hash names survive the dump, so a dump of the seeded app is the same
+	redumpPath := filepath.Join(t.TempDir(), "reseed.db")
+	if err := seed.WriteDump(target, redumpPath, nil); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := seed.GetSeedHash(dumpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rehash, err := seed.GetSeedHash(redumpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != rehash {
+		t.Errorf("redump: expected the hash %s, got %s", hash, rehash)
+	}
+}
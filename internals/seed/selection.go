@@ -0,0 +1,151 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ErrInvalidSelection wraps the errors of a partial seed selection, either an
+// unknown collection or relations the selection would leave dangling.
+var ErrInvalidSelection = errors.New("invalid seed selection")
+
+// selectDictionaries resolves the Only and Exclude options to the collections
+// to seed, kept in the seeding order.
+func selectDictionaries(only []string, exclude []string) ([]dictionaryCollection, error) {
+	names := DictionaryCollections()
+	for _, name := range slices.Concat(only, exclude) {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("%w: unknown collection %q", ErrInvalidSelection, name)
+		}
+	}
+	selected := []dictionaryCollection{}
+	for _, dictionary := range dictionaryCollections {
+		if len(only) > 0 && !slices.Contains(only, dictionary.name) {
+			continue
+		}
+		if slices.Contains(exclude, dictionary.name) {
+			continue
+		}
+		selected = append(selected, dictionary)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w: no collections left to seed", ErrInvalidSelection)
+	}
+	return selected, nil
+}
+
+func isPartialSelection(selected []dictionaryCollection) bool {
+	return len(selected) != len(dictionaryCollections)
+}
+
+// relationIdsCollection lists the distinct ids the seed table holds in the
+// field, a single relation or a json array of them.
+func relationIdsCollection[T any](db dbx.Builder, table string, field string) ([]string, error) {
+	items, err := loadDumpItems[T](db, table)
+	if err != nil {
+		return nil, err
+	}
+	fields := mustGetFieldInfo[T]()
+	idx := slices.IndexFunc(fields, func(fd pbFieldInfo) bool { return fd.pbKey == field && !fd.isFileExt })
+	if idx < 0 {
+		return nil, nil
+	}
+	fd := fields[idx]
+	ids := []string{}
+	for _, item := range items {
+		value := reflect.ValueOf(item).Field(fd.structIdx)
+		switch {
+		case value.Kind() == reflect.String:
+			if id := value.String(); id != "" {
+				ids = append(ids, id)
+			}
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+			for i := range value.Len() {
+				ids = append(ids, value.Index(i).String())
+			}
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// checkSelection makes sure every relation of the selected collections that
+// points to a collection left out of the selection is already satisfied by
+// the app records. The seed only upserts, so the collections left out can't
+// be broken by the selected ones.
+func checkSelection(app core.App, db dbx.Builder, selected []dictionaryCollection) error {
+	if !isPartialSelection(selected) {
+		return nil
+	}
+	selectedNames := make([]string, len(selected))
+	for i, dictionary := range selected {
+		selectedNames[i] = dictionary.name
+	}
+
+	errs := []error{}
+	for _, dictionary := range selected {
+		collection, err := app.FindCachedCollectionByNameOrId(dictionary.name)
+		if err != nil {
+			return err
+		}
+		for _, field := range collection.Fields {
+			relation, ok := field.(*core.RelationField)
+			if !ok {
+				continue
+			}
+			target, err := app.FindCachedCollectionByNameOrId(relation.CollectionId)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(selectedNames, target.Name) || !slices.Contains(DictionaryCollections(), target.Name) {
+				continue
+			}
+			ids, err := dictionary.relationIds(db, relation.Name)
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			records, err := app.FindRecordsByIds(target.Name, ids)
+			if err != nil {
+				return err
+			}
+			if len(records) == len(ids) {
+				continue
+			}
+			missing := slices.DeleteFunc(slices.Clone(ids), func(id string) bool {
+				return slices.ContainsFunc(records, func(r *core.Record) bool { return r.Id == id })
+			})
+			errs = append(errs, fmt.Errorf(
+				"%s.%s references %s missing from the app (%s), add %s to the selection",
+				dictionary.name, relation.Name, target.Name, strings.Join(missing, ", "), target.Name,
+			))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidSelection, errors.Join(errs...))
+	}
+	return nil
+}
+
+// CheckSeedSelection validates the Only and Exclude options of opts against
+// the seed file without seeding anything.
+func CheckSeedSelection(app core.App, path string, opts SeedOptions) error {
+	selected, err := selectDictionaries(opts.Only, opts.Exclude)
+	if err != nil || !isPartialSelection(selected) {
+		return err
+	}
+	db, closeDb, err := openDumpDb(path)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+	return checkSelection(app, db, selected)
+}
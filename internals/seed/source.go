@@ -0,0 +1,340 @@
+package seed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// The dictionary source is a directory meant to be kept in git: a json file
+// per dictionary collection with the items sorted by id, and the icons and
+// the multi file fields as plain files referenced by their path relative to
+// the directory.
+//
+//	characters.json
+//	icons/characters/characterdiluc0.png
+//	files/characters/characterdiluc0/card.png
+const (
+	sourceIconsDir = "icons"
+	sourceFilesDir = "files"
+)
+
+func sourceCollectionFile(dir string, collection string) string {
+	return filepath.Join(dir, collection+".json")
+}
+
+// sourceFileName checks the name of a dumped file before it is written into
+// the source dir, the seed file may come from anywhere.
+func sourceFileName(name string) (string, error) {
+	base := filepath.Base(name)
+	if base != name || base == "." || base == ".." {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return base, nil
+}
+
+// sourceFilePath resolves a path of the source json in dir, it can't point
+// outside of it.
+func sourceFilePath(dir string, sourcePath string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(sourcePath)) {
+		return "", fmt.Errorf("invalid file path %q", sourcePath)
+	}
+	return filepath.Join(dir, filepath.FromSlash(sourcePath)), nil
+}
+
+func exportCollection[T any](db dbx.Builder, dir string, table string) error {
+	items, err := loadDumpItems[T](db, table)
+	if err != nil {
+		return err
+	}
+	fields := mustGetFieldInfo[T]()
+	slices.SortFunc(items, func(a, b T) int {
+		return strings.Compare(itemRef(reflect.ValueOf(a), fields).Id, itemRef(reflect.ValueOf(b), fields).Id)
+	})
+
+	// the output of the collection is written from scratch, so that the
+	// files of the removed items don't linger
+	iconsDir := path.Join(sourceIconsDir, table)
+	for _, outDir := range []string{iconsDir, path.Join(sourceFilesDir, table)} {
+		if err := os.RemoveAll(filepath.Join(dir, filepath.FromSlash(outDir))); err != nil {
+			return err
+		}
+	}
+	out := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		rv := reflect.ValueOf(item)
+		id := itemRef(rv, fields).Id
+		entry := map[string]any{}
+		for _, fd := range fields {
+			if fd.isFileExt {
+				continue
+			}
+			if fd.isFiles {
+				paths := []string{}
+				filesDir := path.Join(sourceFilesDir, table, id)
+				for _, file := range rv.Field(fd.structIdx).Interface().(Files) {
+					name, err := sourceFileName(file.Name)
+					if err != nil {
+						return fmt.Errorf("%s %s: %s: %w", table, id, fd.pbKey, err)
+					}
+					filePath := path.Join(filesDir, name)
+					if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(filesDir)), 0755); err != nil {
+						return err
+					}
+					if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(filePath)), file.Content, 0644); err != nil {
+						return err
+					}
+					paths = append(paths, filePath)
+				}
+				entry[fd.pbKey] = paths
+				continue
+			}
+			if !fd.isFile {
+				entry[fd.pbKey] = rv.Field(fd.structIdx).Interface()
+				continue
+			}
+			// the icon is named after the item, the build takes the fileext
+			// back from it
+			filename, err := sourceFileName(id + path.Ext(fileExtValue(rv, fields, fd.pbKey)))
+			if err != nil {
+				return fmt.Errorf("%s %s: %s: %w", table, id, fd.pbKey, err)
+			}
+			iconPath := path.Join(iconsDir, filename)
+			if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(iconsDir)), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(iconPath)), rv.Field(fd.structIdx).Bytes(), 0644); err != nil {
+				return err
+			}
+			entry[fd.pbKey] = iconPath
+		}
+		out = append(out, entry)
+	}
+
+	raw, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sourceCollectionFile(dir, table), append(raw, '\n'), 0644)
+}
+
+func fileExtValue(rv reflect.Value, fields []pbFieldInfo, pbKey string) string {
+	for _, fd := range fields {
+		if fd.isFileExt && fd.pbKey == pbKey {
+			return rv.Field(fd.structIdx).String()
+		}
+	}
+	return ""
+}
+
+func buildCollection[T any](app core.App, dir string, db dbx.Builder, table string) error {
+	collection, err := app.FindCachedCollectionByNameOrId(table)
+	if err != nil {
+		return err
+	}
+	if err := createTableFromStruct[T](db, table); err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(sourceCollectionFile(dir, table))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	entries := []map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return err
+	}
+
+	fields := mustGetFieldInfo[T]()
+	known := map[string]bool{}
+	for _, fd := range fields {
+		known[fd.pbKey] = true
+	}
+	for i, entry := range entries {
+		for key := range entry {
+			if !known[key] {
+				return fmt.Errorf("item %d: unknown field %q", i, key)
+			}
+		}
+		params := dbx.Params{}
+		for _, fd := range fields {
+			value, ok := entry[fd.pbKey]
+			if !ok && !fd.isOpt {
+				return fmt.Errorf("item %d: missing field %q", i, fd.pbKey)
+			}
+			if fd.isFile || fd.isFileExt {
+				var iconPath string
+				if ok {
+					if err := json.Unmarshal(value, &iconPath); err != nil {
+						return fmt.Errorf("item %d: %s: %w", i, fd.pbKey, err)
+					}
+				}
+				if fd.isFileExt {
+					params[fd.dbKey] = path.Base(iconPath)
+					continue
+				}
+				iconFile, err := sourceFilePath(dir, iconPath)
+				if err != nil {
+					return fmt.Errorf("item %d: %s: %w", i, fd.pbKey, err)
+				}
+				content, err := os.ReadFile(iconFile)
+				if err != nil {
+					return fmt.Errorf("item %d: %s: %w", i, fd.pbKey, err)
+				}
+				params[fd.dbKey] = content
+				continue
+			}
+			if fd.isFiles {
+				paths := []string{}
+				if ok {
+					if err := json.Unmarshal(value, &paths); err != nil {
+						return fmt.Errorf("item %d: %s: %w", i, fd.pbKey, err)
+					}
+				}
+				files := Files{}
+				for _, filePath := range paths {
+					file, err := sourceFilePath(dir, filePath)
+					if err != nil {
+						return fmt.Errorf("item %d: %s: %w", i, fd.pbKey, err)
+					}
+					content, err := os.ReadFile(file)
+					if err != nil {
+						return fmt.Errorf("item %d: %s: %w", i, fd.pbKey, err)
+					}
+					files = append(files, File{Name: path.Base(filePath), Content: content})
+				}
+				params[fd.dbKey] = files
+				continue
+			}
+			ptr := reflect.New(fd.goType)
+			if ok {
+				if err := json.Unmarshal(value, ptr.Interface()); err != nil {
+					return fmt.Errorf("item %d: %s: %w", i, fd.pbKey, err)
+				}
+			}
+			if err := fd.checkSelect(collection, ptr.Elem()); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+			if fd.isJSON {
+				b, err := json.Marshal(ptr.Elem().Interface())
+				if err != nil {
+					return err
+				}
+				params[fd.dbKey] = b
+				continue
+			}
+			params[fd.dbKey] = ptr.Elem().Interface()
+		}
+		if _, err := db.Insert(table, params).Execute(); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ExportDictionarySource writes the dictionaries of the seed file into dir.
+func ExportDictionarySource(seedPath string, dir string) error {
+	db, closeDb, err := openDumpDb(seedPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, dictionary := range dictionaryCollections {
+		if err := dictionary.export(db, dir); err != nil {
+			return fmt.Errorf("%s: %w", dictionary.name, err)
+		}
+	}
+	return nil
+}
+
+// BuildDictionarySource writes a regular seed file out of the dictionary
+// source in dir, checked against the collections of the app. The seed file is
+// built from scratch next to seedPath and only replaces it once complete, a
+// failed build leaves the previous one.
+func BuildDictionarySource(app core.App, dir string, seedPath string) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(seedPath), "*-build.db")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	db, err := core.DefaultDBConnect(tmpPath)
+	if err != nil {
+		return err
+	}
+	err = db.Transactional(func(tx *dbx.Tx) error {
+		for _, dictionary := range dictionaryCollections {
+			if err := dictionary.build(app, dir, tx); err != nil {
+				return fmt.Errorf("%s: %w", dictionary.name, err)
+			}
+		}
+		return nil
+	})
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, seedPath)
+}
+
+func NewCobraDictionaryCommand(app core.App) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "dictionary",
+		Short: "Convert the dictionaries from and to a reviewable source directory",
+	}
+
+	var from string
+	exportCommand := &cobra.Command{
+		Use:   "export source_dir",
+		Short: "Write the dictionaries as json files and icons",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if from != "" {
+				return ExportDictionarySource(from, args[0])
+			}
+			tmpFile, err := os.CreateTemp("", "*-export.db")
+			if err != nil {
+				return err
+			}
+			tmpPath := tmpFile.Name()
+			tmpFile.Close()
+			defer os.Remove(tmpPath)
+			if err := writeDump(app, tmpPath, nil); err != nil {
+				return err
+			}
+			return ExportDictionarySource(tmpPath, args[0])
+		},
+	}
+	exportCommand.Flags().StringVar(&from, "from", "", "export a seed file instead of the app dictionaries")
+
+	buildCommand := &cobra.Command{
+		Use:   "build source_dir seed_file",
+		Short: "Build a seed file from a source directory",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return BuildDictionarySource(app, args[0], args[1])
+		},
+	}
+
+	command.AddCommand(exportCommand, buildCommand)
+	return command
+}
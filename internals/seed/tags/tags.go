@@ -0,0 +1,56 @@
+// Package tags parses the pb struct tags of the seed structs, it is shared by
+// the reflection mapping and the seedgen generator.
+package tags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tag is a parsed pb tag, e.g.
+//
+//	pb:"icon,file"       single file content, paired with a "fileext" name
+//	pb:"gallery,files"   multi file field
+//	pb:"tier,select"     select field, checked against the collection values
+//	pb:"specials,json"   json array, implied for the string slices
+//	pb:"patch,opt"       nullable column
+type Tag struct {
+	Key     string
+	File    bool
+	FileExt bool
+	Files   bool
+	JSON    bool
+	Opt     bool
+	Select  bool
+}
+
+// Parse reads a pb tag, the unknown options are rejected.
+func Parse(tag string) (Tag, error) {
+	parts := strings.Split(tag, ",")
+	t := Tag{Key: parts[0]}
+	if t.Key == "" {
+		return t, fmt.Errorf("pb tag %q: missing field name", tag)
+	}
+	for _, p := range parts[1:] {
+		switch {
+		case p == "file":
+			t.File = true
+		case p == "fileext":
+			t.FileExt = true
+		case p == "files":
+			t.Files = true
+		case p == "json":
+			t.JSON = true
+		case p == "opt":
+			t.Opt = true
+		case p == "select":
+			t.Select = true
+		default:
+			return t, fmt.Errorf("pb tag %q: unknown option %q", tag, p)
+		}
+	}
+	if t.File && t.FileExt || t.File && t.Files || t.FileExt && t.Files {
+		return t, fmt.Errorf("pb tag %q: file, fileext and files are exclusive", tag)
+	}
+	return t, nil
+}
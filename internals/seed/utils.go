@@ -5,31 +5,92 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"path"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/qxuken/gbp/internals/icons"
+	"github.com/qxuken/gbp/internals/seed/tags"
 )
 
 var fieldCache sync.Map
 
+// Progress is notified after every item of a seeded or dumped collection.
+type Progress func(collection string, done int, total int)
+
+func (p Progress) report(collection string, done int, total int) {
+	if p != nil {
+		p(collection, done, total)
+	}
+}
+
+// pbFieldInfo is a seed struct field mapped by its pb tag, see tags.Tag.
 type pbFieldInfo struct {
 	dbKey     string
 	pbKey     string
 	structIdx int
 	isFile    bool
 	isFileExt bool
+	isFiles   bool
 	isJSON    bool
 	isOpt     bool
 	isPK      bool
+	isSelect  bool
 	goType    reflect.Type
 }
 
+var (
+	filesType    = reflect.TypeFor[Files]()
+	dateTimeType = reflect.TypeFor[types.DateTime]()
+)
+
+func isStringSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String
+}
+
+// checkSelect validates the select values of the field against the
+// collection, a single string or a string slice for the multi selects.
+func (fd pbFieldInfo) checkSelect(collection *core.Collection, value reflect.Value) error {
+	if !fd.isSelect {
+		return nil
+	}
+	values := []string{}
+	switch {
+	case value.Kind() == reflect.String:
+		values = append(values, value.String())
+	case isStringSlice(value.Type()):
+		for i := range value.Len() {
+			values = append(values, value.Index(i).String())
+		}
+	}
+	return checkSelectValues(collection, fd.pbKey, values...)
+}
+
+// checkSelectValues validates the values against the ones the select field
+// of the collection allows.
+func checkSelectValues(collection *core.Collection, key string, values ...string) error {
+	field, ok := collection.Fields.GetByName(key).(*core.SelectField)
+	if !ok {
+		return fmt.Errorf("%s: not a select field of %s", key, collection.Name)
+	}
+	for _, v := range values {
+		if v != "" && !slices.Contains(field.Values, v) {
+			return fmt.Errorf("%s: %q is not one of %s", key, v, strings.Join(field.Values, ", "))
+		}
+	}
+	return nil
+}
+
 func getFieldInfo(t reflect.Type) ([]pbFieldInfo, error) {
 	if cached, ok := fieldCache.Load(t); ok {
 		return cached.([]pbFieldInfo), nil
@@ -50,31 +111,27 @@ func getFieldInfo(t reflect.Type) ([]pbFieldInfo, error) {
 		}
 		dbTag := f.Tag.Get("db")
 
-		parts := strings.Split(pbTag, ",")
-		pbKey := parts[0]
-		var isFile, isFileExt, isJSON, isOpt bool
-		for _, p := range parts[1:] {
-			switch p {
-			case "file":
-				isFile = true
-			case "fileext":
-				isFileExt = true
-			case "json":
-				isJSON = true
-			case "opt":
-				isOpt = true
-			}
+		tag, err := tags.Parse(pbTag)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
 		}
+		if tag.Files && f.Type != filesType {
+			return nil, fmt.Errorf("%s.%s: files field must be of type Files, got %s", t.Name(), f.Name, f.Type)
+		}
+		// the multi relations and multi selects are stored as json arrays
+		isJSON := tag.JSON || !tag.Files && isStringSlice(f.Type)
 
 		fields = append(fields, pbFieldInfo{
 			dbKey:     dbTag,
-			pbKey:     pbKey,
+			pbKey:     tag.Key,
 			structIdx: i,
-			isFile:    isFile,
-			isFileExt: isFileExt,
+			isFile:    tag.File,
+			isFileExt: tag.FileExt,
+			isFiles:   tag.Files,
 			isJSON:    isJSON,
-			isOpt:     isOpt,
+			isOpt:     tag.Opt,
 			isPK:      dbTag == "id",
+			isSelect:  tag.Select,
 			goType:    f.Type,
 		})
 	}
@@ -91,11 +148,16 @@ func mustGetFieldInfo[T any]() []pbFieldInfo {
 }
 
 func sqlTypeFromGo(t reflect.Type) string {
+	if t == dateTimeType || t == filesType {
+		return "TEXT"
+	}
 	switch t.Kind() {
 	case reflect.String:
 		return "TEXT"
 	case reflect.Int, reflect.Int64:
 		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
 	case reflect.Bool:
 		return "BOOL"
 	case reflect.Slice:
@@ -108,7 +170,30 @@ func sqlTypeFromGo(t reflect.Type) string {
 	}
 }
 
+// generatedMapping is implemented by the seed structs with a seedgen mapping
+// (models_gen.go), the other structs go through the reflection mapping.
+type generatedMapping interface {
+	recordId() string
+	toRecord(record *core.Record) error
+	fromRecord(fsys *filesystem.System, record *core.Record) (dbx.Params, error)
+	tableSchema() map[string]string
+}
+
+// useGeneratedMappings can be turned off to compare both mappings.
+var useGeneratedMappings = true
+
+func generatedMappingOf[T any](item T) (generatedMapping, bool) {
+	if !useGeneratedMappings {
+		return nil, false
+	}
+	mapping, ok := any(item).(generatedMapping)
+	return mapping, ok
+}
+
 func structTableSchema[T any]() map[string]string {
+	if mapping, ok := generatedMappingOf(*new(T)); ok {
+		return mapping.tableSchema()
+	}
 	fields := mustGetFieldInfo[T]()
 	schema := make(map[string]string, len(fields))
 	for _, fd := range fields {
@@ -143,7 +228,30 @@ func upsertRecordById(app core.App, collectionName string, id string) (*core.Rec
 	return record, err
 }
 
+func (item Files) toFilesystem() ([]*filesystem.File, error) {
+	files := make([]*filesystem.File, len(item))
+	for i, f := range item {
+		file, err := filesystem.NewFileFromBytes(f.Content, f.Name)
+		if err != nil {
+			return nil, err
+		}
+		files[i] = file
+	}
+	return files, nil
+}
+
 func seedItem[T any](app core.App, item T, collectionName string, fields []pbFieldInfo) error {
+	if mapping, ok := generatedMappingOf(item); ok {
+		record, err := upsertRecordById(app, collectionName, mapping.recordId())
+		if err != nil {
+			return err
+		}
+		if err := mapping.toRecord(record); err != nil {
+			return fmt.Errorf("%s %s: %w", collectionName, mapping.recordId(), err)
+		}
+		return app.Save(record)
+	}
+
 	rv := reflect.ValueOf(item)
 
 	var id string
@@ -163,7 +271,16 @@ func seedItem[T any](app core.App, item T, collectionName string, fields []pbFie
 		if fd.isFileExt || fd.isPK {
 			continue
 		}
-		if fd.isFile {
+		if err := fd.checkSelect(record.Collection(), rv.Field(fd.structIdx)); err != nil {
+			return fmt.Errorf("%s %s: %w", collectionName, id, err)
+		}
+		if fd.isFiles {
+			files, err := rv.Field(fd.structIdx).Interface().(Files).toFilesystem()
+			if err != nil {
+				return err
+			}
+			record.Set(fd.pbKey, files)
+		} else if fd.isFile {
 			content := rv.Field(fd.structIdx).Bytes()
 			var filename string
 			for _, fd2 := range fields {
@@ -185,48 +302,88 @@ func seedItem[T any](app core.App, item T, collectionName string, fields []pbFie
 	return app.Save(record)
 }
 
-func seedCollection[T any](app core.App, db dbx.Builder, sourceTable string) error {
+func seedCollection[T any](app core.App, db dbx.Builder, sourceTable string, progress Progress) error {
 	app.Logger().Debug(fmt.Sprintf("Seeding %v", sourceTable))
-	items := []T{}
-	if err := db.NewQuery(fmt.Sprintf("select * from %v", sourceTable)).All(&items); err != nil {
+	// the dumps made before the collection existed don't have its table
+	items, err := loadDumpItems[T](db, sourceTable)
+	if err != nil {
 		return err
 	}
 	app.Logger().Debug(fmt.Sprintf("Fetched %v", sourceTable))
 
 	fields := mustGetFieldInfo[T]()
-	for _, s := range items {
+	progress.report(sourceTable, 0, len(items))
+	for i, s := range items {
 		if err := seedItem(app, s, sourceTable, fields); err != nil {
 			return err
 		}
+		progress.report(sourceTable, i+1, len(items))
 	}
 	return nil
 }
 
-func getFileContent(fsys *filesystem.System, record *core.Record, fieldName string) (string, []byte, error) {
-	orignalFileName := record.GetString(fieldName)
-	iconPath := path.Join(record.BaseFilesPath(), orignalFileName)
-
-	name := record.GetString("name")
-	fileName := strings.ReplaceAll(strings.ReplaceAll(strings.ToLower(name), " ", "_"), "'", "_") + filepath.Ext(iconPath)
-
-	r, err := fsys.GetReader(iconPath)
+func readRecordFile(fsys *filesystem.System, record *core.Record, storedName string) ([]byte, error) {
+	r, err := fsys.GetReader(path.Join(record.BaseFilesPath(), storedName))
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 	defer r.Close()
 	buffer := make([]byte, r.Size())
-	n, err := r.Read(buffer)
+	n, err := io.ReadFull(r, buffer)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 	if n < int(r.Size()) {
-		return "", nil, errors.New("File read corruption")
+		return nil, errors.New("File read corruption")
+	}
+	return buffer, nil
+}
+
+func getFileContent(fsys *filesystem.System, record *core.Record, fieldName string) (string, []byte, error) {
+	orignalFileName := record.GetString(fieldName)
+
+	// the normalized icons keep their content hash name, the others get one
+	// derived from the item name
+	if _, ok := icons.HashFromName(orignalFileName); ok {
+		content, err := readRecordFile(fsys, record, orignalFileName)
+		return orignalFileName, content, err
 	}
+	name := record.GetString("name")
+	fileName := strings.ReplaceAll(strings.ReplaceAll(strings.ToLower(name), " ", "_"), "'", "_") + filepath.Ext(orignalFileName)
 
-	return fileName, buffer, nil
+	content, err := readRecordFile(fsys, record, orignalFileName)
+	if err != nil {
+		return "", nil, err
+	}
+	return fileName, content, nil
+}
+
+// storedNameSuffix is the random suffix the file fields append on upload.
+var storedNameSuffix = regexp.MustCompile(`_[a-z0-9]{10}$`)
+
+// uploadName strips the upload suffix of a stored file name, so the name
+// doesn't grow with every dump and seed cycle.
+func uploadName(storedName string) string {
+	ext := filepath.Ext(storedName)
+	return storedNameSuffix.ReplaceAllString(strings.TrimSuffix(storedName, ext), "") + ext
+}
+
+func getFilesContent(fsys *filesystem.System, record *core.Record, fieldName string) (Files, error) {
+	files := Files{}
+	for _, storedName := range record.GetStringSlice(fieldName) {
+		content, err := readRecordFile(fsys, record, storedName)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: uploadName(storedName), Content: content})
+	}
+	return files, nil
 }
 
 func dumpItem[T any](record *core.Record, fsys *filesystem.System, fields []pbFieldInfo) (dbx.Params, error) {
+	if mapping, ok := generatedMappingOf(*new(T)); ok {
+		return mapping.fromRecord(fsys, record)
+	}
 	params := dbx.Params{}
 	for _, fd := range fields {
 		if fd.isFileExt {
@@ -246,19 +403,37 @@ func dumpItem[T any](record *core.Record, fsys *filesystem.System, fields []pbFi
 			}
 			continue
 		}
+		if fd.isFiles {
+			files, err := getFilesContent(fsys, record, fd.pbKey)
+			if err != nil {
+				return nil, err
+			}
+			params[fd.dbKey] = files
+			continue
+		}
 		if fd.isJSON {
-			b, err := json.Marshal(record.GetStringSlice(fd.pbKey))
+			var value any = record.GetStringSlice(fd.pbKey)
+			if !isStringSlice(fd.goType) {
+				value = record.Get(fd.pbKey)
+			}
+			b, err := json.Marshal(value)
 			if err != nil {
 				return nil, err
 			}
 			params[fd.dbKey] = b
 			continue
 		}
+		if fd.goType == dateTimeType {
+			params[fd.dbKey] = record.GetDateTime(fd.pbKey).String()
+			continue
+		}
 		switch fd.goType.Kind() {
 		case reflect.String:
 			params[fd.dbKey] = record.GetString(fd.pbKey)
 		case reflect.Int, reflect.Int64:
 			params[fd.dbKey] = record.GetInt(fd.pbKey)
+		case reflect.Float32, reflect.Float64:
+			params[fd.dbKey] = record.GetFloat(fd.pbKey)
 		case reflect.Bool:
 			params[fd.dbKey] = record.GetBool(fd.pbKey)
 		default:
@@ -268,7 +443,7 @@ func dumpItem[T any](record *core.Record, fsys *filesystem.System, fields []pbFi
 	return params, nil
 }
 
-func dumpCollection[T any](app core.App, fsys *filesystem.System, db dbx.Builder, sourceTable string) error {
+func dumpCollection[T any](app core.App, fsys *filesystem.System, db dbx.Builder, sourceTable string, progress Progress) error {
 	app.Logger().Debug(fmt.Sprintf("Dumping %v", sourceTable))
 	records, err := app.FindAllRecords(sourceTable)
 	if err != nil {
@@ -276,7 +451,8 @@ func dumpCollection[T any](app core.App, fsys *filesystem.System, db dbx.Builder
 	}
 
 	fields := mustGetFieldInfo[T]()
-	for _, s := range records {
+	progress.report(sourceTable, 0, len(records))
+	for i, s := range records {
 		params, err := dumpItem[T](s, fsys, fields)
 		if err != nil {
 			return err
@@ -284,6 +460,7 @@ func dumpCollection[T any](app core.App, fsys *filesystem.System, db dbx.Builder
 		if _, err := db.Insert(sourceTable, params).Execute(); err != nil {
 			return err
 		}
+		progress.report(sourceTable, i+1, len(records))
 	}
 
 	app.Logger().Debug(fmt.Sprintf("Dumped %v", sourceTable))
@@ -21,7 +21,8 @@ func mustDecodeBase64(v string) []byte {
 	return b
 }
 
-func icon(t testing.TB, name string) *filesystem.File {
+// Icon returns a png file with the given name.
+func Icon(t testing.TB, name string) *filesystem.File {
 	t.Helper()
 	file, err := filesystem.NewFileFromBytes(PngContent, name)
 	if err != nil {
@@ -60,7 +61,7 @@ func SeedDictionaries(t testing.TB, app core.App) {
 		"name": "ATK%", "order": 2, "substat": false,
 	})
 	CreateRecord(t, app, models.ELEMENTS_COLLECTION_NAME, "elementpyro0000", map[string]any{
-		"name": "Pyro", "color": "#ff5722", "inverseTextColor": true, "icon": icon(t, "pyro.png"),
+		"name": "Pyro", "color": "#ff5722", "inverseTextColor": true, "icon": Icon(t, "pyro.png"),
 	})
 	CreateRecord(t, app, models.CHARACTER_ROLES_COLLECTION_NAME, "charrolemaindps", map[string]any{
 		"name": "Main DPS",
@@ -69,14 +70,14 @@ func SeedDictionaries(t testing.TB, app core.App) {
 		"major": 5, "patch": 1,
 	})
 	CreateRecord(t, app, models.WEAPON_TYPES_COLLECTION_NAME, "weapontypesword", map[string]any{
-		"name": "Sword", "icon": icon(t, "sword.png"),
+		"name": "Sword", "icon": Icon(t, "sword.png"),
 	})
 	CreateRecord(t, app, models.ARTIFACT_SETS_COLLECTION_NAME, "artsetgladiator", map[string]any{
 		"name": "Gladiator's Finale", "rarity": 5, "patch": "patch5dot100000",
-		"useless": false, "icon": icon(t, "gladiator.png"),
+		"useless": false, "icon": Icon(t, "gladiator.png"),
 	})
 	CreateRecord(t, app, models.ARTIFACT_TYPES_COLLECTION_NAME, "arttypeflower00", map[string]any{
-		"name": "Flower of Life", "order": 1, "icon": icon(t, "flower.png"),
+		"name": "Flower of Life", "order": 1, "icon": Icon(t, "flower.png"),
 		"specials": []string{"spcritrate00000", "spatkpercent000"},
 	})
 	CreateRecord(t, app, models.DOMAINS_OF_BLESSING_COLLECTION_NAME, "domainofvalor00", map[string]any{
@@ -85,11 +86,67 @@ func SeedDictionaries(t testing.TB, app core.App) {
 	CreateRecord(t, app, models.WEAPONS_COLLECTION_NAME, "weaponaquila000", map[string]any{
 		"name": "Aquila Favonia", "rarity": 5, "weaponType": "weapontypesword",
 		"special": "spatkpercent000", "patch": "patch5dot100000", "useless": false,
-		"icon": icon(t, "aquila.png"),
+		"icon": Icon(t, "aquila.png"),
 	})
 	CreateRecord(t, app, models.CHARACTERS_COLLECTION_NAME, "characterdiluc0", map[string]any{
 		"name": "Diluc", "rarity": 5, "element": "elementpyro0000",
 		"weaponType": "weapontypesword", "special": "spcritrate00000",
-		"patch": "patch5dot100000", "icon": icon(t, "diluc.png"),
+		"patch": "patch5dot100000", "icon": Icon(t, "diluc.png"),
+	})
+}
+
+// CreateUser saves a verified user with the given email and returns it along
+// with an auth token for the api requests.
+func CreateUser(t testing.TB, app core.App, email string) (*core.Record, string) {
+	t.Helper()
+	collection, err := app.FindCollectionByNameOrId(models.USERS_COLLECTION_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := core.NewRecord(collection)
+	record.SetEmail(email)
+	record.SetPassword("testtest")
+	record.SetVerified(true)
+	if err := app.Save(record); err != nil {
+		t.Fatalf("user %q: %v", email, err)
+	}
+	token, err := record.NewAuthToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record, token
+}
+
+// SeedPlans saves two plans in the default game profile of the user on top of
+// SeedDictionaries: an incomplete one with a child plan of every kind, and a
+// complete one without children.
+func SeedPlans(t testing.TB, app core.App, userId string) {
+	t.Helper()
+
+	profile, err := models.FindDefaultGameProfile(app, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan00", map[string]any{
+		"user": userId, "profile": profile.Id, "character": "characterdiluc0", "order": 1,
+		"characterRole": "charrolemaindps", "substats": []string{"spcritrate00000"},
+		"levelCurrent": 80, "levelTarget": 90,
+	})
+	CreateRecord(t, app, models.WEAPON_PLANS_COLLECTION_NAME, "weaponplan00000", map[string]any{
+		"characterPlan": "characterplan00", "weapon": "weaponaquila000", "order": 1, "tag": "current",
+		"levelCurrent": 70, "levelTarget": 90, "refinementCurrent": 1, "refinementTarget": 1,
+	})
+	CreateRecord(t, app, models.ARTIFACT_SETS_PLANS_COLLECTION_NAME, "artsetsplan0000", map[string]any{
+		"characterPlan": "characterplan00", "artifactSets": []string{"artsetgladiator"}, "order": 1,
+	})
+	CreateRecord(t, app, models.ARTIFACT_TYPE_PLANS_COLLECTION_NAME, "arttypeplan0000", map[string]any{
+		"characterPlan": "characterplan00", "artifactType": "arttypeflower00", "special": "spcritrate00000",
+	})
+	CreateRecord(t, app, models.TEAM_PLANS_COLLECTION_NAME, "teamplan0000000", map[string]any{
+		"characterPlan": "characterplan00", "characters": []string{"characterdiluc0"},
+	})
+	CreateRecord(t, app, models.CHARACTER_PLANS_COLLECTION_NAME, "characterplan01", map[string]any{
+		"user": userId, "profile": profile.Id, "character": "characterdiluc0", "order": 2, "complete": true,
 	})
 }
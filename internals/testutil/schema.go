@@ -0,0 +1,18 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/seed"
+)
+
+// RequireSeedSchema fails the test when a dictionary seed struct doesn't
+// conform to the migrated collections of the app.
+func RequireSeedSchema(t testing.TB, app core.App) {
+	t.Helper()
+	for _, issue := range seed.CheckSchema(app) {
+		t.Error(issue)
+	}
+}
@@ -0,0 +1,224 @@
+// Package workspaces shares plans between users: a workspace has members with
+// a role each, the plans put in a workspace can be read by its members and
+// written by its owners and editors. The users join through invitations.
+package workspaces
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+)
+
+var (
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrInvalidRole        = errors.New("unknown workspace role")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationExpired  = errors.New("the invitation has expired")
+	ErrAlreadyMember      = errors.New("already a member of the workspace")
+)
+
+// fillRoles sets the members, writers and owners fields of the workspace from
+// its membership records, the rules of the plans collections match them.
+func fillRoles(app core.App, workspace *core.Record) error {
+	members := []string{}
+	writers := []string{}
+	owners := []string{}
+	if !workspace.IsNew() {
+		memberships, err := models.FindWorkspaceMembers(app, workspace.Id)
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(memberships, func(a, b *models.WorkspaceMember) int {
+			return strings.Compare(a.UserId(), b.UserId())
+		})
+		for _, member := range memberships {
+			members = append(members, member.UserId())
+			if member.CanWrite() {
+				writers = append(writers, member.UserId())
+			}
+			if member.Role() == models.WORKSPACE_ROLE_OWNER {
+				owners = append(owners, member.UserId())
+			}
+		}
+	}
+	workspace.Set("members", members)
+	workspace.Set("writers", writers)
+	workspace.Set("owners", owners)
+	return nil
+}
+
+// syncRoles saves the roles of the members on the workspace, unless it is
+// gone with its memberships.
+func syncRoles(app core.App, workspaceId string) error {
+	workspace, err := app.FindRecordById(models.WORKSPACES_COLLECTION_NAME, workspaceId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return app.Save(workspace)
+}
+
+// Bind keeps the roles of the workspaces in sync with their members, makes
+// the creator of a workspace its owner and keeps an owner in every
+// workspace.
+func Bind(app core.App) {
+	fill := func(e *core.RecordEvent) error {
+		if err := fillRoles(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	}
+	app.OnRecordCreate(models.WORKSPACES_COLLECTION_NAME).BindFunc(fill)
+	app.OnRecordUpdate(models.WORKSPACES_COLLECTION_NAME).BindFunc(fill)
+
+	sync := func(e *core.RecordEvent) error {
+		if err := e.Next(); err != nil {
+			return err
+		}
+		workspaceIds := []string{e.Record.GetString("workspace"), e.Record.Original().GetString("workspace")}
+		for _, workspaceId := range slices.Compact(workspaceIds) {
+			if workspaceId == "" {
+				continue
+			}
+			if err := syncRoles(e.App, workspaceId); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	app.OnRecordCreate(models.WORKSPACE_MEMBERS_COLLECTION_NAME).BindFunc(sync)
+	app.OnRecordUpdate(models.WORKSPACE_MEMBERS_COLLECTION_NAME).BindFunc(sync)
+	app.OnRecordDelete(models.WORKSPACE_MEMBERS_COLLECTION_NAME).BindFunc(sync)
+
+	app.OnRecordCreateRequest(models.WORKSPACES_COLLECTION_NAME).BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.Auth == nil || e.Auth.Collection().Name != models.USERS_COLLECTION_NAME {
+			return e.Next()
+		}
+		return e.App.RunInTransaction(func(txApp core.App) error {
+			e.App = txApp
+			if err := e.Next(); err != nil {
+				return err
+			}
+			owner, err := models.NewWorkspaceMember(txApp, e.Record.Id, e.Auth.Id, models.WORKSPACE_ROLE_OWNER)
+			if err != nil {
+				return err
+			}
+			if err := txApp.Save(owner); err != nil {
+				return err
+			}
+			// the response carries the roles of the owner membership
+			return fillRoles(txApp, e.Record)
+		})
+	})
+
+	app.OnRecordUpdateRequest(models.WORKSPACE_MEMBERS_COLLECTION_NAME).BindFunc(func(e *core.RecordRequestEvent) error {
+		original := e.Record.Original()
+		if e.Record.GetString("user") != original.GetString("user") || e.Record.GetString("workspace") != original.GetString("workspace") {
+			return e.BadRequestError("Only the role of a member can change.", nil)
+		}
+		if original.GetString("role") == models.WORKSPACE_ROLE_OWNER && e.Record.GetString("role") != models.WORKSPACE_ROLE_OWNER {
+			if err := checkOtherOwner(e); err != nil {
+				return err
+			}
+		}
+		return e.Next()
+	})
+	app.OnRecordDeleteRequest(models.WORKSPACE_MEMBERS_COLLECTION_NAME).BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.Record.GetString("role") == models.WORKSPACE_ROLE_OWNER {
+			if err := checkOtherOwner(e); err != nil {
+				return err
+			}
+		}
+		return e.Next()
+	})
+}
+
+// checkOtherOwner rejects the request taking the last owner of a workspace,
+// the workspace is deleted instead.
+func checkOtherOwner(e *core.RecordRequestEvent) error {
+	members, err := models.FindWorkspaceMembers(e.App, e.Record.GetString("workspace"))
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member.Id != e.Record.Id && member.Role() == models.WORKSPACE_ROLE_OWNER {
+			return nil
+		}
+	}
+	return e.Error(http.StatusBadRequest, "A workspace needs an owner, delete the workspace instead.", nil)
+}
+
+// FindMember returns the membership of the user in the workspace,
+// ErrWorkspaceNotFound if the user isn't a member.
+func FindMember(app core.App, workspaceId string, userId string) (*models.WorkspaceMember, error) {
+	member, err := models.FindWorkspaceMember(app, workspaceId, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkspaceNotFound
+	}
+	return member, err
+}
+
+// Invite creates an invitation to the workspace with the role, the inviter
+// has to own the workspace. With an email only the user of that email can
+// accept it.
+func Invite(app core.App, workspaceId string, inviterId string, role string, email string) (*models.WorkspaceInvitation, error) {
+	if !slices.Contains(models.WORKSPACE_ROLES, role) {
+		return nil, ErrInvalidRole
+	}
+	inviter, err := FindMember(app, workspaceId, inviterId)
+	if err != nil {
+		return nil, err
+	}
+	if inviter.Role() != models.WORKSPACE_ROLE_OWNER {
+		return nil, ErrWorkspaceNotFound
+	}
+	invitation, err := models.NewWorkspaceInvitation(app, workspaceId, role, strings.TrimSpace(email))
+	if err != nil {
+		return nil, err
+	}
+	return invitation, app.Save(invitation)
+}
+
+// Accept makes the user a member of the workspace of the invitation with its
+// role, the invitation is used up.
+func Accept(app core.App, token string, user *core.Record) (*models.WorkspaceMember, error) {
+	var member *models.WorkspaceMember
+	err := app.RunInTransaction(func(txApp core.App) error {
+		invitation, err := models.FindWorkspaceInvitationByToken(txApp, token)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvitationNotFound
+		} else if err != nil {
+			return err
+		}
+		if invitation.Email() != "" && !strings.EqualFold(invitation.Email(), user.Email()) {
+			return ErrInvitationNotFound
+		}
+		if invitation.IsExpired() {
+			return ErrInvitationExpired
+		}
+		if _, err := FindMember(txApp, invitation.WorkspaceId(), user.Id); err == nil {
+			return ErrAlreadyMember
+		} else if !errors.Is(err, ErrWorkspaceNotFound) {
+			return err
+		}
+		member, err = models.NewWorkspaceMember(txApp, invitation.WorkspaceId(), user.Id, invitation.Role())
+		if err != nil {
+			return err
+		}
+		if err := txApp.Save(member); err != nil {
+			return err
+		}
+		return txApp.Delete(invitation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
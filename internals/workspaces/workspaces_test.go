@@ -0,0 +1,134 @@
+package workspaces_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/qxuken/gbp/internals/models"
+	"github.com/qxuken/gbp/internals/testutil"
+	"github.com/qxuken/gbp/internals/workspaces"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// roles returns the synced role fields of the workspace.
+func roles(t *testing.T, app core.App, workspaceId string) (members []string, writers []string, owners []string) {
+	t.Helper()
+	workspace, err := app.FindRecordById(models.WORKSPACES_COLLECTION_NAME, workspaceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return workspace.GetStringSlice("members"), workspace.GetStringSlice("writers"), workspace.GetStringSlice("owners")
+}
+
+func TestRolesSync(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	workspaces.Bind(app)
+	owner, _ := testutil.CreateUser(t, app, "owner@test.com")
+	editor, _ := testutil.CreateUser(t, app, "editor@test.com")
+	testutil.CreateRecord(t, app, models.WORKSPACES_COLLECTION_NAME, "workspace000000", map[string]any{
+		"name": "Abyss", "members": []string{editor.Id}, "owners": []string{editor.Id},
+	})
+	// the role fields only come from the members
+	if members, _, owners := roles(t, app, "workspace000000"); len(members) != 0 || len(owners) != 0 {
+		t.Fatalf("expected no roles, got members %v owners %v", members, owners)
+	}
+
+	testutil.CreateRecord(t, app, models.WORKSPACE_MEMBERS_COLLECTION_NAME, "memberowner0000", map[string]any{
+		"workspace": "workspace000000", "user": owner.Id, "role": models.WORKSPACE_ROLE_OWNER,
+	})
+	member := testutil.CreateRecord(t, app, models.WORKSPACE_MEMBERS_COLLECTION_NAME, "membereditor000", map[string]any{
+		"workspace": "workspace000000", "user": editor.Id, "role": models.WORKSPACE_ROLE_VIEWER,
+	})
+	members, writers, owners := roles(t, app, "workspace000000")
+	if len(members) != 2 || !slices.Equal(writers, []string{owner.Id}) || !slices.Equal(owners, []string{owner.Id}) {
+		t.Errorf("viewer: got members %v writers %v owners %v", members, writers, owners)
+	}
+
+	member.Set("role", models.WORKSPACE_ROLE_EDITOR)
+	if err := app.Save(member); err != nil {
+		t.Fatal(err)
+	}
+	if _, writers, _ := roles(t, app, "workspace000000"); len(writers) != 2 {
+		t.Errorf("editor: expected two writers, got %v", writers)
+	}
+
+	if err := app.Delete(member); err != nil {
+		t.Fatal(err)
+	}
+	if members, writers, _ := roles(t, app, "workspace000000"); !slices.Equal(members, []string{owner.Id}) || !slices.Equal(writers, []string{owner.Id}) {
+		t.Errorf("removed: got members %v writers %v", members, writers)
+	}
+}
+
+func TestInvitations(t *testing.T) {
+	app := testutil.NewTestApp(t)
+	workspaces.Bind(app)
+	owner, _ := testutil.CreateUser(t, app, "owner@test.com")
+	viewer, _ := testutil.CreateUser(t, app, "viewer@test.com")
+	other, _ := testutil.CreateUser(t, app, "other@test.com")
+	testutil.CreateRecord(t, app, models.WORKSPACES_COLLECTION_NAME, "workspace000000", map[string]any{
+		"name": "Abyss",
+	})
+	testutil.CreateRecord(t, app, models.WORKSPACE_MEMBERS_COLLECTION_NAME, "memberowner0000", map[string]any{
+		"workspace": "workspace000000", "user": owner.Id, "role": models.WORKSPACE_ROLE_OWNER,
+	})
+
+	if _, err := workspaces.Invite(app, "workspace000000", owner.Id, "admin", ""); !errors.Is(err, workspaces.ErrInvalidRole) {
+		t.Errorf("unknown role: expected invalid role, got %v", err)
+	}
+	if _, err := workspaces.Invite(app, "workspace000000", other.Id, models.WORKSPACE_ROLE_VIEWER, ""); !errors.Is(err, workspaces.ErrWorkspaceNotFound) {
+		t.Errorf("not a member: expected not found, got %v", err)
+	}
+
+	invitation, err := workspaces.Invite(app, "workspace000000", owner.Id, models.WORKSPACE_ROLE_VIEWER, "viewer@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := workspaces.Accept(app, invitation.Token(), other); !errors.Is(err, workspaces.ErrInvitationNotFound) {
+		t.Errorf("other email: expected not found, got %v", err)
+	}
+	member, err := workspaces.Accept(app, invitation.Token(), viewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.Role() != models.WORKSPACE_ROLE_VIEWER || member.UserId() != viewer.Id {
+		t.Errorf("expected the viewer membership, got %v", member.PublicExport())
+	}
+	if _, err := workspaces.Accept(app, invitation.Token(), viewer); !errors.Is(err, workspaces.ErrInvitationNotFound) {
+		t.Errorf("used up: expected not found, got %v", err)
+	}
+	// a viewer can't invite
+	if _, err := workspaces.Invite(app, "workspace000000", viewer.Id, models.WORKSPACE_ROLE_OWNER, ""); !errors.Is(err, workspaces.ErrWorkspaceNotFound) {
+		t.Errorf("viewer invite: expected not found, got %v", err)
+	}
+
+	again, err := workspaces.Invite(app, "workspace000000", owner.Id, models.WORKSPACE_ROLE_EDITOR, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := workspaces.Accept(app, again.Token(), viewer); !errors.Is(err, workspaces.ErrAlreadyMember) {
+		t.Errorf("member: expected already a member, got %v", err)
+	}
+
+	expired, err := workspaces.Invite(app, "workspace000000", owner.Id, models.WORKSPACE_ROLE_EDITOR, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.Set("expires", time.Now().Add(-time.Minute))
+	if err := app.Save(expired); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := workspaces.Accept(app, expired.Token(), other); !errors.Is(err, workspaces.ErrInvitationExpired) {
+		t.Errorf("expired: expected expired, got %v", err)
+	}
+	if _, err := workspaces.FindMember(app, "workspace000000", other.Id); !errors.Is(err, workspaces.ErrWorkspaceNotFound) {
+		t.Errorf("expected no membership for the expired invitation, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId(models.DB_DUMPS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+
+		// the Content-Encoding token of the stored dump file, empty for a raw sqlite file
+		collection.Fields.Add(&core.SelectField{
+			Name:      "encoding",
+			Values:    []string{"gzip", "zstd"},
+			MaxSelect: 1,
+		})
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId(models.DB_DUMPS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.RemoveByName("encoding")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		if _, err := app.FindCollectionByNameOrId(models.JOBS_COLLECTION_NAME); err == nil {
+			return nil
+		}
+		collection := core.NewBaseCollection(models.JOBS_COLLECTION_NAME)
+		collection.Fields.Add(&core.TextField{
+			Name:        "kind",
+			Required:    true,
+			Presentable: true,
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values: []string{
+				models.JOB_STATUS_RUNNING,
+				models.JOB_STATUS_DONE,
+				models.JOB_STATUS_FAILED,
+			},
+		})
+		collection.Fields.Add(&core.JSONField{
+			Name: "progress",
+		})
+		collection.Fields.Add(&core.JSONField{
+			Name: "result",
+		})
+		collection.Fields.Add(&core.TextField{
+			Name: "error",
+		})
+		collection.Fields.Add(&core.AutodateField{
+			Name:     "created",
+			System:   true,
+			OnCreate: true,
+		})
+		collection.Fields.Add(&core.AutodateField{
+			Name:     "updated",
+			System:   true,
+			OnCreate: true,
+			OnUpdate: true,
+		})
+		collection.AddIndex("idx_"+models.JOBS_COLLECTION_NAME+"_status", false, "`status`", "")
+		collection.System = true
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId(models.JOBS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		if _, err := app.FindCollectionByNameOrId(models.ICONS_COLLECTION_NAME); err == nil {
+			return nil
+		}
+		collection := core.NewBaseCollection(models.ICONS_COLLECTION_NAME)
+		collection.Fields.Add(&core.TextField{
+			Name:        "hash",
+			Required:    true,
+			Presentable: true,
+		})
+		collection.Fields.Add(&core.FileField{
+			Name:      "image",
+			Required:  true,
+			MaxSelect: 1,
+			MimeTypes: []string{"image/webp"},
+		})
+		collection.Fields.Add(&core.FileField{
+			Name:      "thumb",
+			Required:  true,
+			MaxSelect: 1,
+			MimeTypes: []string{"image/webp"},
+		})
+		collection.Fields.Add(&core.NumberField{
+			Name:    "width",
+			OnlyInt: true,
+		})
+		collection.Fields.Add(&core.NumberField{
+			Name:    "height",
+			OnlyInt: true,
+		})
+		collection.Fields.Add(&core.AutodateField{
+			Name:     "created",
+			System:   true,
+			OnCreate: true,
+		})
+		collection.AddIndex("idx_"+models.ICONS_COLLECTION_NAME+"_hash", true, "`hash`", "")
+		collection.System = true
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId(models.ICONS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		if _, err := app.FindCollectionByNameOrId(models.TRANSLATIONS_COLLECTION_NAME); err == nil {
+			return nil
+		}
+		collection := core.NewBaseCollection(models.TRANSLATIONS_COLLECTION_NAME)
+		collection.Fields.Add(&core.SelectField{
+			Name:      "collection",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    models.TRANSLATED_COLLECTIONS,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "record",
+			Required: true,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "locale",
+			Required: true,
+			Pattern:  `^[a-z]{2,3}(-[a-z0-9]{2,8})*$`,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:        "name",
+			Required:    true,
+			Presentable: true,
+		})
+		collection.AddIndex("idx_"+models.TRANSLATIONS_COLLECTION_NAME+"_record_locale", true, "`collection`, `record`, `locale`", "")
+		collection.ListRule = types.Pointer("")
+		collection.ViewRule = types.Pointer("")
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId(models.TRANSLATIONS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+var aliasesCollections = []string{
+	models.CHARACTERS_COLLECTION_NAME,
+	models.WEAPONS_COLLECTION_NAME,
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		for _, name := range aliasesCollections {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			if collection.Fields.GetByName("aliases") != nil {
+				continue
+			}
+			collection.Fields.Add(&core.JSONField{
+				Name: "aliases",
+			})
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		for _, name := range aliasesCollections {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			collection.Fields.RemoveByName("aliases")
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,186 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// the plans created without a profile get the default one of the user, see
+// plans.BindProfiles, so the create rule lets an empty profile through
+const (
+	profilePlanRule       = `@request.auth.id != "" && user = @request.auth.id && profile.user = @request.auth.id`
+	profilePlanCreateRule = `@request.auth.id != "" && user = @request.auth.id && (profile = "" || profile.user = @request.auth.id)`
+	profileChildRule      = `@request.auth.id != "" && characterPlan.user = @request.auth.id && characterPlan.profile.user = @request.auth.id`
+	userPlanRule          = `@request.auth.id != "" && user = @request.auth.id`
+	userChildRule         = `@request.auth.id != "" && characterPlan.user = @request.auth.id`
+)
+
+var profileChildCollections = []string{
+	models.WEAPON_PLANS_COLLECTION_NAME,
+	models.ARTIFACT_SETS_PLANS_COLLECTION_NAME,
+	models.ARTIFACT_TYPE_PLANS_COLLECTION_NAME,
+	models.TEAM_PLANS_COLLECTION_NAME,
+}
+
+func setRules(collection *core.Collection, rule string, createRule string) {
+	collection.ListRule = types.Pointer(rule)
+	collection.ViewRule = types.Pointer(rule)
+	collection.CreateRule = types.Pointer(createRule)
+	collection.UpdateRule = types.Pointer(rule)
+	collection.DeleteRule = types.Pointer(rule)
+}
+
+// replaceInViewQuery replaces the anchor in the query of the view, it fails
+// when the query misses it rather than leave the view as it was.
+func replaceInViewQuery(view *core.Collection, anchor string, replacement string) error {
+	if !strings.Contains(view.ViewQuery, anchor) {
+		return fmt.Errorf("%s view query misses %q", view.Name, anchor)
+	}
+	view.ViewQuery = strings.Replace(view.ViewQuery, anchor, replacement, 1)
+	return nil
+}
+
+func setChildRules(app core.App, rule string) error {
+	for _, name := range profileChildCollections {
+		collection, err := app.FindCollectionByNameOrId(name)
+		if err != nil {
+			return err
+		}
+		setRules(collection, rule, rule)
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId(models.USERS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		profiles := core.NewBaseCollection(models.GAME_PROFILES_COLLECTION_NAME)
+		profiles.Fields.Add(&core.RelationField{
+			Name:          "user",
+			Required:      true,
+			CollectionId:  users.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		profiles.Fields.Add(&core.TextField{
+			Name:        "name",
+			Required:    true,
+			Presentable: true,
+			Max:         64,
+		})
+		profiles.Fields.Add(&core.SelectField{
+			Name:      "serverRegion",
+			MaxSelect: 1,
+			Values:    models.GAME_SERVER_REGIONS,
+		})
+		profiles.Fields.Add(&core.TextField{
+			Name:    "uid",
+			Pattern: `^[0-9]{9,10}$`,
+		})
+		profiles.Fields.Add(&core.AutodateField{
+			Name:     "created",
+			OnCreate: true,
+		})
+		profiles.Fields.Add(&core.AutodateField{
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+		profiles.AddIndex("idx_"+models.GAME_PROFILES_COLLECTION_NAME+"_user_name", true, "`user`, `name`", "")
+		setRules(profiles, userPlanRule, userPlanRule)
+		if err := app.Save(profiles); err != nil {
+			return err
+		}
+
+		characterPlans, err := app.FindCollectionByNameOrId(models.CHARACTER_PLANS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans.Fields.Add(&core.RelationField{
+			Name:          "profile",
+			CollectionId:  profiles.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		characterPlans.AddIndex("idx_"+models.CHARACTER_PLANS_COLLECTION_NAME+"_profile", false, "`profile`", "")
+		setRules(characterPlans, profilePlanRule, profilePlanCreateRule)
+		if err := app.Save(characterPlans); err != nil {
+			return err
+		}
+		if err := setChildRules(app, profileChildRule); err != nil {
+			return err
+		}
+
+		// the existing plans go to a default profile of their user
+		userIds := []string{}
+		err = app.DB().Select("user").Distinct(true).From(models.CHARACTER_PLANS_COLLECTION_NAME).Column(&userIds)
+		if err != nil {
+			return err
+		}
+		for _, userId := range userIds {
+			profile, err := models.FindDefaultGameProfile(app, userId)
+			if err != nil {
+				return err
+			}
+			_, err = app.DB().Update(models.CHARACTER_PLANS_COLLECTION_NAME,
+				dbx.Params{"profile": profile.Id},
+				dbx.HashExp{"user": userId, "profile": ""},
+			).Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		view, err := app.FindCollectionByNameOrId(models.PLANS_VIEW_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		if err := replaceInViewQuery(view, "  cp.user,\n", "  cp.user,\n  cp.profile,\n"); err != nil {
+			return err
+		}
+		return app.Save(view)
+	}, func(app core.App) error {
+		view, err := app.FindCollectionByNameOrId(models.PLANS_VIEW_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		if err := replaceInViewQuery(view, "  cp.profile,\n", ""); err != nil {
+			return err
+		}
+		if err := app.Save(view); err != nil {
+			return err
+		}
+
+		if err := setChildRules(app, userChildRule); err != nil {
+			return err
+		}
+		characterPlans, err := app.FindCollectionByNameOrId(models.CHARACTER_PLANS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans.Fields.RemoveByName("profile")
+		characterPlans.RemoveIndex("idx_" + models.CHARACTER_PLANS_COLLECTION_NAME + "_profile")
+		setRules(characterPlans, userPlanRule, userPlanRule)
+		if err := app.Save(characterPlans); err != nil {
+			return err
+		}
+
+		profiles, err := app.FindCollectionByNameOrId(models.GAME_PROFILES_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		return app.Delete(profiles)
+	})
+}
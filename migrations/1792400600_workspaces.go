@@ -0,0 +1,236 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// The workspaces keep the user ids of their members, writers (owners and
+// editors) and owners in relation fields synced from the workspaceMembers
+// records, see workspaces.Bind, so that the rules can match a single role
+// of a member.
+const (
+	workspaceOwnedPlan   = `(user = @request.auth.id && profile.user = @request.auth.id)`
+	workspacePlanRead    = `@request.auth.id != "" && (` + workspaceOwnedPlan + ` || workspace.members.id ?= @request.auth.id)`
+	workspacePlanWrite   = `@request.auth.id != "" && (` + workspaceOwnedPlan + ` || workspace.writers.id ?= @request.auth.id)`
+	workspacePlanMove    = ` && (@request.body.user:isset = false || @request.body.user = user) && (@request.body.workspace:isset = false || @request.body.workspace = workspace || user = @request.auth.id && (@request.body.workspace = "" || @request.body.workspace.writers.id ?= @request.auth.id))`
+	workspacePlanCreate  = `@request.auth.id != "" && user = @request.auth.id && (profile = "" || profile.user = @request.auth.id) && (workspace = "" || workspace.writers.id ?= @request.auth.id)`
+	workspaceOwnedChild  = `(characterPlan.user = @request.auth.id && characterPlan.profile.user = @request.auth.id)`
+	workspaceChildRead   = `@request.auth.id != "" && (` + workspaceOwnedChild + ` || characterPlan.workspace.members.id ?= @request.auth.id)`
+	workspaceChildWrite  = `@request.auth.id != "" && (` + workspaceOwnedChild + ` || characterPlan.workspace.writers.id ?= @request.auth.id)`
+	workspaceChildMove   = ` && (@request.body.characterPlan:isset = false || @request.body.characterPlan = characterPlan)`
+	workspaceMemberRule  = `@request.auth.id != "" && members.id ?= @request.auth.id`
+	workspaceOwnerRule   = `@request.auth.id != "" && owners.id ?= @request.auth.id`
+	workspaceOfMember    = `@request.auth.id != "" && workspace.members.id ?= @request.auth.id`
+	workspaceOfOwner     = `@request.auth.id != "" && workspace.owners.id ?= @request.auth.id`
+	workspaceMemberLeave = `@request.auth.id != "" && (workspace.owners.id ?= @request.auth.id || user = @request.auth.id)`
+)
+
+func setPlanRules(collection *core.Collection, read string, create string, update string, delete string) {
+	collection.ListRule = types.Pointer(read)
+	collection.ViewRule = types.Pointer(read)
+	collection.CreateRule = types.Pointer(create)
+	collection.UpdateRule = types.Pointer(update)
+	collection.DeleteRule = types.Pointer(delete)
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId(models.USERS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+
+		workspaces := core.NewBaseCollection(models.WORKSPACES_COLLECTION_NAME)
+		workspaces.Fields.Add(&core.TextField{
+			Name:        "name",
+			Required:    true,
+			Presentable: true,
+			Max:         64,
+		})
+		for _, name := range []string{"members", "writers", "owners"} {
+			workspaces.Fields.Add(&core.RelationField{
+				Name:         name,
+				CollectionId: users.Id,
+				MaxSelect:    1000,
+			})
+		}
+		workspaces.Fields.Add(&core.AutodateField{
+			Name:     "created",
+			OnCreate: true,
+		})
+		workspaces.Fields.Add(&core.AutodateField{
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+		workspaces.ListRule = types.Pointer(workspaceMemberRule)
+		workspaces.ViewRule = types.Pointer(workspaceMemberRule)
+		workspaces.CreateRule = types.Pointer(`@request.auth.id != ""`)
+		workspaces.UpdateRule = types.Pointer(workspaceOwnerRule)
+		workspaces.DeleteRule = types.Pointer(workspaceOwnerRule)
+		if err := app.Save(workspaces); err != nil {
+			return err
+		}
+
+		members := core.NewBaseCollection(models.WORKSPACE_MEMBERS_COLLECTION_NAME)
+		members.Fields.Add(&core.RelationField{
+			Name:          "workspace",
+			Required:      true,
+			CollectionId:  workspaces.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		members.Fields.Add(&core.RelationField{
+			Name:          "user",
+			Required:      true,
+			CollectionId:  users.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		members.Fields.Add(&core.SelectField{
+			Name:      "role",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    models.WORKSPACE_ROLES,
+		})
+		members.Fields.Add(&core.AutodateField{
+			Name:     "created",
+			OnCreate: true,
+		})
+		members.Fields.Add(&core.AutodateField{
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+		members.AddIndex("idx_"+models.WORKSPACE_MEMBERS_COLLECTION_NAME+"_workspace_user", true, "`workspace`, `user`", "")
+		members.AddIndex("idx_"+models.WORKSPACE_MEMBERS_COLLECTION_NAME+"_user", false, "`user`", "")
+		// the members join through the invitations
+		members.ListRule = types.Pointer(workspaceOfMember)
+		members.ViewRule = types.Pointer(workspaceOfMember)
+		members.UpdateRule = types.Pointer(workspaceOfOwner)
+		members.DeleteRule = types.Pointer(workspaceMemberLeave)
+		if err := app.Save(members); err != nil {
+			return err
+		}
+
+		invitations := core.NewBaseCollection(models.WORKSPACE_INVITATIONS_COLLECTION_NAME)
+		invitations.Fields.Add(&core.RelationField{
+			Name:          "workspace",
+			Required:      true,
+			CollectionId:  workspaces.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		invitations.Fields.Add(&core.SelectField{
+			Name:      "role",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    models.WORKSPACE_ROLES,
+		})
+		invitations.Fields.Add(&core.EmailField{
+			Name: "email",
+		})
+		invitations.Fields.Add(&core.TextField{
+			Name:     "token",
+			Required: true,
+			Hidden:   true,
+		})
+		invitations.Fields.Add(&core.DateField{
+			Name:     "expires",
+			Required: true,
+		})
+		invitations.Fields.Add(&core.AutodateField{
+			Name:     "created",
+			OnCreate: true,
+		})
+		invitations.AddIndex("idx_"+models.WORKSPACE_INVITATIONS_COLLECTION_NAME+"_token", true, "`token`", "")
+		// the invitations are made and accepted through the workspace routes
+		invitations.ListRule = types.Pointer(workspaceOfOwner)
+		invitations.ViewRule = types.Pointer(workspaceOfOwner)
+		invitations.DeleteRule = types.Pointer(workspaceOfOwner)
+		if err := app.Save(invitations); err != nil {
+			return err
+		}
+
+		characterPlans, err := app.FindCollectionByNameOrId(models.CHARACTER_PLANS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans.Fields.Add(&core.RelationField{
+			Name:         "workspace",
+			CollectionId: workspaces.Id,
+			MaxSelect:    1,
+		})
+		characterPlans.AddIndex("idx_"+models.CHARACTER_PLANS_COLLECTION_NAME+"_workspace", false, "`workspace`", "")
+		setPlanRules(characterPlans, workspacePlanRead, workspacePlanCreate, workspacePlanWrite+workspacePlanMove, workspacePlanWrite)
+		if err := app.Save(characterPlans); err != nil {
+			return err
+		}
+		for _, name := range profileChildCollections {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			setPlanRules(collection, workspaceChildRead, workspaceChildWrite, workspaceChildWrite+workspaceChildMove, workspaceChildWrite)
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		view, err := app.FindCollectionByNameOrId(models.PLANS_VIEW_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		if err := replaceInViewQuery(view, "  cp.profile,\n", "  cp.profile,\n  cp.workspace,\n"); err != nil {
+			return err
+		}
+		view.ListRule = types.Pointer(workspacePlanRead)
+		view.ViewRule = types.Pointer(workspacePlanRead)
+		return app.Save(view)
+	}, func(app core.App) error {
+		view, err := app.FindCollectionByNameOrId(models.PLANS_VIEW_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		if err := replaceInViewQuery(view, "  cp.workspace,\n", ""); err != nil {
+			return err
+		}
+		view.ListRule = types.Pointer(userPlanRule)
+		view.ViewRule = types.Pointer(userPlanRule)
+		if err := app.Save(view); err != nil {
+			return err
+		}
+
+		if err := setChildRules(app, profileChildRule); err != nil {
+			return err
+		}
+		characterPlans, err := app.FindCollectionByNameOrId(models.CHARACTER_PLANS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans.Fields.RemoveByName("workspace")
+		characterPlans.RemoveIndex("idx_" + models.CHARACTER_PLANS_COLLECTION_NAME + "_workspace")
+		setRules(characterPlans, profilePlanRule, profilePlanCreateRule)
+		if err := app.Save(characterPlans); err != nil {
+			return err
+		}
+
+		for _, name := range []string{
+			models.WORKSPACE_INVITATIONS_COLLECTION_NAME,
+			models.WORKSPACE_MEMBERS_COLLECTION_NAME,
+			models.WORKSPACES_COLLECTION_NAME,
+		} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		patches, err := app.FindCollectionByNameOrId(models.PATCH_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans, err := app.FindCollectionByNameOrId(models.CHARACTER_PLANS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans.Fields.Add(&core.NumberField{
+			Name:    "priority",
+			OnlyInt: true,
+			Min:     types.Pointer(0.0),
+			Max:     types.Pointer(float64(models.MAX_PLAN_PRIORITY)),
+		})
+		characterPlans.Fields.Add(&core.RelationField{
+			Name:         "targetPatch",
+			CollectionId: patches.Id,
+			MaxSelect:    1,
+		})
+		characterPlans.AddIndex("idx_"+models.CHARACTER_PLANS_COLLECTION_NAME+"_targetPatch", false, "`targetPatch`", "")
+		if err := app.Save(characterPlans); err != nil {
+			return err
+		}
+
+		view, err := app.FindCollectionByNameOrId(models.PLANS_VIEW_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		if err := replaceInViewQuery(view, "  cp.workspace,\n", "  cp.workspace,\n  cp.priority,\n  cp.targetPatch,\n"); err != nil {
+			return err
+		}
+		return app.Save(view)
+	}, func(app core.App) error {
+		view, err := app.FindCollectionByNameOrId(models.PLANS_VIEW_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		if err := replaceInViewQuery(view, "  cp.priority,\n  cp.targetPatch,\n", ""); err != nil {
+			return err
+		}
+		if err := app.Save(view); err != nil {
+			return err
+		}
+
+		characterPlans, err := app.FindCollectionByNameOrId(models.CHARACTER_PLANS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans.Fields.RemoveByName("priority")
+		characterPlans.Fields.RemoveByName("targetPatch")
+		characterPlans.RemoveIndex("idx_" + models.CHARACTER_PLANS_COLLECTION_NAME + "_targetPatch")
+		return app.Save(characterPlans)
+	})
+}
@@ -0,0 +1,117 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+// planCompletionFill computes the completion fields of the plans of before
+// them, as plans.BindCompletion would: the mean progress of the targets of
+// the plan and of its weapon plan tagged target, or else current.
+var planCompletionFill = []string{
+	fmt.Sprintf(`
+		UPDATE {{%[1]s}} SET [[completion]] = COALESCE((
+			SELECT CAST(SUM(MIN(MAX(p.cur, 0), p.target) * 1.0 / p.target) / COUNT(*) * 100 AS INTEGER)
+			FROM (
+				SELECT {{%[1]s}}.[[levelCurrent]] AS cur, {{%[1]s}}.[[levelTarget]] AS target
+				UNION ALL SELECT {{%[1]s}}.[[constellationCurrent]], {{%[1]s}}.[[constellationTarget]]
+				UNION ALL SELECT {{%[1]s}}.[[talentAtkCurrent]], {{%[1]s}}.[[talentAtkTarget]]
+				UNION ALL SELECT {{%[1]s}}.[[talentSkillCurrent]], {{%[1]s}}.[[talentSkillTarget]]
+				UNION ALL SELECT {{%[1]s}}.[[talentBurstCurrent]], {{%[1]s}}.[[talentBurstTarget]]
+				UNION ALL SELECT w.[[levelCurrent]], w.[[levelTarget]] FROM (%[2]s) w
+				UNION ALL SELECT w.[[refinementCurrent]], w.[[refinementTarget]] FROM (%[2]s) w
+			) p
+			WHERE p.target > 0
+		), 0)`,
+		models.CHARACTER_PLANS_COLLECTION_NAME,
+		fmt.Sprintf(
+			"SELECT * FROM {{%[2]s}} WHERE [[characterPlan]] = {{%[1]s}}.[[id]] AND [[tag]] IN ('target', 'current') "+
+				"ORDER BY [[tag]] = 'target' DESC, [[order]] ASC, [[created]] ASC LIMIT 1",
+			models.CHARACTER_PLANS_COLLECTION_NAME,
+			models.WEAPON_PLANS_COLLECTION_NAME,
+		),
+	),
+	fmt.Sprintf(
+		"UPDATE {{%s}} SET [[completeSuggested]] = ([[completion]] = 100 AND [[complete]] = FALSE)",
+		models.CHARACTER_PLANS_COLLECTION_NAME,
+	),
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId(models.USERS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		users.Fields.Add(&core.BoolField{
+			Name: "autoCompletePlans",
+		})
+		if err := app.Save(users); err != nil {
+			return err
+		}
+
+		// computed by plans.BindCompletion
+		characterPlans, err := app.FindCollectionByNameOrId(models.CHARACTER_PLANS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans.Fields.Add(&core.NumberField{
+			Name:    "completion",
+			OnlyInt: true,
+			Min:     types.Pointer(0.0),
+			Max:     types.Pointer(100.0),
+		})
+		characterPlans.Fields.Add(&core.BoolField{
+			Name: "completeSuggested",
+		})
+		if err := app.Save(characterPlans); err != nil {
+			return err
+		}
+		for _, query := range planCompletionFill {
+			if _, err := app.DB().NewQuery(query).Execute(); err != nil {
+				return err
+			}
+		}
+
+		view, err := app.FindCollectionByNameOrId(models.PLANS_VIEW_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		if err := replaceInViewQuery(view, "  cp.targetPatch,\n", "  cp.targetPatch,\n  cp.completion,\n  cp.completeSuggested,\n"); err != nil {
+			return err
+		}
+		return app.Save(view)
+	}, func(app core.App) error {
+		view, err := app.FindCollectionByNameOrId(models.PLANS_VIEW_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		if err := replaceInViewQuery(view, "  cp.completion,\n  cp.completeSuggested,\n", ""); err != nil {
+			return err
+		}
+		if err := app.Save(view); err != nil {
+			return err
+		}
+
+		characterPlans, err := app.FindCollectionByNameOrId(models.CHARACTER_PLANS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans.Fields.RemoveByName("completion")
+		characterPlans.Fields.RemoveByName("completeSuggested")
+		if err := app.Save(characterPlans); err != nil {
+			return err
+		}
+
+		users, err := app.FindCollectionByNameOrId(models.USERS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		users.Fields.RemoveByName("autoCompletePlans")
+		return app.Save(users)
+	})
+}
@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/qxuken/gbp/internals/models"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId(models.USERS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		characterPlans, err := app.FindCollectionByNameOrId(models.CHARACTER_PLANS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+
+		snapshots := core.NewBaseCollection(models.PLAN_SNAPSHOTS_COLLECTION_NAME)
+		snapshots.Fields.Add(&core.RelationField{
+			Name:          "user",
+			Required:      true,
+			CollectionId:  users.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		snapshots.Fields.Add(&core.RelationField{
+			Name:          "characterPlan",
+			Required:      true,
+			CollectionId:  characterPlans.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		snapshots.Fields.Add(&core.SelectField{
+			Name:      "reason",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"change", "periodic"},
+		})
+		snapshots.Fields.Add(&core.DateField{
+			Name:     "taken",
+			Required: true,
+		})
+		for _, name := range []string{
+			"level",
+			"constellation",
+			"talentAtk",
+			"talentSkill",
+			"talentBurst",
+			"weaponLevel",
+			"weaponRefinement",
+			"completion",
+		} {
+			snapshots.Fields.Add(&core.NumberField{
+				Name:    name,
+				OnlyInt: true,
+			})
+		}
+		snapshots.Fields.Add(&core.BoolField{
+			Name: "complete",
+		})
+		snapshots.AddIndex("idx_"+models.PLAN_SNAPSHOTS_COLLECTION_NAME+"_characterPlan_taken", false, "`characterPlan`, `taken`", "")
+		snapshots.AddIndex("idx_"+models.PLAN_SNAPSHOTS_COLLECTION_NAME+"_user_taken", false, "`user`, `taken`", "")
+		// the snapshots are taken by the server only, see progress.Bind, the
+		// history of the plans of before starts with the first periodic one
+		snapshots.ListRule = types.Pointer(userPlanRule)
+		snapshots.ViewRule = types.Pointer(userPlanRule)
+		return app.Save(snapshots)
+	}, func(app core.App) error {
+		snapshots, err := app.FindCollectionByNameOrId(models.PLAN_SNAPSHOTS_COLLECTION_NAME)
+		if err != nil {
+			return err
+		}
+		return app.Delete(snapshots)
+	})
+}
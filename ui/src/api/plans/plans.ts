@@ -20,9 +20,7 @@ export const PLANS_QUERY = queryOptions({
   queryKey: ['plans'],
   async queryFn({ signal, ...conf }) {
     logger.trace('query:plans->start', conf);
-    const res = await pbClient
-      .collection<Plans>('plans')
-      .getFullList({ signal });
+    const res = await pbClient.send<Plans[]>('/api/plans/all', { signal });
     logger.debug('query:plans->success');
     return res.map((plan): Plans => ({
       ...plan,
@@ -30,7 +28,6 @@ export const PLANS_QUERY = queryOptions({
       updated: new Date(plan.updated),
       artifactSetsPlans: plan.artifactSetsPlans?.map((asp) => ({
         ...asp,
-        artifactSets: JSON.parse(asp.artifactSets as unknown as string),
         created: new Date(asp.created),
         updated: new Date(asp.updated),
       })),
@@ -46,7 +43,6 @@ export const PLANS_QUERY = queryOptions({
       })),
       teamPlans: plan.teamPlans?.map((tp) => ({
         ...tp,
-        characters: JSON.parse(tp.characters as unknown as string),
         created: new Date(tp.created),
         updated: new Date(tp.updated),
       })),
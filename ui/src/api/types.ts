@@ -7,6 +7,7 @@ export interface Users extends RecordModel {
   email: string;
   name?: string;
   avatar?: string;
+  autoCompletePlans?: boolean;
 }
 
 // Dictionaries
@@ -57,6 +58,7 @@ export interface Weapons extends RecordModel {
   rarity: number;
   patch: string;
   useless: boolean;
+  aliases?: string[] | null;
 }
 
 export interface Characters extends RecordModel {
@@ -68,6 +70,7 @@ export interface Characters extends RecordModel {
   special: string;
   rarity: number;
   patch: string;
+  aliases?: string[] | null;
 }
 
 export interface ArtifactSets extends RecordModel {
@@ -107,7 +110,13 @@ export interface Plans extends CharacterPlans, PlansExtra {}
 export interface CharacterPlans {
   id: string;
   user: string;
+  profile?: string;
+  workspace?: string;
+  priority?: number;
+  targetPatch?: string;
   complete: boolean;
+  completion?: number;
+  completeSuggested?: boolean;
   character: string;
   characterRole?: string;
   order: number;
@@ -284,6 +284,7 @@ function FilterArtifactTypes() {
                 fileName={at.icon}
                 name={at.name}
                 className={cn('size-5', { 'opacity-40': isEmpty })}
+                thumb
               />
               <span className="text-[10px] font-semibold uppercase tracking-[0.09em] text-muted-foreground">
                 {at.name}
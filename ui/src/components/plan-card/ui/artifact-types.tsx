@@ -132,6 +132,7 @@ export function ArtifactTypesItem(props: ArtifactTypesItemProps) {
           className={cn('size-6', {
             'opacity-40': isEmpty || props.disabled,
           })}
+          thumb
         />
         <span className="text-[10px] font-semibold uppercase tracking-[0.09em] text-muted-foreground">
           {props.artifactTypesItem.name}
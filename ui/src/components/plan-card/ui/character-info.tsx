@@ -37,6 +37,7 @@ export function CharacterInfoContent({ character }: CharacterInfoContentProps) {
           fileName={element.icon}
           name={element.name}
           className="size-4"
+          thumb
         />
         {element.name}
       </Badge>
@@ -47,6 +48,7 @@ export function CharacterInfoContent({ character }: CharacterInfoContentProps) {
           fileName={weaponType.icon}
           name={weaponType.name}
           className="size-4 not-dark:invert"
+          thumb
         />
         {weaponType.name}
       </Badge>
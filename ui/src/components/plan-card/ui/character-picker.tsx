@@ -1,4 +1,3 @@
-import fuzzysearch from 'fuzzysearch';
 import { PropsWithChildren, useMemo, useState } from 'react';
 
 import {
@@ -11,6 +10,7 @@ import { CollectionAvatar } from '@/components/ui/collection-avatar';
 import { Input } from '@/components/ui/input';
 import { ResponsiveDialog } from '@/components/ui/responsive-dialog';
 import { ScrollArea, ScrollBar } from '@/components/ui/scroll-area';
+import { fuzzyNameMatch } from '@/lib/fuzzy-name-match';
 
 import { ElementChip, WeaponTypeChip } from './filter-chip';
 
@@ -42,8 +42,7 @@ function Picker({ onSelect, ignoreCharacters, disabled }: PickerProps) {
             filter.elements.has(c.element)) &&
           (filter.weaponTypes.size === 0 ||
             filter.weaponTypes.has(c.weaponType)) &&
-          (filter.name.length === 0 ||
-            fuzzysearch(filter.name.toLowerCase(), c.name.toLowerCase())),
+          (filter.name.length === 0 || fuzzyNameMatch(filter.name, c)),
       ),
     [characters, ignoreCharacters, filter],
   );
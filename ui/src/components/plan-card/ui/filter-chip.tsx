@@ -54,6 +54,7 @@ export function ElementChip({
         fileName={element.icon}
         name={element.name}
         className={SIZES[size].icon}
+        thumb
       />
       {element.name}
     </button>
@@ -88,6 +89,7 @@ export function WeaponTypeChip({
         fileName={weaponType.icon}
         name={weaponType.name}
         className={cn(SIZES[size].icon, 'not-dark:invert')}
+        thumb
       />
       {weaponType.name}
     </button>
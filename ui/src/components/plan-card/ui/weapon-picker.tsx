@@ -1,4 +1,3 @@
-import fuzzysearch from 'fuzzysearch';
 import { PropsWithChildren, useMemo, useState } from 'react';
 
 import { useWeapons, useWeaponTypes } from '@/api/dictionaries/hooks';
@@ -7,6 +6,7 @@ import { CollectionAvatar } from '@/components/ui/collection-avatar';
 import { Input } from '@/components/ui/input';
 import { ResponsiveDialog } from '@/components/ui/responsive-dialog';
 import { ScrollArea, ScrollBar } from '@/components/ui/scroll-area';
+import { fuzzyNameMatch } from '@/lib/fuzzy-name-match';
 
 import { WeaponTypeChip } from './filter-chip';
 
@@ -35,8 +35,7 @@ function Picker({ weaponTypeId, ignoreWeapons, onSelect }: PickerProps) {
           (weaponTypeId === undefined || w.weaponType === weaponTypeId) &&
           (filter.weaponTypes.size === 0 ||
             filter.weaponTypes.has(w.weaponType)) &&
-          (filter.name.length === 0 ||
-            fuzzysearch(filter.name.toLowerCase(), w.name.toLowerCase())),
+          (filter.name.length === 0 || fuzzyNameMatch(filter.name, w)),
       ),
     [weapons, ignoreWeapons, filter],
   );
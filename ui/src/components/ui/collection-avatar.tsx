@@ -1,17 +1,25 @@
 import { RecordModel } from 'pocketbase';
 
-import { pbClient } from '@/api/pocketbase';
 import { Avatar, AvatarFallback, AvatarImage } from '@/components/ui/avatar';
 import { getShortName } from '@/lib/get-short-name';
+import { iconUrl } from '@/lib/icon-url';
 
 type Props = Parameters<typeof Avatar>[0] & {
   record: RecordModel;
   fileName: string;
   name: string;
+  /** Loads the 64px thumbnail, for the avatars rendered at 32px or less. */
+  thumb?: boolean;
 };
 
-export function CollectionAvatar({ record, fileName, name, ...props }: Props) {
-  const imgSrc = pbClient.files.getURL(record, fileName);
+export function CollectionAvatar({
+  record,
+  fileName,
+  name,
+  thumb,
+  ...props
+}: Props) {
+  const imgSrc = iconUrl(record, fileName, thumb);
   const shortName: string = getShortName(name);
 
   return (
@@ -0,0 +1,14 @@
+import fuzzysearch from 'fuzzysearch';
+
+export function fuzzyNameMatch(
+  query: string,
+  item: { name: string; aliases?: string[] | null },
+) {
+  const needle = query.toLowerCase();
+  return (
+    fuzzysearch(needle, item.name.toLowerCase()) ||
+    (item.aliases ?? []).some((alias) =>
+      fuzzysearch(needle, alias.toLowerCase()),
+    )
+  );
+}
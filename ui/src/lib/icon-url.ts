@@ -0,0 +1,19 @@
+import { RecordModel } from 'pocketbase';
+
+import { pbClient } from '@/api/pocketbase';
+
+// The dictionary icons are named after their content hash, see internals/icons.
+const ICON_NAME = /^([0-9a-f]{32})\.webp$/;
+
+/**
+ * Points a dictionary icon at the shared `/api/icons/{hash}` copy, cached for
+ * good across the collections, and falls back to the record file for the
+ * icons saved before the pipeline.
+ */
+export function iconUrl(record: RecordModel, fileName: string, thumb = false) {
+  const hash = ICON_NAME.exec(fileName)?.[1];
+  if (!hash) {
+    return pbClient.files.getURL(record, fileName);
+  }
+  return pbClient.buildURL(`/api/icons/${hash}${thumb ? '?thumb=1' : ''}`);
+}
@@ -44,8 +44,51 @@ interface Dump extends RecordModel {
   notes: string;
 }
 
+interface JobStarted {
+  status: string;
+  jobId: string;
+  topic: string;
+}
+
+interface Job {
+  id: string;
+  kind: string;
+  status: 'running' | 'done' | 'failed';
+  error: string;
+  progress: Record<string, { done: number; total: number }> | null;
+}
+
 const ROOT_QUERY_KEY = 'dumps';
 
+// The dump routes queue a background job, its outcome arrives on the job
+// realtime topic. The job is also fetched once in case it finished before the
+// subscription was established.
+function waitForJob({ jobId, topic }: JobStarted): Promise<Job> {
+  return new Promise((resolve, reject) => {
+    let settled = false;
+    let unsubscribe: (() => Promise<void>) | undefined;
+    const settle = (job: Job) => {
+      if (settled || job.status === 'running') return;
+      settled = true;
+      unsubscribe?.();
+      if (job.status === 'done') {
+        resolve(job);
+      } else {
+        reject(new Error(job.error || `${job.kind} job failed`));
+      }
+    };
+    pbClient.realtime
+      .subscribe(topic, settle)
+      .then((unsub) => {
+        unsubscribe = unsub;
+        if (settled) unsub();
+        return pbClient.send<Job>(`/api/jobs/${jobId}`, {});
+      })
+      .then(settle)
+      .catch(reject);
+  });
+}
+
 const dumpsQuery = queryOptions({
   queryKey: [ROOT_QUERY_KEY],
   queryFn: () =>
@@ -61,10 +104,12 @@ function useDumpGenerateMutations() {
   const mutation = useMutation({
     mutationKey: [ROOT_QUERY_KEY, 'dump'],
     mutationFn(notes: string | undefined = '') {
-      return pbClient.send<{ status: string }>('/api/dump/generate', {
-        method: 'POST',
-        body: JSON.stringify({ notes }),
-      });
+      return pbClient
+        .send<JobStarted>('/api/dump/generate', {
+          method: 'POST',
+          body: JSON.stringify({ notes }),
+        })
+        .then(waitForJob);
     },
     onSuccess() {
       queryClient.invalidateQueries({ queryKey: [ROOT_QUERY_KEY] });
@@ -81,9 +126,11 @@ function useDumpRestoreMutations() {
   const mutation = useMutation({
     mutationKey: ['dumps', 'restore'],
     mutationFn(dumpId: string) {
-      return pbClient.send<{ status: string }>(`/api/dump/restore/${dumpId}`, {
-        method: 'POST',
-      });
+      return pbClient
+        .send<JobStarted>(`/api/dump/restore/${dumpId}`, {
+          method: 'POST',
+        })
+        .then(waitForJob);
     },
     onSuccess() {
       queryClient.invalidateQueries({ queryKey: [ROOT_QUERY_KEY] });
@@ -97,14 +144,38 @@ function useDumpRestoreMutations() {
   return mutation;
 }
 
+function useDumpRollbackMutations() {
+  const mutation = useMutation({
+    mutationKey: ['dumps', 'rollback'],
+    mutationFn() {
+      return pbClient
+        .send<JobStarted>('/api/dump/rollback', {
+          method: 'POST',
+        })
+        .then(waitForJob);
+    },
+    onSuccess() {
+      queryClient.invalidateQueries({ queryKey: [ROOT_QUERY_KEY] });
+      reloadDictionaries();
+      toast.success('Rolled back');
+    },
+    onError(err, v) {
+      notifyWithRetry(() => mutation.mutate())(err, v);
+    },
+  });
+  return mutation;
+}
+
 function useDumpUploadDbMutations() {
   const mutation = useMutation({
     mutationKey: ['dumps', 'upload'],
     mutationFn(body: FormData) {
-      return pbClient.send<{ status: string }>(`/api/dump/upload`, {
-        method: 'POST',
-        body,
-      });
+      return pbClient
+        .send<JobStarted>(`/api/dump/upload`, {
+          method: 'POST',
+          body,
+        })
+        .then(waitForJob);
     },
     onSuccess() {
       queryClient.invalidateQueries({ queryKey: [ROOT_QUERY_KEY] });
@@ -137,6 +208,7 @@ function RouteComponent() {
   const dumpGenerate = useDumpGenerateMutations();
   const dumpRestore = useDumpRestoreMutations();
   const dumpUpload = useDumpUploadDbMutations();
+  const dumpRollback = useDumpRollbackMutations();
   const dictionaryVersion = useQuery(dictionaryVersionQuery);
 
   const onDumpUpload = (e: ChangeEvent<HTMLInputElement>) => {
@@ -160,6 +232,14 @@ function RouteComponent() {
           {dumpGenerate.isPending && <Icons.Spinner className="animate-spin" />}
           Dump current
         </Button>
+        <Button
+          variant="secondary"
+          onClick={() => dumpRollback.mutateAsync()}
+          disabled={dumpRollback.isPending}
+        >
+          {dumpRollback.isPending && <Icons.Spinner className="animate-spin" />}
+          Rollback
+        </Button>
         <div className="border rounded-md p-2 flex gap-2">
           <Label
             className="data-[error=true]:text-destructive flex gap-1 items-center"
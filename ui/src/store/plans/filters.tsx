@@ -1,4 +1,3 @@
-import fuzzysearch from 'fuzzysearch';
 import { produce, WritableDraft } from 'immer';
 import {
   createContext,
@@ -11,6 +10,7 @@ import {
 import { useCharactersMap } from '@/api/dictionaries/hooks';
 import { usePlans } from '@/api/plans/plans';
 import { Characters, Plans } from '@/api/types';
+import { fuzzyNameMatch } from '@/lib/fuzzy-name-match';
 import { mapGetOrSetDefault } from '@/lib/map-get-or-set-default';
 
 /** Key: artifact type, Value: set of specials */
@@ -102,8 +102,7 @@ export function FiltersProvider({ children, value, setValue }: Props) {
       };
 
       const nameFilter = () =>
-        !value.name ||
-        fuzzysearch(value.name.toLowerCase(), character.name.toLowerCase());
+        !value.name || fuzzyNameMatch(value.name, character);
 
       return (
         simpleFilters &&